@@ -0,0 +1,213 @@
+package synexis
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/synxms/synexis/pkg/agent"
+	"github.com/synxms/synexis/pkg/client"
+	"github.com/synxms/synexis/pkg/storage"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func agentSocketPath() string {
+	return profileSocketPath(deps.NewStorage("").Profile())
+}
+
+// profileSocketPath is the agent socket of profile in the store in use, an
+// unresolvable store path only leaves the store out of the name.
+func profileSocketPath(profile string) string {
+	storePath, err := storage.Path()
+	if err == nil {
+		storePath, err = filepath.Abs(storePath)
+	}
+	if err != nil {
+		storePath = ""
+	}
+	return agent.SocketPath(storePath, profile)
+}
+
+// agentForwardedFlags are the flags the daemon needs to reach the same store
+// and server the way this command would.
+var agentForwardedFlags = []string{
+	"store-path",
+	"connect-timeout",
+	"read-timeout",
+	"http-timeout",
+	"retries",
+	"ca-cert",
+	"client-cert",
+	"client-key",
+	"insecure-skip-verify",
+}
+
+func agentDaemonArgs(cmd *cobra.Command) []string {
+	args := []string{"agent", "run", "--profile", deps.NewStorage("").Profile()}
+	for _, name := range agentForwardedFlags {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || !flag.Changed {
+			continue
+		}
+		value := flag.Value.String()
+		if flag.Value.Type() == "string" && value != "" {
+			if abs, err := filepath.Abs(value); err == nil {
+				value = abs
+			}
+		}
+		args = append(args, "--"+name+"="+value)
+	}
+	return args
+}
+
+func agentStart(cmd *cobra.Command, _ []string) error {
+	if !agent.Supported {
+		fatalln("The credential agent is not supported on this platform.")
+	}
+	socketPath := agentSocketPath()
+	if _, err := agent.Query(cmd.Context(), socketPath, agent.OpStatus); err == nil {
+		fmt.Fprintln(deps.Stdout, "Agent already running.")
+		return nil
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		fatalln("Failed to locate synexis executable:", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0700); err != nil {
+		fatalln("Failed to create agent directory:", err)
+	}
+	logPath := strings.TrimSuffix(socketPath, ".sock") + ".log"
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		fatalln("Failed to open agent log:", err)
+	}
+	defer logFile.Close()
+	daemon := exec.Command(executable, agentDaemonArgs(cmd)...)
+	daemon.Stdout = logFile
+	daemon.Stderr = logFile
+	daemon.SysProcAttr = detachedProcessAttributes()
+	if err := daemon.Start(); err != nil {
+		fatalln("Failed to start agent:", err)
+	}
+	_ = daemon.Process.Release()
+
+	deadline := time.After(5 * time.Second)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if _, err := agent.Query(cmd.Context(), socketPath, agent.OpStatus); err == nil {
+			fmt.Fprintln(deps.Stdout, "Agent started, listening on", socketPath)
+			return nil
+		}
+		select {
+		case <-deadline:
+			fatalln("Agent did not come up, see", logPath)
+		case <-cmd.Context().Done():
+			exitIfCancelled(cmd.Context())
+		case <-ticker.C:
+		}
+	}
+}
+
+func agentRun(cmd *cobra.Command, _ []string) error {
+	store := deps.NewStorage("")
+	if err := store.Init(); err != nil {
+		fatalln("Failed to init storage:", err)
+	}
+	defer store.Close()
+	profile := store.Profile()
+	baseUrl, err := store.Get("base_url")
+	if err != nil {
+		fatalln("Failed to get base url:", err)
+	}
+	authenticationService := newAuthenticationService(baseUrl, client.WithStoredTokens(store))
+	source := tokenSourceFunc(authenticationService.AccessToken)
+	server := agent.NewServer(source, baseUrl, profile, log.New(deps.Stderr, "", log.LstdFlags).Printf)
+	if err := server.ListenAndServe(cmd.Context(), profileSocketPath(profile)); err != nil {
+		fatalln("Agent stopped:", err)
+	}
+	return nil
+}
+
+func agentStop(cmd *cobra.Command, _ []string) error {
+	if _, err := agent.Query(cmd.Context(), agentSocketPath(), agent.OpStop); err != nil {
+		if errors.Is(err, agent.ErrNotRunning) {
+			fmt.Fprintln(deps.Stdout, "Agent is not running.")
+			return nil
+		}
+		fatalln("Failed to stop agent:", err)
+	}
+	fmt.Fprintln(deps.Stdout, "Agent stopped.")
+	return nil
+}
+
+func agentStatus(cmd *cobra.Command, _ []string) error {
+	status, err := agent.Query(cmd.Context(), agentSocketPath(), agent.OpStatus)
+	if err != nil {
+		if errors.Is(err, agent.ErrNotRunning) {
+			fmt.Fprintln(deps.Stdout, "Agent is not running.")
+			deps.Exit(exitCodeCheckFailed)
+		}
+		fatalln("Failed to query agent:", err)
+	}
+	fmt.Fprintln(deps.Stdout, "Agent running, pid", status.PID)
+	fmt.Fprintln(deps.Stdout, "Profile:", status.Profile)
+	fmt.Fprintln(deps.Stdout, "Base URL:", status.BaseURL)
+	fmt.Fprintln(deps.Stdout, "Started at:", status.StartedAt.Local().Format(time.DateTime))
+	if !status.Expiry.IsZero() {
+		fmt.Fprintln(deps.Stdout, "Token expires at:", status.Expiry.Local().Format(time.DateTime), "("+relativeTime(status.Expiry)+")")
+	}
+	return nil
+}
+
+func agentToken(cmd *cobra.Command, _ []string) error {
+	resp, err := agent.Query(cmd.Context(), agentSocketPath(), agent.OpToken)
+	if err != nil {
+		fatalln("Failed to get token from agent:", err)
+	}
+	fmt.Fprintln(deps.Stdout, resp.AccessToken)
+	return nil
+}
+
+func InitializeAgentCmd(agentCmd *cobra.Command) {
+	agentCmd.AddCommand(&cobra.Command{
+		Use:   "start",
+		Short: "Start the credential agent in the background",
+		Long:  `Start the credential agent in the background, it keeps the access token fresh and serves it over a Unix socket`,
+		Args:  cobra.NoArgs,
+		RunE:  agentStart,
+	})
+	agentCmd.AddCommand(&cobra.Command{
+		Use:    "run",
+		Short:  "Run the credential agent in the foreground",
+		Long:   `Run the credential agent in the foreground`,
+		Args:   cobra.NoArgs,
+		Hidden: true,
+		RunE:   agentRun,
+	})
+	agentCmd.AddCommand(&cobra.Command{
+		Use:   "stop",
+		Short: "Stop the running credential agent",
+		Long:  `Stop the running credential agent`,
+		Args:  cobra.NoArgs,
+		RunE:  agentStop,
+	})
+	agentCmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show whether the credential agent is running",
+		Long:  `Show whether the credential agent is running, exits non-zero when it is not`,
+		Args:  cobra.NoArgs,
+		RunE:  agentStatus,
+	})
+	agentCmd.AddCommand(&cobra.Command{
+		Use:   "token",
+		Short: "Print a fresh access token from the credential agent",
+		Long:  `Print a fresh access token from the credential agent`,
+		Args:  cobra.NoArgs,
+		RunE:  agentToken,
+	})
+}
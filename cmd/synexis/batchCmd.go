@@ -0,0 +1,321 @@
+package synexis
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/synxms/synexis/pkg/storage"
+	"github.com/synxms/synexis/src/service"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var batchResultHeader = []string{"row", "name", "dataset", "sensory", "dataset_id", "sensory_id", "request_id", "error"}
+
+type (
+	// batchResult is one line of the results file. A row without RequestID
+	// is submitted again by --continue.
+	batchResult struct {
+		batchRow
+		DatasetID string
+		SensoryID string
+		RequestID string
+		Error     string
+	}
+	// batchUpload makes rows that share a file upload it once.
+	batchUpload struct {
+		once   sync.Once
+		id     string
+		err    error
+		reused bool
+	}
+	batch struct {
+		service     service.Authentication
+		manifestDir string
+
+		mu      sync.Mutex
+		uploads map[string]*batchUpload
+	}
+)
+
+func sentinelBatch(cmd *cobra.Command, _ []string) error {
+	manifestPath, _ := cmd.Flags().GetString("file")
+	resultsPath, _ := cmd.Flags().GetString("results")
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	resume, _ := cmd.Flags().GetBool("continue")
+	if manifestPath == "" {
+		fatalln("Use '-f' to specify the manifest file")
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+	if resultsPath == "" {
+		resultsPath = strings.TrimSuffix(manifestPath, filepath.Ext(manifestPath)) + ".results.csv"
+	}
+	rows, err := readBatchManifest(manifestPath)
+	if err != nil {
+		fatalln("Failed to read manifest:", err)
+	}
+	results := make([]batchResult, len(rows))
+	for i, row := range rows {
+		results[i].batchRow = row
+	}
+	if resume {
+		if err := mergeBatchResults(resultsPath, results); err != nil {
+			fatalln("Failed to continue batch:", err)
+		}
+	} else if _, err := os.Stat(resultsPath); err == nil {
+		fatalln("Results file", resultsPath, "already exists, use --continue to pick up that batch or remove it")
+	}
+
+	authenticationService, store, closeStore := openAuthenticatedService(nil)
+	defer closeStore()
+	b := &batch{service: authenticationService, manifestDir: filepath.Dir(manifestPath), uploads: map[string]*batchUpload{}}
+	var pending []int
+	for i, result := range results {
+		b.seedUploads(result)
+		if result.RequestID == "" {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) < len(results) {
+		fmt.Fprintf(deps.Stdout, "Skipping %d rows already submitted.\n", len(results)-len(pending))
+	}
+
+	ctx := cmd.Context()
+	queue := make(chan int)
+	done := make(chan int)
+	var workers sync.WaitGroup
+	for range min(parallel, max(len(pending), 1)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for i := range queue {
+				b.submit(ctx, &results[i])
+				done <- i
+			}
+		}()
+	}
+	go func() {
+		defer close(queue)
+		for _, i := range pending {
+			select {
+			case queue <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+
+	completed, failed := 0, 0
+	for i := range done {
+		completed++
+		result := &results[i]
+		switch {
+		case result.RequestID != "":
+			recordJob(jobRecord{RequestID: result.RequestID, DatasetID: result.DatasetID, SensoryID: result.SensoryID, CreatedAt: deps.Now()})
+			fmt.Fprintf(deps.Stdout, "[%d/%d] row %d%s: request %s\n", completed, len(pending), result.Row, batchRowLabel(result.Name), result.RequestID)
+		case ctx.Err() != nil:
+			result.Error = ""
+		default:
+			failed++
+			fmt.Fprintf(deps.Stdout, "[%d/%d] row %d%s: %s\n", completed, len(pending), result.Row, batchRowLabel(result.Name), result.Error)
+		}
+		if err := writeBatchResults(resultsPath, results); err != nil {
+			fmt.Fprintln(deps.Stderr, "Warning: could not write results:", err)
+		}
+	}
+	if store != nil {
+		b.recordUploads(store)
+	}
+	if err := writeBatchResults(resultsPath, results); err != nil {
+		fatalln("Failed to write results:", err)
+	}
+	fmt.Fprintln(deps.Stdout, "Results written to", resultsPath)
+	if ctx.Err() != nil {
+		fmt.Fprintln(deps.Stderr, "Run again with --continue to submit the remaining rows.")
+		exitIfCancelled(ctx)
+	}
+	if failed > 0 {
+		fatalln(failed, "of", len(pending), "rows failed, run again with --continue to retry them")
+	}
+	return nil
+}
+
+func batchRowLabel(name string) string {
+	if name == "" {
+		return ""
+	}
+	return " (" + name + ")"
+}
+
+// submit uploads the files of a row that are not uploaded yet and creates its
+// training request.
+func (b *batch) submit(ctx context.Context, result *batchResult) {
+	var err error
+	result.Error = ""
+	if result.DatasetID == "" {
+		result.DatasetID, err = b.resolve(ctx, "dataset", result.Dataset)
+	}
+	if err == nil && result.SensoryID == "" {
+		result.SensoryID, err = b.resolve(ctx, "sensory", result.Sensory)
+	}
+	if err == nil {
+		var response *service.ResponseCreateRequest
+		if response, err = b.service.CreateRequest(ctx, result.SensoryID, result.DatasetID); err == nil {
+			if response.ResponseCode == "00" {
+				result.RequestID = response.Data.RequestID
+			} else {
+				err = errors.New("create request failed: " + response.ResponseMessage)
+			}
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+}
+
+// resolve returns value itself when it is an ID, and uploads it when it
+// names a file. Relative paths are taken from the manifest's directory.
+func (b *batch) resolve(ctx context.Context, kind, value string) (string, error) {
+	path := value
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(b.manifestDir, path)
+	}
+	if info, err := os.Stat(path); err != nil || (info.IsDir() && kind != "dataset") {
+		if strings.ContainsAny(value, `/\`) {
+			return "", fmt.Errorf("%s file %s not found", kind, value)
+		}
+		return value, nil
+	}
+	b.mu.Lock()
+	upload, ok := b.uploads[kind+":"+path]
+	if !ok {
+		upload = &batchUpload{}
+		b.uploads[kind+":"+path] = upload
+	}
+	b.mu.Unlock()
+	upload.once.Do(func() {
+		upload.id, upload.err = b.upload(ctx, kind, path)
+	})
+	return upload.id, upload.err
+}
+
+func (b *batch) upload(ctx context.Context, kind, path string) (string, error) {
+	if kind == "dataset" {
+		result, err := uploadDataset(ctx, b.service, &pendingUpload{Kind: kind, File: path})
+		if err != nil {
+			return "", err
+		}
+		if result.ResponseCode != "00" {
+			return "", errors.New("upload dataset failed: " + result.ResponseMessage)
+		}
+		return result.Data.DatasetID, nil
+	}
+	result, err := uploadSensory(ctx, b.service, &pendingUpload{Kind: kind, File: path})
+	if err != nil {
+		return "", err
+	}
+	if result.ResponseCode != "00" {
+		return "", errors.New("upload sensory failed: " + result.ResponseMessage)
+	}
+	return result.Data.SensoryID, nil
+}
+
+// seedUploads reuses the IDs of files uploaded by an earlier run of the batch.
+func (b *batch) seedUploads(result batchResult) {
+	for _, seed := range []struct{ kind, value, id string }{
+		{"dataset", result.Dataset, result.DatasetID},
+		{"sensory", result.Sensory, result.SensoryID},
+	} {
+		path := seed.value
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(b.manifestDir, path)
+		}
+		if seed.id == "" || seed.id == seed.value {
+			continue
+		}
+		upload := &batchUpload{id: seed.id, reused: true}
+		upload.once.Do(func() {})
+		b.uploads[seed.kind+":"+path] = upload
+	}
+}
+
+func (b *batch) recordUploads(store storage.Storage) {
+	for key, upload := range b.uploads {
+		if upload.reused || upload.err != nil || upload.id == "" {
+			continue
+		}
+		kind, file, _ := strings.Cut(key, ":")
+		recordUpload(store, uploadRecord{ID: upload.id, Kind: kind, File: file, UploadedAt: deps.Now()})
+	}
+}
+
+// mergeBatchResults copies the progress of an earlier run into results. The
+// manifest may gain rows and failed rows may be fixed, but rows that were
+// submitted must not change.
+func mergeBatchResults(path string, results []batchResult) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to read results %s: %w", path, err)
+	}
+	if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(batchResultHeader, ",") {
+		return fmt.Errorf("%s is not a batch results file", path)
+	}
+	for _, record := range records[1:] {
+		row, err := strconv.Atoi(record[0])
+		if err != nil || row < 1 || row > len(results) {
+			return fmt.Errorf("results %s has row %s which is not in the manifest", path, record[0])
+		}
+		result := &results[row-1]
+		changed := record[2] != result.Dataset || record[3] != result.Sensory
+		if changed && record[6] != "" {
+			return fmt.Errorf("row %d of the manifest changed since it was submitted as %s", row, record[6])
+		}
+		if !changed {
+			result.DatasetID, result.SensoryID, result.RequestID, result.Error = record[4], record[5], record[6], record[7]
+		}
+	}
+	return nil
+}
+
+// writeBatchResults replaces the results file in one step, so an interrupted
+// batch always leaves a complete file behind.
+func writeBatchResults(path string, results []batchResult) error {
+	file, err := os.CreateTemp(filepath.Dir(path), ".synexis-batch-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	writer := csv.NewWriter(file)
+	_ = writer.Write(batchResultHeader)
+	for _, result := range results {
+		_ = writer.Write([]string{
+			strconv.Itoa(result.Row), result.Name, result.Dataset, result.Sensory,
+			result.DatasetID, result.SensoryID, result.RequestID, result.Error,
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
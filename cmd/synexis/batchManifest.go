@@ -0,0 +1,184 @@
+package synexis
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// batchRow is one (dataset, sensory) pair of a batch manifest. Dataset and
+// Sensory are either IDs or paths of files to upload.
+type batchRow struct {
+	Row     int
+	Name    string
+	Dataset string
+	Sensory string
+}
+
+var errEmptyManifest = errors.New("manifest has no rows")
+
+// readBatchManifest reads a CSV or YAML manifest, chosen by file extension.
+func readBatchManifest(path string) ([]batchRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var rows []batchRow
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		rows, err = parseBatchCSV(file)
+	case ".yaml", ".yml":
+		rows, err = parseBatchYAML(file)
+	default:
+		return nil, fmt.Errorf("unsupported manifest %s, use a .csv or .yaml file", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	if len(rows) == 0 {
+		return nil, errEmptyManifest
+	}
+	for i := range rows {
+		rows[i].Row = i + 1
+		if rows[i].Dataset == "" || rows[i].Sensory == "" {
+			return nil, fmt.Errorf("row %d of %s needs both dataset and sensory", rows[i].Row, path)
+		}
+	}
+	return rows, nil
+}
+
+// parseBatchCSV expects a header naming the dataset and sensory columns, and
+// optionally name. Other columns are ignored so a manifest can carry notes.
+func parseBatchCSV(r io.Reader) ([]batchRow, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, required := range []string{"dataset", "sensory"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("header has no %s column", required)
+		}
+	}
+	field := func(record []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	var rows []batchRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		rows = append(rows, batchRow{
+			Name:    field(record, "name"),
+			Dataset: field(record, "dataset"),
+			Sensory: field(record, "sensory"),
+		})
+	}
+}
+
+// parseBatchYAML understands the small part of YAML a manifest needs: a list
+// of flat mappings, optionally under a single top level key such as rows.
+//
+//	rows:
+//	  - name: baseline
+//	    dataset: data/train.csv
+//	    sensory: sensory-42
+func parseBatchYAML(r io.Reader) ([]batchRow, error) {
+	var (
+		rows    []batchRow
+		current *batchRow
+		keyed   bool
+	)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(stripYAMLComment(scanner.Text()))
+		switch {
+		case text == "" || text == "---":
+			continue
+		case strings.HasPrefix(text, "- ") || text == "-":
+			rows = append(rows, batchRow{})
+			current = &rows[len(rows)-1]
+			text = strings.TrimSpace(strings.TrimPrefix(text, "-"))
+			if text == "" {
+				continue
+			}
+		case current == nil && !keyed && strings.HasSuffix(text, ":"):
+			keyed = true
+			continue
+		case current == nil:
+			return nil, fmt.Errorf("line %d: expected a list item starting with '-'", line)
+		}
+		key, value, ok := strings.Cut(text, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", line)
+		}
+		value, err := unquoteYAML(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		switch strings.TrimSpace(key) {
+		case "name":
+			current.Name = value
+		case "dataset":
+			current.Dataset = value
+		case "sensory":
+			current.Sensory = value
+		}
+	}
+	return rows, scanner.Err()
+}
+
+// stripYAMLComment drops a # comment that is not inside quotes.
+func stripYAMLComment(line string) string {
+	var quote rune
+	for i, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func unquoteYAML(value string) (string, error) {
+	switch {
+	case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+		return strconv.Unquote(value)
+	case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	}
+	return value, nil
+}
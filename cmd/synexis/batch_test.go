@@ -0,0 +1,130 @@
+package synexis
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadBatchManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []batchRow
+		wantErr string
+	}{
+		{"csv", "batch.csv", "name,dataset,sensory\nfirst,train.csv,sensory-1\n", []batchRow{{1, "first", "train.csv", "sensory-1"}}, ""},
+		{"csv columns in any order with notes", "batch.CSV", "# tuning runs\nSensory, notes, Dataset\nsensory-1, baseline, dataset-1\n\n sensory-2 ,, dataset-2\n", []batchRow{{1, "", "dataset-1", "sensory-1"}, {2, "", "dataset-2", "sensory-2"}}, ""},
+		{"csv without sensory column", "batch.csv", "name,dataset\nfirst,train.csv\n", nil, "header has no sensory column"},
+		{"csv row without sensory", "batch.csv", "dataset,sensory\ntrain.csv,sensory-1\ntest.csv\n", nil, "row 2 of"},
+		{"csv empty", "batch.csv", "", nil, errEmptyManifest.Error()},
+		{"csv header only", "batch.csv", "dataset,sensory\n", nil, errEmptyManifest.Error()},
+		{"yaml under rows", "batch.yaml", "---\nrows:\n  # the baseline\n  - name: baseline\n    dataset: data/train.csv\n    sensory: sensory-42\n  - dataset: \"data/with # hash.csv\"\n    sensory: 'it''s'  # quoted\n", []batchRow{{1, "baseline", "data/train.csv", "sensory-42"}, {2, "", "data/with # hash.csv", "it's"}}, ""},
+		{"yaml top level list", "batch.yml", "-\n  dataset: train.csv\n  sensory: sensory-1\n  owner: ml-team\n", []batchRow{{1, "", "train.csv", "sensory-1"}}, ""},
+		{"yaml text before the list", "batch.yaml", "rows:\nname: baseline\n", nil, "line 2: expected a list item"},
+		{"yaml item without a value", "batch.yaml", "- dataset train.csv\n", nil, "line 1: expected key: value"},
+		{"yaml bad quoting", "batch.yaml", "- dataset: \"train\\q.csv\"\n  sensory: s\n", nil, "line 1:"},
+		{"unsupported extension", "batch.json", "[]", nil, "unsupported manifest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readBatchManifest(writeFile(t, tt.file, tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want an error with %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(rows, tt.want) {
+				t.Errorf("got %+v, want %+v", rows, tt.want)
+			}
+		})
+	}
+}
+
+func readBatchResults(t *testing.T, path string) [][]string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records[1:]
+}
+
+// Rows sharing a file upload it once, a failed row is left for --continue,
+// which neither uploads nor submits the finished rows again.
+func TestBatchContinue(t *testing.T) {
+	e := newCLIEnv(t)
+	e.login()
+	dir := t.TempDir()
+	for name, content := range map[string]string{"train.csv": "x,y\n1,2\n", "sensory.json": `{"sensors": ["camera"]}`} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifest := filepath.Join(dir, "batch.csv")
+	rows := "name,dataset,sensory\nfirst,train.csv,sensory.json\nsecond,train.csv,sensory.json\nthird,data/extra.csv,sensory.json\n"
+	if err := os.WriteFile(manifest, []byte(rows), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	result := e.run("service", "sentinel", "batch", "-f", manifest, "-p", "3")
+	if result.Code != 1 || !strings.Contains(result.Stderr, "1 of 3 rows failed") {
+		t.Fatalf("batch exited with %d\nstdout:\n%s\nstderr:\n%s", result.Code, result.Stdout, result.Stderr)
+	}
+	if uploads := e.mock.Uploads(); len(uploads) != 2 {
+		t.Errorf("batch made %d uploads, want the shared dataset and sensory once each", len(uploads))
+	}
+	resultsPath := filepath.Join(dir, "batch.results.csv")
+	results := readBatchResults(t, resultsPath)
+	if results[0][6] == "" || results[1][6] == "" || results[0][4] != results[1][4] {
+		t.Errorf("results of the shared rows: %v", results[:2])
+	}
+	if results[2][6] != "" || !strings.Contains(results[2][7], "data/extra.csv not found") {
+		t.Errorf("result of the failing row: %v", results[2])
+	}
+
+	if result := e.run("service", "sentinel", "batch", "-f", manifest); result.Code != 1 || !strings.Contains(result.Stderr, "already exists") {
+		t.Errorf("second run without --continue exited with %d: %s", result.Code, result.Stderr)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "data"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "data", "extra.csv"), []byte("x,y\n3,4\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	result = e.mustRun("service", "sentinel", "batch", "-f", manifest, "--continue")
+	if !strings.Contains(result.Stdout, "Skipping 2 rows already submitted.") {
+		t.Errorf("continue printed\n%s\nwant the finished rows skipped", result.Stdout)
+	}
+	if uploads := e.mock.Uploads(); len(uploads) != 3 {
+		t.Errorf("server received %d uploads in total, want only the new dataset added", len(uploads))
+	}
+	if requests := e.mock.Requests(); len(requests) != 3 {
+		t.Errorf("server holds %d requests, want one per row", len(requests))
+	}
+	if final := readBatchResults(t, resultsPath); final[0][6] != results[0][6] || final[2][6] == "" || final[2][7] != "" {
+		t.Errorf("results after continue: %v", final)
+	}
+
+	changed := strings.Replace(rows, "first,train.csv", "first,data/extra.csv", 1)
+	if err := os.WriteFile(manifest, []byte(changed), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if result := e.run("service", "sentinel", "batch", "-f", manifest, "--continue"); result.Code != 1 || !strings.Contains(result.Stderr, "row 1 of the manifest changed") {
+		t.Errorf("continue after a submitted row changed exited with %d: %s", result.Code, result.Stderr)
+	}
+}
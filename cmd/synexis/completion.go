@@ -0,0 +1,136 @@
+package synexis
+
+import (
+	"context"
+	"github.com/spf13/cobra"
+	"github.com/synxms/synexis/pkg/storage"
+	"os"
+	"strings"
+	"time"
+)
+
+// completionTimeout bounds network lookups so a slow server never stalls
+// the shell.
+const completionTimeout = 2 * time.Second
+
+var (
+	datasetExtensions = []string{"csv", "tsv", "json", "jsonl", "parquet", "zip", "gz", "tgz"}
+	sensoryExtensions = []string{"json", "yaml", "yml"}
+)
+
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+func completeFilesWithExtension(extensions []string) completionFunc {
+	return func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return extensions, cobra.ShellCompDirectiveFilterFileExt
+	}
+}
+
+// openCompletionStore returns nil when the store cannot be used, completions
+// then simply offer nothing. PersistentPreRun does not run for completions,
+// so the store flags are applied here.
+func openCompletionStore() storage.Storage {
+	useStoreFlags()
+	store := deps.NewStorage("")
+	if err := store.Init(); err != nil {
+		return nil
+	}
+	return store
+}
+
+func completeProfiles(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	store := openCompletionStore()
+	if store == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer store.Close()
+	profiles, _ := store.Profiles()
+	return profiles, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeStoreKeys(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	store := openCompletionStore()
+	if store == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer store.Close()
+	keys, _ := store.Keys()
+	return keys, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeRequestIDs offers the training requests of the local job registry.
+func completeRequestIDs(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	store := openCompletionStore()
+	if store == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer store.Close()
+	jobs, _ := listJobs(store)
+	var completions []string
+	for _, job := range jobs {
+		completions = append(completions, job.RequestID+"\t"+job.CreatedAt.Local().Format(time.DateTime)+", dataset "+job.DatasetID)
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeUploadIDs offers IDs of earlier uploads of kind, falling back to
+// file names for flags that also take an ID file.
+func completeUploadIDs(kind string) completionFunc {
+	return func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		store := openCompletionStore()
+		if store == nil {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		defer store.Close()
+		uploads, _ := listUploads(store, kind)
+		var completions []string
+		for _, upload := range uploads {
+			completions = append(completions, upload.ID+"\t"+upload.File)
+		}
+		return completions, cobra.ShellCompDirectiveDefault
+	}
+}
+
+// completeAPIKeyPrefixes asks the server, once, without retries.
+func completeAPIKeyPrefixes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	store := openCompletionStore()
+	if store == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer store.Close()
+	if baseUrl, _ := store.Get("base_url"); baseUrl == "" && os.Getenv(envBaseURL) == "" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	config := httpConfig
+	config.MaxRetries = 0
+	config.Timeout = completionTimeout
+	authenticationService, _, _, err := connectService(store, config)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	ctx, cancel := context.WithTimeout(cmd.Context(), completionTimeout)
+	defer cancel()
+	result, err := authenticationService.ListAPIKeys(ctx)
+	if err != nil || result.ResponseCode != "00" {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var prefixes []string
+	for _, key := range result.Data {
+		if strings.HasPrefix(key.Prefix, toComplete) {
+			prefixes = append(prefixes, key.Prefix)
+		}
+	}
+	return prefixes, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,40 @@
+package synexis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/synxms/synexis/pkg/httpclient"
+	"github.com/synxms/synexis/pkg/mockserver"
+)
+
+func TestCompleteAPIKeyPrefixes(t *testing.T) {
+	e := newCLIEnv(t)
+	e.login()
+	e.mustRun("service", "sentinel", "apikey")
+
+	result := e.mustRun(cobra.ShellCompRequestCmd, "service", "sentinel", "apikey", "list", "SYX")
+	lines := strings.Split(strings.TrimSpace(result.Stdout), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "SYX") || lines[1] != ":4" {
+		t.Errorf("completion printed\n%s\nwant the generated prefix and the no file directive", result.Stdout)
+	}
+	if httpConfig != httpclient.DefaultConfig() {
+		t.Errorf("completion left the http config at %+v", httpConfig)
+	}
+
+	e.mock.SetFaults(mockserver.Faults{ErrorRate: 1, ErrorStatus: 500})
+	result = e.mustRun(cobra.ShellCompRequestCmd, "service", "sentinel", "apikey", "list", "")
+	if strings.TrimSpace(result.Stdout) != ":1" {
+		t.Errorf("completion printed\n%s\nwant only the error directive", result.Stdout)
+	}
+}
+
+func TestCompleteAPIKeyPrefixesWithoutBaseURL(t *testing.T) {
+	e := newCLIEnv(t)
+	t.Setenv(envBaseURL, "not a url")
+	result := e.run(cobra.ShellCompRequestCmd, "service", "sentinel", "apikey", "list", "")
+	if result.Code != 0 || strings.TrimSpace(result.Stdout) != ":1" {
+		t.Errorf("completion exited with %d and printed\n%s\nwant only the error directive", result.Code, result.Stdout)
+	}
+}
@@ -0,0 +1,519 @@
+package synexis
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/synxms/synexis/pkg/client"
+	"github.com/synxms/synexis/pkg/storage"
+	"github.com/synxms/synexis/pkg/terminal"
+	"github.com/synxms/synexis/src/service"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	dashboardCallTimeout = 10 * time.Second
+	dashboardLogLines    = 200
+)
+
+type (
+	dashboard struct {
+		service   service.Authentication
+		store     storage.Storage
+		outputDir string
+		interval  time.Duration
+
+		requests  []client.TrainingRequest
+		selected  int
+		detail    *dashboardDetail
+		message   string
+		confirm   string
+		refreshed time.Time
+	}
+	dashboardDetail struct {
+		request   client.TrainingRequest
+		logs      []client.TrainingLogEntry
+		artifacts []client.TrainingArtifact
+	}
+	dashboardKey int
+	fdFile       interface{ Fd() uintptr }
+)
+
+const (
+	keyNone dashboardKey = iota
+	keyUp
+	keyDown
+	keyEnter
+	keyBack
+	keyQuit
+	keyCancel
+	keyRetry
+	keyDownload
+	keyRefresh
+)
+
+func sentinelDashboard(cmd *cobra.Command, _ []string) error {
+	interval, _ := cmd.Flags().GetDuration("interval")
+	once, _ := cmd.Flags().GetBool("once")
+	outputDir, _ := cmd.Flags().GetString("output-dir")
+	if interval < time.Second {
+		interval = time.Second
+	}
+	authenticationService, store, closeStore := openAuthenticatedService(nil)
+	defer closeStore()
+	d := &dashboard{service: authenticationService, store: store, outputDir: outputDir, interval: interval}
+
+	input, inputOk := deps.Stdin.(fdFile)
+	output, outputOk := deps.Stdout.(fdFile)
+	if once || !inputOk || !outputOk || !terminal.IsTerminal(input.Fd()) || !terminal.IsTerminal(output.Fd()) {
+		return d.runPlain(cmd.Context(), once)
+	}
+	return d.runInteractive(cmd.Context(), input.Fd(), output.Fd())
+}
+
+// runPlain prints the request table on every refresh, for pipes, CI logs and
+// terminals that cannot be switched to raw input.
+func (d *dashboard) runPlain(ctx context.Context, once bool) error {
+	for {
+		if err := d.refresh(ctx); err != nil {
+			exitIfCancelled(ctx)
+			if once {
+				fatalln("Failed to list training requests:", err)
+			}
+			fmt.Fprintln(deps.Stderr, "Failed to list training requests:", err)
+		} else {
+			fmt.Fprintln(deps.Stdout, "Training requests at", d.refreshed.Local().Format(time.DateTime))
+			writer := tabwriter.NewWriter(deps.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "REQUEST ID\tSTATUS\tCREATED\tUPDATED\tDATASET\tSENSORY")
+			for _, request := range d.requests {
+				fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", request.RequestID, request.Status,
+					request.CreatedAt.Local().Format(time.DateTime), request.UpdatedAt.Local().Format(time.DateTime),
+					request.DatasetID, request.SensoryID)
+			}
+			_ = writer.Flush()
+		}
+		if once {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(d.interval):
+			fmt.Fprintln(deps.Stdout)
+		}
+	}
+}
+
+func (d *dashboard) runInteractive(ctx context.Context, inputFd, outputFd uintptr) error {
+	restoreInput, err := terminal.MakeRaw(inputFd)
+	if err != nil {
+		return d.runPlain(ctx, false)
+	}
+	defer restoreInput()
+	if restoreOutput, err := terminal.EnableANSI(outputFd); err == nil {
+		defer restoreOutput()
+	}
+	// alternate screen with a hidden cursor, undone in reverse on exit
+	fmt.Fprint(deps.Stdout, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(deps.Stdout, "\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan dashboardKey)
+	go readDashboardKeys(deps.Stdin, keys)
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	d.reload(ctx)
+	for {
+		d.render(outputFd)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			d.reload(ctx)
+		case key, ok := <-keys:
+			if !ok || key == keyQuit {
+				return nil
+			}
+			d.handleKey(ctx, key)
+		}
+	}
+}
+
+func readDashboardKeys(input interface{ Read([]byte) (int, error) }, keys chan<- dashboardKey) {
+	defer close(keys)
+	buf := make([]byte, 16)
+	for {
+		n, err := input.Read(buf)
+		if err != nil {
+			return
+		}
+		if key := parseDashboardKey(string(buf[:n])); key != keyNone {
+			keys <- key
+		}
+	}
+}
+
+func parseDashboardKey(input string) dashboardKey {
+	switch input {
+	case "\x1b[A", "\x1bOA", "k":
+		return keyUp
+	case "\x1b[B", "\x1bOB", "j":
+		return keyDown
+	case "\r", "\n":
+		return keyEnter
+	case "\x1b", "\x7f", "\b", "h":
+		return keyBack
+	case "q", "\x03":
+		return keyQuit
+	case "c":
+		return keyCancel
+	case "r":
+		return keyRetry
+	case "d":
+		return keyDownload
+	case " ":
+		return keyRefresh
+	}
+	return keyNone
+}
+
+func (d *dashboard) handleKey(ctx context.Context, key dashboardKey) {
+	confirm := d.confirm
+	d.confirm = ""
+	switch key {
+	case keyUp:
+		if d.detail == nil && d.selected > 0 {
+			d.selected--
+		}
+	case keyDown:
+		if d.detail == nil && d.selected < len(d.requests)-1 {
+			d.selected++
+		}
+	case keyEnter:
+		if request := d.current(); request != nil && d.detail == nil {
+			d.detail = &dashboardDetail{request: *request}
+			d.reload(ctx)
+		}
+	case keyBack:
+		d.detail = nil
+	case keyRefresh:
+		d.reload(ctx)
+	case keyCancel:
+		request := d.current()
+		if request == nil {
+			return
+		}
+		if confirm != request.RequestID {
+			d.confirm = request.RequestID
+			d.message = "Press c again to cancel " + request.RequestID
+			return
+		}
+		d.cancel(ctx, request.RequestID)
+	case keyRetry:
+		if request := d.current(); request != nil {
+			d.retry(ctx, *request)
+		}
+	case keyDownload:
+		if request := d.current(); request != nil {
+			d.download(ctx, request.RequestID)
+		}
+	}
+}
+
+// current is the request the actions apply to, the one shown in detail or
+// the one selected in the list.
+func (d *dashboard) current() *client.TrainingRequest {
+	if d.detail != nil {
+		return &d.detail.request
+	}
+	if d.selected < len(d.requests) {
+		return &d.requests[d.selected]
+	}
+	return nil
+}
+
+func (d *dashboard) reload(ctx context.Context) {
+	if err := d.refresh(ctx); err != nil && ctx.Err() == nil {
+		d.message = "Refresh failed: " + err.Error()
+	}
+}
+
+func (d *dashboard) refresh(ctx context.Context) error {
+	callCtx, cancel := context.WithTimeout(ctx, dashboardCallTimeout)
+	defer cancel()
+	list, err := d.service.ListRequests(callCtx)
+	if err != nil {
+		return err
+	}
+	if list.ResponseCode != "00" {
+		return fmt.Errorf("server answered: %s", list.ResponseMessage)
+	}
+	selectedID := ""
+	if request := d.current(); request != nil && d.detail == nil {
+		selectedID = request.RequestID
+	}
+	d.requests = list.Data
+	d.selected = min(d.selected, max(len(d.requests)-1, 0))
+	for i, request := range d.requests {
+		if request.RequestID == selectedID {
+			d.selected = i
+		}
+	}
+	d.refreshed = deps.Now()
+	if d.detail == nil {
+		return nil
+	}
+
+	requestID := d.detail.request.RequestID
+	status, err := d.service.RequestStatus(callCtx, requestID)
+	if err != nil {
+		return err
+	}
+	d.detail.request = status.Data
+	if logs, err := d.service.RequestLogs(callCtx, requestID); err == nil {
+		d.detail.logs = logs.Data
+	}
+	if status.Data.Status == client.TrainingStatusSucceeded {
+		if artifacts, err := d.service.RequestArtifacts(callCtx, requestID); err == nil {
+			d.detail.artifacts = artifacts.Data
+		}
+	}
+	return nil
+}
+
+func (d *dashboard) cancel(ctx context.Context, requestID string) {
+	callCtx, cancel := context.WithTimeout(ctx, dashboardCallTimeout)
+	defer cancel()
+	result, err := d.service.CancelRequest(callCtx, requestID)
+	switch {
+	case err != nil:
+		d.message = "Cancel failed: " + err.Error()
+	case result.ResponseCode != "00":
+		d.message = "Cancel failed: " + result.ResponseMessage
+	default:
+		d.message = "Cancelled " + requestID
+	}
+	d.reload(ctx)
+}
+
+func (d *dashboard) retry(ctx context.Context, request client.TrainingRequest) {
+	callCtx, cancel := context.WithTimeout(ctx, dashboardCallTimeout)
+	defer cancel()
+	result, err := d.service.RetryRequest(callCtx, request.RequestID)
+	switch {
+	case err != nil:
+		d.message = "Retry failed: " + err.Error()
+	case result.ResponseCode != "00":
+		d.message = "Retry failed: " + result.ResponseMessage
+	default:
+		recordJob(jobRecord{
+			RequestID: result.Data.RequestID,
+			DatasetID: request.DatasetID,
+			SensoryID: request.SensoryID,
+			CreatedAt: deps.Now(),
+		})
+		d.message = "Retried " + request.RequestID + " as " + result.Data.RequestID
+	}
+	d.reload(ctx)
+}
+
+// download saves every artifact of the request to <output-dir>/<request-id>.
+func (d *dashboard) download(ctx context.Context, requestID string) {
+	callCtx, cancel := context.WithTimeout(ctx, dashboardCallTimeout)
+	defer cancel()
+	artifacts, err := d.service.RequestArtifacts(callCtx, requestID)
+	if err != nil {
+		d.message = "Download failed: " + err.Error()
+		return
+	}
+	if len(artifacts.Data) == 0 {
+		d.message = "No artifacts for " + requestID + " yet"
+		return
+	}
+	dir := filepath.Join(d.outputDir, requestID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		d.message = "Download failed: " + err.Error()
+		return
+	}
+	for _, artifact := range artifacts.Data {
+		if err := d.downloadArtifact(ctx, artifact, filepath.Join(dir, filepath.Base(artifact.Name))); err != nil {
+			d.message = "Download of " + artifact.Name + " failed: " + err.Error()
+			return
+		}
+	}
+	d.message = fmt.Sprintf("Downloaded %d artifacts to %s", len(artifacts.Data), dir)
+}
+
+func (d *dashboard) downloadArtifact(ctx context.Context, artifact client.TrainingArtifact, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := d.service.DownloadArtifact(ctx, artifact, file); err != nil {
+		_ = file.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
+
+func (d *dashboard) render(outputFd uintptr) {
+	width, height, err := terminal.Size(outputFd)
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+	var lines []string
+	if d.detail != nil {
+		lines = d.detailLines(height)
+	} else {
+		lines = d.listLines(height)
+	}
+	var screen strings.Builder
+	screen.WriteString("\x1b[H\x1b[2J")
+	for i, line := range lines {
+		if i == height {
+			break
+		}
+		screen.WriteString(fitLine(line, width))
+		if i < len(lines)-1 && i < height-1 {
+			screen.WriteString("\n")
+		}
+	}
+	fmt.Fprint(deps.Stdout, screen.String())
+}
+
+func (d *dashboard) header() string {
+	return fmt.Sprintf("Synexis training requests - profile %s - refreshed %s, every %s",
+		deps.NewStorage("").Profile(), d.refreshed.Local().Format(time.TimeOnly), d.interval)
+}
+
+func (d *dashboard) listLines(height int) []string {
+	lines := []string{d.header(), "", fmt.Sprintf("  %-28s %-10s %-19s  %-19s  %s", "REQUEST ID", "STATUS", "CREATED", "UPDATED", "DATASET")}
+	rows := max(height-6, 1)
+	first := max(0, min(d.selected-rows/2, len(d.requests)-rows))
+	for i := first; i < len(d.requests) && i < first+rows; i++ {
+		request := d.requests[i]
+		status := colorStatus(request.Status)
+		if i == d.selected {
+			// a color reset would also end the highlight
+			status = fmt.Sprintf("%-10s", request.Status)
+		}
+		row := fmt.Sprintf("%-28s %s %-19s  %-19s  %s", request.RequestID, status,
+			request.CreatedAt.Local().Format(time.DateTime), request.UpdatedAt.Local().Format(time.DateTime), request.DatasetID)
+		if i == d.selected {
+			row = "\x1b[7m> " + row + "\x1b[0m"
+		} else {
+			row = "  " + row
+		}
+		lines = append(lines, row)
+	}
+	if len(d.requests) == 0 {
+		lines = append(lines, "  No training requests yet.")
+	}
+	return append(lines, "", d.message, "Up/Down select  Enter details  c cancel  r retry  d download  Space refresh  q quit")
+}
+
+func (d *dashboard) detailLines(height int) []string {
+	request := d.detail.request
+	lines := []string{
+		d.header(),
+		"",
+		"Request:   " + request.RequestID + "  " + colorStatus(request.Status),
+		"Sensory:   " + d.describeUpload("sensory", request.SensoryID),
+		"Dataset:   " + d.describeUpload("dataset", request.DatasetID),
+		"Created:   " + request.CreatedAt.Local().Format(time.DateTime) + "  updated " + request.UpdatedAt.Local().Format(time.DateTime),
+	}
+	if request.Message != "" {
+		lines = append(lines, "Message:   "+request.Message)
+	}
+	if len(request.Metrics) > 0 {
+		names := make([]string, 0, len(request.Metrics))
+		for name := range request.Metrics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var metrics []string
+		for _, name := range names {
+			metrics = append(metrics, fmt.Sprintf("%s %.4g", name, request.Metrics[name]))
+		}
+		lines = append(lines, "Metrics:   "+strings.Join(metrics, "  "))
+	}
+	if len(d.detail.artifacts) > 0 {
+		var artifacts []string
+		for _, artifact := range d.detail.artifacts {
+			artifacts = append(artifacts, fmt.Sprintf("%s (%d bytes)", artifact.Name, artifact.Size))
+		}
+		lines = append(lines, "Artifacts: "+strings.Join(artifacts, ", "))
+	}
+	lines = append(lines, "", "Logs:")
+	logs := d.detail.logs
+	if len(logs) > dashboardLogLines {
+		logs = logs[len(logs)-dashboardLogLines:]
+	}
+	// keep the newest log lines that fit above the footer
+	if room := height - len(lines) - 3; room < len(logs) {
+		logs = logs[len(logs)-max(room, 0):]
+	}
+	for _, entry := range logs {
+		lines = append(lines, "  "+entry.Time.Local().Format(time.TimeOnly)+" "+entry.Message)
+	}
+	return append(lines, "", d.message, "Esc back  c cancel  r retry  d download  Space refresh  q quit")
+}
+
+// describeUpload adds the local file name when the upload came from here.
+func (d *dashboard) describeUpload(kind, id string) string {
+	if d.store == nil {
+		return id
+	}
+	uploads, _ := listUploads(d.store, kind)
+	for _, upload := range uploads {
+		if upload.ID == id {
+			return id + " (" + upload.File + ")"
+		}
+	}
+	return id
+}
+
+func colorStatus(status string) string {
+	color := "0"
+	switch status {
+	case client.TrainingStatusSucceeded:
+		color = "32"
+	case client.TrainingStatusFailed:
+		color = "31"
+	case client.TrainingStatusRunning:
+		color = "33"
+	case client.TrainingStatusCancelled:
+		color = "2"
+	}
+	return fmt.Sprintf("\x1b[%sm%-10s\x1b[0m", color, status)
+}
+
+// fitLine cuts line to width visible characters, escape sequences do not
+// count and are kept so colors are closed properly.
+func fitLine(line string, width int) string {
+	var out strings.Builder
+	visible := 0
+	inEscape := false
+	for _, r := range line {
+		switch {
+		case r == '\x1b':
+			inEscape = true
+		case inEscape:
+			if r >= '@' && r <= '~' && r != '[' {
+				inEscape = false
+			}
+		case visible == width:
+			continue
+		default:
+			visible++
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
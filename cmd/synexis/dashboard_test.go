@@ -0,0 +1,177 @@
+package synexis
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/synxms/synexis/pkg/client"
+	"github.com/synxms/synexis/pkg/mockserver"
+)
+
+func TestParseDashboardKey(t *testing.T) {
+	tests := map[string]dashboardKey{
+		"\x1b[A": keyUp,
+		"\x1bOA": keyUp,
+		"k":      keyUp,
+		"\x1b[B": keyDown,
+		"j":      keyDown,
+		"\r":     keyEnter,
+		"\x1b":   keyBack,
+		"\x7f":   keyBack,
+		"q":      keyQuit,
+		"\x03":   keyQuit,
+		"c":      keyCancel,
+		"r":      keyRetry,
+		"d":      keyDownload,
+		" ":      keyRefresh,
+		"x":      keyNone,
+		"\x1b[C": keyNone,
+	}
+	for input, want := range tests {
+		if got := parseDashboardKey(input); got != want {
+			t.Errorf("parseDashboardKey(%q) = %d, want %d", input, got, want)
+		}
+	}
+}
+
+func TestFitLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		width int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"truncated line", 9, "truncated"},
+		{"\x1b[32msucceeded\x1b[0m", 4, "\x1b[32msucc\x1b[0m"},
+		{"\x1b[7m> request\x1b[0m", 20, "\x1b[7m> request\x1b[0m"},
+		{"", 5, ""},
+	}
+	for _, tt := range tests {
+		if got := fitLine(tt.line, tt.width); got != tt.want {
+			t.Errorf("fitLine(%q, %d) = %q, want %q", tt.line, tt.width, got, tt.want)
+		}
+	}
+}
+
+// newTestDashboard signs in and submits count requests, then returns a
+// dashboard over them that has loaded the list.
+func newTestDashboard(t *testing.T, count int, opts ...mockserver.Option) (*cliEnv, *dashboard) {
+	t.Helper()
+	e := newCLIEnv(t, opts...)
+	e.login()
+	sensoryPath, datasetPath := e.uploadInputs("train.csv")
+	for i := 0; i < count; i++ {
+		e.mustRun("service", "sentinel", "request", "-s", sensoryPath, "-d", datasetPath)
+	}
+	authenticationService, store, closeStore := openAuthenticatedService(nil)
+	t.Cleanup(closeStore)
+	d := &dashboard{service: authenticationService, store: store, outputDir: t.TempDir(), interval: time.Second}
+	d.reload(context.Background())
+	if len(d.requests) != count {
+		t.Fatalf("dashboard lists %d requests, want %d: %s", len(d.requests), count, d.message)
+	}
+	return e, d
+}
+
+func TestDashboardNavigation(t *testing.T) {
+	_, d := newTestDashboard(t, 2, mockserver.WithTrainingStep(time.Hour))
+	ctx := context.Background()
+	d.handleKey(ctx, keyUp)
+	if d.selected != 0 {
+		t.Errorf("up at the top selected %d", d.selected)
+	}
+	d.handleKey(ctx, keyDown)
+	d.handleKey(ctx, keyDown)
+	if d.selected != 1 {
+		t.Errorf("down past the end selected %d", d.selected)
+	}
+	selected := d.requests[1].RequestID
+	if lines := strings.Join(d.listLines(24), "\n"); !strings.Contains(lines, "\x1b[7m> "+selected) {
+		t.Errorf("list does not highlight %s:\n%s", selected, lines)
+	}
+
+	d.handleKey(ctx, keyEnter)
+	if d.detail == nil || d.detail.request.RequestID != selected || len(d.detail.logs) == 0 {
+		t.Fatalf("enter opened %+v, want the details of %s", d.detail, selected)
+	}
+	if lines := strings.Join(d.detailLines(24), "\n"); !strings.Contains(lines, "Request:   "+selected) || !strings.Contains(lines, "request queued") {
+		t.Errorf("details of %s:\n%s", selected, lines)
+	}
+	d.handleKey(ctx, keyDown)
+	if d.selected != 1 || d.current().RequestID != selected {
+		t.Errorf("down in the details moved to %s", d.current().RequestID)
+	}
+	d.handleKey(ctx, keyBack)
+	if d.detail != nil {
+		t.Error("back left the details open")
+	}
+}
+
+func TestDashboardCancelAndRetry(t *testing.T) {
+	e, d := newTestDashboard(t, 1, mockserver.WithTrainingStep(time.Hour))
+	ctx := context.Background()
+	requestID := d.requests[0].RequestID
+
+	d.handleKey(ctx, keyCancel)
+	if d.confirm != requestID || !strings.Contains(d.message, "Press c again") {
+		t.Errorf("first c set confirm %q, message %q", d.confirm, d.message)
+	}
+	if status := e.mock.Requests()[0].Status; status != client.TrainingStatusPending {
+		t.Fatalf("request %s before confirming", status)
+	}
+	d.handleKey(ctx, keyCancel)
+	if d.message != "Cancelled "+requestID || e.mock.Requests()[0].Status != client.TrainingStatusCancelled {
+		t.Fatalf("second c left message %q and status %s", d.message, e.mock.Requests()[0].Status)
+	}
+
+	// another key in between asks again
+	d.handleKey(ctx, keyCancel)
+	d.handleKey(ctx, keyRefresh)
+	d.handleKey(ctx, keyCancel)
+	if d.confirm == "" {
+		t.Error("cancel went through without asking again after another key")
+	}
+
+	d.selected = 0
+	d.handleKey(ctx, keyRetry)
+	if !strings.HasPrefix(d.message, "Retried "+requestID+" as ") {
+		t.Fatalf("retry left message %q", d.message)
+	}
+	if len(d.requests) != 2 {
+		t.Errorf("dashboard lists %d requests after retry, want 2", len(d.requests))
+	}
+	retried := strings.TrimPrefix(d.message, "Retried "+requestID+" as ")
+	if job, ok := findJob(d.store, retried); !ok || job.DatasetID != d.requests[0].DatasetID {
+		t.Errorf("retried request %s is recorded as %+v, %v", retried, job, ok)
+	}
+}
+
+func TestDashboardDownload(t *testing.T) {
+	_, d := newTestDashboard(t, 1, mockserver.WithTrainingStep(time.Hour))
+	ctx := context.Background()
+	requestID := d.requests[0].RequestID
+	d.handleKey(ctx, keyDownload)
+	if d.message != "No artifacts for "+requestID+" yet" {
+		t.Errorf("download of a pending request left message %q", d.message)
+	}
+
+	_, d = newTestDashboard(t, 1, mockserver.WithTrainingStep(time.Microsecond))
+	requestID = d.requests[0].RequestID
+	d.handleKey(ctx, keyEnter)
+	if lines := strings.Join(d.detailLines(40), "\n"); !strings.Contains(lines, "Artifacts: model.onnx") {
+		t.Errorf("details of a finished request:\n%s", lines)
+	}
+	d.handleKey(ctx, keyDownload)
+	dir := filepath.Join(d.outputDir, requestID)
+	if d.message != "Downloaded 2 artifacts to "+dir {
+		t.Fatalf("download left message %q", d.message)
+	}
+	model, err := os.ReadFile(filepath.Join(dir, "model.onnx"))
+	if err != nil || string(model) != "mock model for "+requestID+"\n" {
+		t.Errorf("downloaded model %q, %v", model, err)
+	}
+}
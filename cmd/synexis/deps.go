@@ -0,0 +1,58 @@
+package synexis
+
+import (
+	"fmt"
+	"github.com/synxms/synexis/pkg/client"
+	"github.com/synxms/synexis/pkg/storage"
+	"github.com/synxms/synexis/src/service"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Dependencies is everything commands take from the outside world, so that
+// a test can run the command tree against buffers, a scratch store and a
+// fake server.
+type Dependencies struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// NewStorage returns the store of the named profile, or of the active
+	// profile when name is empty.
+	NewStorage func(name string) storage.Storage
+	// NewClient builds the service client for baseUrl on top of the
+	// configured http client.
+	NewClient func(baseUrl string, httpClient *http.Client, opts ...client.Option) service.Authentication
+	Now       func() time.Time
+	// Exit ends the command with code. It must not return, a test double
+	// should panic and recover in the test.
+	Exit func(code int)
+}
+
+func DefaultDependencies() Dependencies {
+	return Dependencies{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		NewStorage: func(name string) storage.Storage {
+			if name == "" {
+				return storage.NewStorage()
+			}
+			return storage.NewProfileStorage(name)
+		},
+		NewClient: service.NewAuthentication,
+		Now:       time.Now,
+		Exit:      os.Exit,
+	}
+}
+
+// deps are the dependencies of the command tree built last.
+var deps = DefaultDependencies()
+
+// fatalln reports an unrecoverable failure in the format of log.Fatalln and
+// exits.
+func fatalln(v ...interface{}) {
+	fmt.Fprint(deps.Stderr, deps.Now().Format("2006/01/02 15:04:05 ")+fmt.Sprintln(v...))
+	deps.Exit(1)
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package synexis
+
+import "syscall"
+
+// detachedProcessAttributes starts the agent in its own session so it
+// outlives the terminal that launched it.
+func detachedProcessAttributes() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,9 @@
+package synexis
+
+import "syscall"
+
+const detachedProcess = 0x00000008
+
+func detachedProcessAttributes() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP}
+}
@@ -0,0 +1,79 @@
+package synexis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/synxms/synexis/pkg/client"
+	"github.com/synxms/synexis/pkg/mockserver"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func devMockServer(cmd *cobra.Command, _ []string) error {
+	host, _ := cmd.Flags().GetString("host")
+	port, _ := cmd.Flags().GetInt("port")
+	step, _ := cmd.Flags().GetDuration("training-step")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	encodings, _ := cmd.Flags().GetStringSlice("upload-encodings")
+	var faults mockserver.Faults
+	faults.Latency, _ = cmd.Flags().GetDuration("latency")
+	faults.ErrorRate, _ = cmd.Flags().GetFloat64("error-rate")
+	faults.ErrorStatus, _ = cmd.Flags().GetInt("error-status")
+	faults.TruncateRate, _ = cmd.Flags().GetFloat64("truncate-rate")
+	faults.PathPrefix, _ = cmd.Flags().GetString("fault-path")
+
+	opts := []mockserver.Option{mockserver.WithFaults(faults), mockserver.WithTrainingStep(step), mockserver.WithUploadEncodings(encodings...)}
+	if noDiscovery, _ := cmd.Flags().GetBool("no-service-discovery"); noDiscovery {
+		opts = append(opts, mockserver.WithServices())
+	}
+	if !quiet {
+		opts = append(opts, mockserver.WithLogger(log.New(deps.Stderr, "", log.LstdFlags).Printf))
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		fatalln("Failed to listen:", err)
+	}
+	baseUrl := "http://" + listener.Addr().String()
+	server := &http.Server{Handler: mockserver.New(opts...), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-cmd.Context().Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}()
+
+	fmt.Fprintln(deps.Stdout, "Mock server listening on", baseUrl)
+	fmt.Fprintln(deps.Stdout, "Point the CLI at it with: synexis server-base-url", baseUrl)
+	fmt.Fprintln(deps.Stdout, "Sign in by opening:", baseUrl+"/_mock/authorize")
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatalln("Mock server stopped:", err)
+	}
+	return nil
+}
+
+func InitializeDevCmd(devCmd *cobra.Command) {
+	mockServerCmd := &cobra.Command{
+		Use:   "mock-server",
+		Short: "Run an in-memory Synexis API for local development",
+		Long:  `Run an in-memory Synexis API implementing every endpoint the CLI uses, with optional latency and error injection. Faults can also be changed at runtime through /_mock/faults`,
+		Args:  cobra.NoArgs,
+		RunE:  devMockServer,
+	}
+	mockServerCmd.Flags().String("host", "127.0.0.1", "Address to listen on")
+	mockServerCmd.Flags().IntP("port", "p", 8787, "Port to listen on, 0 picks a free one")
+	mockServerCmd.Flags().Duration("training-step", 5*time.Second, "How long training requests stay in each state")
+	mockServerCmd.Flags().Duration("latency", 0, "Delay added to every API response")
+	mockServerCmd.Flags().Float64("error-rate", 0, "Share of API requests answered with --error-status, between 0 and 1")
+	mockServerCmd.Flags().Int("error-status", 500, "Status code of injected errors, for example 401 or 503")
+	mockServerCmd.Flags().Float64("truncate-rate", 0, "Share of API responses cut off halfway, between 0 and 1")
+	mockServerCmd.Flags().String("fault-path", "", "Only inject faults on endpoints starting with this path")
+	mockServerCmd.Flags().StringSlice("upload-encodings", []string{client.EncodingGzip, client.EncodingZstd}, "Content encodings accepted for uploads, empty to accept only uncompressed uploads")
+	mockServerCmd.Flags().Bool("no-service-discovery", false, "Do not serve the service list, like servers that predate it")
+	mockServerCmd.Flags().BoolP("quiet", "q", false, "Do not log requests")
+	devCmd.AddCommand(mockServerCmd)
+}
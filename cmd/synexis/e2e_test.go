@@ -0,0 +1,197 @@
+package synexis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/synxms/synexis/pkg/mockserver"
+)
+
+// writeFile creates a file in a fresh directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readID(t *testing.T, path string) string {
+	t.Helper()
+	id, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(id))
+}
+
+// uploadInputs uploads a sensory config and a dataset and returns the files
+// holding their IDs.
+func (e *cliEnv) uploadInputs(datasetName string) (sensoryPath, datasetPath string) {
+	e.t.Helper()
+	dir := e.t.TempDir()
+	sensoryPath, datasetPath = filepath.Join(dir, "sensory.id"), filepath.Join(dir, "dataset.id")
+	e.mustRun("service", "sentinel", "sensory", writeFile(e.t, "sensory.json", `{"sensors": ["camera"]}`), "-o", sensoryPath)
+	e.mustRun("service", "sentinel", "dataset", writeFile(e.t, datasetName, "x,y\n1,2\n"), "-o", datasetPath)
+	return sensoryPath, datasetPath
+}
+
+func TestEndToEndLogin(t *testing.T) {
+	e := newCLIEnv(t)
+	e.mustRun("server-base-url", e.server.URL)
+	if result := e.run("whoami"); result.Code == 0 {
+		t.Fatalf("whoami succeeded before signing in:\n%s", result.Stdout)
+	}
+	e.login()
+	if !strings.HasPrefix(e.opened[0], e.server.URL+"/_mock/authorize") {
+		t.Errorf("authenticate opened %s, want the server's sign-in page", e.opened[0])
+	}
+	result := e.mustRun("whoami")
+	if !strings.Contains(result.Stdout, "mock@synexis.test") {
+		t.Errorf("whoami printed\n%s\nwant the signed in account", result.Stdout)
+	}
+	e.mustRun("logout")
+	if result := e.run("whoami"); result.Code == 0 {
+		t.Fatalf("whoami succeeded after logout:\n%s", result.Stdout)
+	}
+}
+
+func TestEndToEndUpload(t *testing.T) {
+	tests := []struct {
+		compress     string
+		wantEncoding string
+	}{
+		{"none", ""},
+		{"gzip", "gzip"},
+		{"zstd", "zstd"},
+		{"auto", "zstd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.compress, func(t *testing.T) {
+			e := newCLIEnv(t)
+			e.login()
+			content := strings.Repeat("x,y\n1,2\n", 2000)
+			path := writeFile(t, "train.csv", content)
+			output := filepath.Join(t.TempDir(), "dataset.id")
+			e.mustRun("service", "sentinel", "dataset", path, "-o", output, "--compress", tt.compress)
+
+			uploads := e.mock.Uploads()
+			if len(uploads) != 1 {
+				t.Fatalf("server received %d uploads, want 1", len(uploads))
+			}
+			upload := uploads[0]
+			digest := sha256.Sum256([]byte(content))
+			if upload.ID != readID(t, output) {
+				t.Errorf("saved dataset id %s, server assigned %s", readID(t, output), upload.ID)
+			}
+			if upload.Size != int64(len(content)) || upload.SHA256 != hex.EncodeToString(digest[:]) {
+				t.Errorf("server received %d bytes with digest %s, want the file unchanged", upload.Size, upload.SHA256)
+			}
+			if upload.ContentEncoding != tt.wantEncoding {
+				t.Errorf("upload was sent with encoding %q, want %q", upload.ContentEncoding, tt.wantEncoding)
+			}
+		})
+	}
+}
+
+func TestEndToEndSubmit(t *testing.T) {
+	tests := []struct {
+		name       string
+		dataset    string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{"succeeded", "train.csv", 0, "succeeded", ""},
+		{"failed", "fail.csv", 1, "", "Training failed: training diverged"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newCLIEnv(t, mockserver.WithTrainingStep(time.Microsecond))
+			e.login()
+			sensoryPath, datasetPath := e.uploadInputs(tt.dataset)
+			result := e.run("service", "sentinel", "submit", "-s", sensoryPath, "-d", datasetPath)
+			if result.Code != tt.wantCode {
+				t.Fatalf("submit exited with %d, want %d\nstdout:\n%s\nstderr:\n%s", result.Code, tt.wantCode, result.Stdout, result.Stderr)
+			}
+			if !strings.Contains(result.Stdout, "Request ID:") || !strings.Contains(result.Stdout, tt.wantStdout) {
+				t.Errorf("submit printed\n%s\nwant the request id and %q", result.Stdout, tt.wantStdout)
+			}
+			if !strings.Contains(result.Stderr, tt.wantStderr) {
+				t.Errorf("submit reported\n%s\nwant %q", result.Stderr, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestEndToEndFaults(t *testing.T) {
+	tests := []struct {
+		name       string
+		faults     mockserver.Faults
+		wantStderr string
+	}{
+		{"unauthorized", mockserver.Faults{ErrorRate: 1, ErrorStatus: 401}, "Request status failed, reason : injected fault"},
+		{"server error", mockserver.Faults{ErrorRate: 1, ErrorStatus: 500}, "Request status failed, reason : injected fault"},
+		{"unavailable", mockserver.Faults{ErrorRate: 1, ErrorStatus: 503}, "Request status failed, reason : injected fault"},
+		{"truncated body", mockserver.Faults{TruncateRate: 1}, "unexpected response from server: 200 OK"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newCLIEnv(t)
+			e.login()
+			sensoryPath, datasetPath := e.uploadInputs("train.csv")
+			e.mustRun("service", "sentinel", "request", "-s", sensoryPath, "-d", datasetPath)
+			requestID := e.mock.Requests()[0].RequestID
+			tt.faults.PathPrefix = "/api/v1/sentinel"
+			e.mock.SetFaults(tt.faults)
+
+			result := e.run("service", "sentinel", "status", requestID, "--retries", "0")
+			if result.Code != 1 {
+				t.Fatalf("status exited with %d under fault, want 1\nstdout:\n%s\nstderr:\n%s", result.Code, result.Stdout, result.Stderr)
+			}
+			if !strings.Contains(result.Stderr, tt.wantStderr) {
+				t.Errorf("status reported\n%s\nwant %q", result.Stderr, tt.wantStderr)
+			}
+
+			e.mock.SetFaults(mockserver.Faults{})
+			if result := e.mustRun("service", "sentinel", "status", requestID); !strings.Contains(result.Stdout, requestID) {
+				t.Errorf("status printed\n%s\nwant request %s once the fault cleared", result.Stdout, requestID)
+			}
+		})
+	}
+}
+
+func TestEndToEndRerunInterrupted(t *testing.T) {
+	e := newCLIEnv(t)
+	e.login()
+	if result := e.run("service", "sentinel", "dataset", "--rerun-interrupted"); result.Code == 0 || !strings.Contains(result.Stderr, "no interrupted dataset upload") {
+		t.Errorf("rerun without an interrupted upload exited with %d and reported\n%s", result.Code, result.Stderr)
+	}
+
+	path := writeFile(t, "train.csv", "x,y\n1,2\n")
+	output := filepath.Join(t.TempDir(), "dataset.id")
+	store := deps.NewStorage("")
+	if err := store.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	savePendingUpload(store, &pendingUpload{Kind: "dataset", File: path, Output: output})
+
+	result := e.mustRun("service", "sentinel", "dataset", "--rerun-interrupted")
+	if !strings.Contains(result.Stdout, "again from the start") {
+		t.Errorf("rerun printed\n%s\nwant it to say the upload starts over", result.Stdout)
+	}
+	uploads := e.mock.Uploads()
+	if len(uploads) != 1 || uploads[0].ID != readID(t, output) || uploads[0].Size != 8 {
+		t.Errorf("server received %+v, want the whole interrupted file once", uploads)
+	}
+	if raw, _ := store.Get(pendingUploadKey("dataset")); raw != "" {
+		t.Errorf("interrupted upload still recorded after it finished: %s", raw)
+	}
+}
@@ -0,0 +1,272 @@
+package synexis
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/synxms/synexis/pkg/mockserver"
+	"go.etcd.io/bbolt"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// scrubbers replace what differs between runs, after the server url and the
+// temporary directories have been replaced.
+var scrubbers = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`eyJ[\w-]*\.[\w-]*\.[\w-]*`), "{{jwt}}"},
+	{regexp.MustCompile(`\d{4}[/-]\d\d[/-]\d\d[ T]\d\d:\d\d:\d\d(\.\d+)?(Z|[+-]\d\d:\d\d)?`), "{{time}}"},
+	{regexp.MustCompile(`\b(dataset|sensory|request)-(\d{4})-[0-9a-f]{6}\b`), "$1-$2-{{random}}"},
+	{regexp.MustCompile(`\bSYX[A-Z]{3}-\w{5}-\w{10}-`), "{{apikey}}-"},
+	{regexp.MustCompile(`\bmock-[0-9a-f]{8}\b`), "{{kid}}"},
+	{regexp.MustCompile(`\b(exp|iat): \d+`), "$1: {{unix}}"},
+	{regexp.MustCompile(`\.v(\d+)-\d{14}\.bak`), ".v$1-{{stamp}}.bak"},
+	{regexp.MustCompile(`\(secret, \d+ bytes\)`), "(secret, {{n}} bytes)"},
+	{regexp.MustCompile(`\b(\d+h)?(\d+m)?\d+(\.\d+)?(s|ms|µs)\b`), "{{duration}}"},
+	{regexp.MustCompile(`\((in {{duration}}|{{duration}} ago)\)`), "({{relative}})"},
+}
+
+func (e *cliEnv) scrub(output string) string {
+	output = strings.ReplaceAll(output, e.server.URL, "{{server}}")
+	output = strings.ReplaceAll(output, filepath.Dir(e.home), "{{tmp}}")
+	for _, s := range scrubbers {
+		output = s.pattern.ReplaceAllString(output, s.replacement)
+	}
+	return output
+}
+
+// vars are substituted into the arguments of a golden case as $NAME.
+type vars map[string]string
+
+func withBaseURL(e *cliEnv, _ vars) {
+	e.mustRun("server-base-url", e.server.URL)
+}
+
+func loggedIn(e *cliEnv, _ vars) {
+	e.login()
+}
+
+func withUploads(datasetName string) func(e *cliEnv, v vars) {
+	return func(e *cliEnv, v vars) {
+		e.login()
+		v["SENSORY"], v["DATASET"] = e.uploadInputs(datasetName)
+	}
+}
+
+func withRequest(datasetName string) func(e *cliEnv, v vars) {
+	return func(e *cliEnv, v vars) {
+		e.login()
+		v["SENSORY"], v["DATASET"] = e.uploadInputs(datasetName)
+		e.mustRun("service", "sentinel", "request", "-s", v["SENSORY"], "-d", v["DATASET"])
+		v["REQUEST"] = e.mock.Requests()[0].RequestID
+	}
+}
+
+func withFile(name, content string, setup func(e *cliEnv, v vars)) func(e *cliEnv, v vars) {
+	return func(e *cliEnv, v vars) {
+		if setup != nil {
+			setup(e, v)
+		}
+		v["FILE"] = writeFile(e.t, name, content)
+	}
+}
+
+func withExport(e *cliEnv, v vars) {
+	withBaseURL(e, v)
+	v["FILE"] = filepath.Join(e.t.TempDir(), "archive.json")
+	e.mustRun("store", "export", "-o", v["FILE"])
+	e.mustRun("server-base-url", "https://other.example")
+}
+
+// withLegacyStore leaves a store written before profiles existed, which the
+// next command migrates.
+func withLegacyStore(e *cliEnv, _ vars) {
+	db, err := bbolt.Open(filepath.Join(e.home, "synexis.db"), 0o600, nil)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte("synexis-cli-cache.db"))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte("base_url"), []byte(e.server.URL))
+	})
+	if err != nil {
+		e.t.Fatal(err)
+	}
+}
+
+// withForgedTokens stores tokens with the claims the server issues, signed
+// by a key it never published.
+func withForgedTokens(e *cliEnv, _ vars) {
+	e.login()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	for _, name := range []string{"accesstoken", "refreshtoken"} {
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+			"sub":       "user-mock",
+			"companyId": "company-mock",
+			"iss":       e.server.URL,
+			"iat":       time.Now().Unix(),
+			"exp":       time.Now().Add(time.Hour).Unix(),
+		})
+		token.Header["kid"] = "forged"
+		signed, err := token.SignedString(key)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		e.mustRun("token", "set", name, signed)
+	}
+}
+
+func withFaults(faults mockserver.Faults) func(e *cliEnv, v vars) {
+	return func(e *cliEnv, v vars) {
+		e.login()
+		e.mock.SetFaults(faults)
+	}
+}
+
+// TestGolden runs every command that finishes on its own once on the way to
+// success and once into a failure, and compares what it prints with
+// testdata/golden. Run go test -update to accept changed output.
+func TestGolden(t *testing.T) {
+	unauthorized := mockserver.Faults{ErrorRate: 1, ErrorStatus: 401, PathPrefix: "/api/v1"}
+	tests := []struct {
+		name  string
+		setup func(e *cliEnv, v vars)
+		args  []string
+	}{
+		{"unknown-command", nil, []string{"nonsense"}},
+
+		{"server-base-url", nil, []string{"server-base-url", "https://synexis.example"}},
+		{"server-base-url-invalid", nil, []string{"server-base-url", "not a url"}},
+		{"server-base-url-missing", nil, []string{"server-base-url"}},
+		{"authenticate", withBaseURL, []string{"authenticate"}},
+		{"authenticate-no-base-url", nil, []string{"authenticate"}},
+
+		{"token-set-accesstoken", withBaseURL, []string{"token", "set", "accesstoken", "token"}},
+		{"token-set-missing", withBaseURL, []string{"token", "set", "accesstoken"}},
+		{"token-get-accesstoken", loggedIn, []string{"token", "get", "accesstoken"}},
+		{"token-get-refreshtoken", loggedIn, []string{"token", "get", "refreshtoken"}},
+		{"token-get-missing", withBaseURL, []string{"token", "get", "accesstoken"}},
+		{"token-check", loggedIn, []string{"token", "check"}},
+		{"token-check-min-remaining", loggedIn, []string{"token", "check", "--min-remaining", "24h"}},
+		{"token-check-forged", withForgedTokens, []string{"token", "check"}},
+		{"token-check-logged-out", withBaseURL, []string{"token", "check"}},
+		{"token-refresh", loggedIn, []string{"token", "refresh"}},
+		{"token-refresh-logged-out", withBaseURL, []string{"token", "refresh"}},
+		{"token-inspect", loggedIn, []string{"token", "inspect", "accesstoken"}},
+		{"token-inspect-invalid", withBaseURL, []string{"token", "inspect", "not.a.jwt"}},
+
+		{"whoami", loggedIn, []string{"whoami"}},
+		{"whoami-json", loggedIn, []string{"whoami", "-o", "json"}},
+		{"whoami-logged-out", withBaseURL, []string{"whoami"}},
+		{"whoami-no-base-url", nil, []string{"whoami"}},
+		{"logout", loggedIn, []string{"logout"}},
+		{"logout-logged-out", withBaseURL, []string{"logout"}},
+
+		{"store-path", nil, []string{"store", "path"}},
+		{"store-list", loggedIn, []string{"store", "list"}},
+		{"store-get", withBaseURL, []string{"store", "get", "base_url"}},
+		{"store-get-missing", withBaseURL, []string{"store", "get", "missing"}},
+		{"store-delete", withBaseURL, []string{"store", "delete", "base_url"}},
+		{"store-export", withBaseURL, []string{"store", "export"}},
+		{"store-import", withExport, []string{"store", "import", "$FILE"}},
+		{"store-import-invalid", withFile("archive.json", `{`, nil), []string{"store", "import", "$FILE"}},
+		{"store-migrate", withBaseURL, []string{"store", "migrate"}},
+		{"store-legacy", withLegacyStore, []string{"store", "get", "base_url"}},
+		{"store-reset", withBaseURL, []string{"store", "reset", "--yes"}},
+		{"store-reset-unconfirmed", withBaseURL, []string{"store", "reset"}},
+
+		{"service-list", loggedIn, []string{"service", "list"}},
+		{"service-list-unauthorized", withFaults(unauthorized), []string{"service", "list"}},
+		{"sentinel-apikey", loggedIn, []string{"service", "sentinel", "apikey"}},
+		{"sentinel-apikey-list", loggedIn, []string{"service", "sentinel", "apikey", "list"}},
+		{"sentinel-apikey-list-unauthorized", withFaults(unauthorized), []string{"service", "sentinel", "apikey", "list"}},
+		{"sentinel-dataset", withFile("train.csv", "x,y\n1,2\n", loggedIn), []string{"service", "sentinel", "dataset", "$FILE", "-o", "$OUT"}},
+		{"sentinel-dataset-no-output", withFile("train.csv", "x,y\n1,2\n", loggedIn), []string{"service", "sentinel", "dataset", "$FILE"}},
+		{"sentinel-dataset-missing-file", loggedIn, []string{"service", "sentinel", "dataset", "missing.csv", "-o", "$OUT"}},
+		{"sentinel-dataset-bad-compress", withFile("train.csv", "x,y\n1,2\n", loggedIn), []string{"service", "sentinel", "dataset", "$FILE", "-o", "$OUT", "--compress", "brotli"}},
+		{"sentinel-sensory", withFile("sensory.json", `{"sensors": ["camera"]}`, loggedIn), []string{"service", "sentinel", "sensory", "$FILE", "-o", "$OUT"}},
+		{"sentinel-sensory-unauthorized", withFile("sensory.json", "{}", withFaults(unauthorized)), []string{"service", "sentinel", "sensory", "$FILE", "-o", "$OUT"}},
+		{"sentinel-request", withUploads("train.csv"), []string{"service", "sentinel", "request", "-s", "$SENSORY", "-d", "$DATASET"}},
+		{"sentinel-request-no-dataset", withUploads("train.csv"), []string{"service", "sentinel", "request", "-s", "$SENSORY"}},
+		{"sentinel-status", withRequest("train.csv"), []string{"service", "sentinel", "status", "$REQUEST"}},
+		{"sentinel-status-unknown", loggedIn, []string{"service", "sentinel", "status", "request-9999-000000"}},
+		{"sentinel-watch", withRequest("train.csv"), []string{"service", "sentinel", "watch", "$REQUEST"}},
+		{"sentinel-watch-failed", withRequest("fail.csv"), []string{"service", "sentinel", "watch", "$REQUEST"}},
+		{"sentinel-submit", withUploads("train.csv"), []string{"service", "sentinel", "submit", "-s", "$SENSORY", "-d", "$DATASET"}},
+		{"sentinel-submit-failed", withUploads("fail.csv"), []string{"service", "sentinel", "submit", "-s", "$SENSORY", "-d", "$DATASET"}},
+		{"sentinel-batch", withFile("batch.csv", "name,dataset,sensory\nfirst,$DATASET,$SENSORY\n", withUploads("train.csv")), []string{"service", "sentinel", "batch", "-f", "$FILE", "-p", "1"}},
+		{"sentinel-batch-missing-manifest", loggedIn, []string{"service", "sentinel", "batch", "-f", "missing.csv"}},
+		{"sentinel-dashboard", withRequest("train.csv"), []string{"service", "sentinel", "dashboard", "--once"}},
+
+		{"agent-status", nil, []string{"agent", "status"}},
+		{"agent-token", nil, []string{"agent", "token"}},
+		{"agent-stop", nil, []string{"agent", "stop"}},
+		{"plugin-list", nil, []string{"plugin", "list"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// no plugins and no shell tools are found on PATH
+			t.Setenv("PATH", t.TempDir())
+			e := newCLIEnv(t, mockserver.WithTrainingStep(time.Microsecond))
+			v := vars{"OUT": filepath.Join(t.TempDir(), "id")}
+			if tt.setup != nil {
+				tt.setup(e, v)
+			}
+			if manifest, ok := v["FILE"]; ok && strings.HasSuffix(manifest, "batch.csv") {
+				raw, err := os.ReadFile(manifest)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(manifest, []byte(os.Expand(string(raw), func(name string) string { return v[name] })), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			args := make([]string, len(tt.args))
+			for i, arg := range tt.args {
+				args[i] = os.Expand(arg, func(name string) string { return v[name] })
+			}
+			result := e.run(args...)
+
+			got := "$ synexis " + strings.Join(tt.args, " ") + "\n" +
+				"exit " + strconv.Itoa(result.Code) + "\n" +
+				"-- stdout --\n" + e.scrub(result.Stdout) +
+				"-- stderr --\n" + e.scrub(result.Stderr)
+			path := filepath.Join("testdata", "golden", tt.name+".golden")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("output differs from %s, run go test -update to accept it\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package synexis
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/synxms/synexis/pkg/client"
+	"github.com/synxms/synexis/pkg/mockserver"
+	"github.com/synxms/synexis/pkg/storage"
+	"github.com/synxms/synexis/src/service"
+)
+
+type (
+	// exitCode is what the Exit test double panics with.
+	exitCode  int
+	cliResult struct {
+		Stdout string
+		Stderr string
+		Code   int
+	}
+	// cliEnv runs the command tree against a mock server and a scratch store,
+	// with none of the caller's environment leaking in.
+	cliEnv struct {
+		t      *testing.T
+		mock   *mockserver.Server
+		server *httptest.Server
+		home   string
+		stdin  string
+		opened []string
+	}
+	// fakeBrowser records the pages commands would open.
+	fakeBrowser struct {
+		service.Authentication
+		env *cliEnv
+	}
+)
+
+func (b *fakeBrowser) OpenDefaultBrowser(url string) error {
+	b.env.opened = append(b.env.opened, url)
+	return nil
+}
+
+func newCLIEnv(t *testing.T, opts ...mockserver.Option) *cliEnv {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv(storage.EnvHome, home)
+	// agent sockets are looked up here, away from a real agent
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	for _, name := range []string{envBaseURL, client.EnvAccessToken, client.EnvAPIKey, client.EnvTokenCommand, envPluginProfile, "SYNEXIS_DEBUG", envWebhookURL, envStorePassphrase} {
+		t.Setenv(name, "")
+	}
+	mock := mockserver.New(opts...)
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+	return &cliEnv{t: t, mock: mock, server: server, home: home}
+}
+
+// run executes one synexis command line. A command that exits reports its
+// code instead of ending the test binary.
+func (e *cliEnv) run(args ...string) (result cliResult) {
+	e.t.Helper()
+	var stdout, stderr bytes.Buffer
+	dependencies := DefaultDependencies()
+	dependencies.Stdin = strings.NewReader(e.stdin)
+	dependencies.Stdout = &stdout
+	dependencies.Stderr = &stderr
+	dependencies.NewClient = func(baseUrl string, httpClient *http.Client, opts ...client.Option) service.Authentication {
+		return &fakeBrowser{Authentication: service.NewAuthentication(baseUrl, httpClient, opts...), env: e}
+	}
+	dependencies.Exit = func(code int) {
+		panic(exitCode(code))
+	}
+	storage.UseProfile("")
+	storage.UsePath("")
+	rootCmd := NewRootCommand(dependencies)
+	rootCmd.SetArgs(args)
+	defer func() {
+		if r := recover(); r != nil {
+			code, ok := r.(exitCode)
+			if !ok {
+				panic(r)
+			}
+			result.Code = int(code)
+		}
+		result.Stdout, result.Stderr = stdout.String(), stderr.String()
+	}()
+	if err := rootCmd.ExecuteContext(context.Background()); err != nil {
+		result.Code = 1
+	}
+	return result
+}
+
+// mustRun fails the test unless the command succeeds.
+func (e *cliEnv) mustRun(args ...string) cliResult {
+	e.t.Helper()
+	result := e.run(args...)
+	if result.Code != 0 {
+		e.t.Fatalf("synexis %s exited with %d\nstdout:\n%s\nstderr:\n%s", strings.Join(args, " "), result.Code, result.Stdout, result.Stderr)
+	}
+	return result
+}
+
+// login signs in the way a user does: point the CLI at the server, let it
+// open the sign-in page, and store the tokens the page hands out.
+func (e *cliEnv) login() {
+	e.t.Helper()
+	e.mustRun("server-base-url", e.server.URL)
+	e.mustRun("authenticate")
+	if len(e.opened) == 0 {
+		e.t.Fatal("authenticate did not open the sign-in page")
+	}
+	resp, err := http.Get(e.opened[len(e.opened)-1])
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	stored := 0
+	for scanner.Scan() {
+		if command, ok := strings.CutPrefix(scanner.Text(), "synexis "); ok {
+			e.mustRun(strings.Fields(command)...)
+			stored++
+		}
+	}
+	if stored != 2 {
+		e.t.Fatalf("sign-in page handed out %d commands instead of 2", stored)
+	}
+}
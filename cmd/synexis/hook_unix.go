@@ -0,0 +1,14 @@
+//go:build !windows
+
+package synexis
+
+import (
+	"context"
+	"os/exec"
+)
+
+// hookCommand runs a completion hook through the POSIX shell, so hooks can
+// use pipes and variables like "$SYNEXIS_REQUEST_ID".
+func hookCommand(ctx context.Context, command string) *exec.Cmd {
+	return exec.CommandContext(ctx, "/bin/sh", "-c", command)
+}
@@ -0,0 +1,10 @@
+package synexis
+
+import (
+	"context"
+	"os/exec"
+)
+
+func hookCommand(ctx context.Context, command string) *exec.Cmd {
+	return exec.CommandContext(ctx, "cmd.exe", "/C", command)
+}
@@ -0,0 +1,251 @@
+package synexis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/synxms/synexis/pkg/client"
+	"github.com/synxms/synexis/pkg/httpclient"
+	"github.com/synxms/synexis/src/service"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	envWebhookURL = "SYNEXIS_WEBHOOK_URL"
+	// hookOutputLimit bounds what is kept of each hook stream in the job
+	// registry, the terminal still sees everything.
+	hookOutputLimit = 64 << 10
+)
+
+type (
+	// completionHooks are the commands of --on-success, --on-failure and
+	// --on-complete, and the --webhook to notify.
+	completionHooks struct {
+		OnSuccess  string
+		OnFailure  string
+		OnComplete string
+		Webhook    string
+	}
+	hookRun struct {
+		Event      string    `json:"event"`
+		Command    string    `json:"command"`
+		ExitCode   int       `json:"exitCode"`
+		Error      string    `json:"error,omitempty"`
+		Stdout     string    `json:"stdout,omitempty"`
+		Stderr     string    `json:"stderr,omitempty"`
+		StartedAt  time.Time `json:"startedAt"`
+		FinishedAt time.Time `json:"finishedAt"`
+	}
+	webhookDelivery struct {
+		URL         string    `json:"url"`
+		StatusCode  int       `json:"statusCode,omitempty"`
+		Error       string    `json:"error,omitempty"`
+		DeliveredAt time.Time `json:"deliveredAt"`
+	}
+	webhookPayload struct {
+		Event     string                    `json:"event"`
+		Request   client.TrainingRequest    `json:"request"`
+		Artifacts []client.TrainingArtifact `json:"artifacts"`
+		Hooks     []hookRun                 `json:"hooks"`
+	}
+	// cappedBuffer keeps the first hookOutputLimit bytes written to it.
+	cappedBuffer struct {
+		bytes.Buffer
+		truncated bool
+	}
+)
+
+func (h completionHooks) empty() bool {
+	return h.OnSuccess == "" && h.OnFailure == "" && h.OnComplete == "" && h.Webhook == ""
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := hookOutputLimit - b.Len(); room < len(p) {
+		b.truncated = true
+		b.Buffer.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+func (b *cappedBuffer) String() string {
+	if b.truncated {
+		return b.Buffer.String() + "\n[output truncated]"
+	}
+	return b.Buffer.String()
+}
+
+// runCompletionHooks runs the hooks matching the outcome of request, posts
+// the webhook and keeps both outcomes in the job registry. It reports whether
+// everything succeeded.
+func runCompletionHooks(ctx context.Context, authenticationService service.Authentication, request client.TrainingRequest, hooks completionHooks) bool {
+	if hooks.empty() {
+		return true
+	}
+	artifacts := finishedArtifacts(ctx, authenticationService, request)
+	env := hookEnvironment(authenticationService.BaseURL(), request, artifacts)
+
+	var runs []hookRun
+	ok := true
+	outcome, command := "on-failure", hooks.OnFailure
+	if request.Status == client.TrainingStatusSucceeded {
+		outcome, command = "on-success", hooks.OnSuccess
+	}
+	for _, event := range [][2]string{{outcome, command}, {"on-complete", hooks.OnComplete}} {
+		if event[1] == "" {
+			continue
+		}
+		run := runHook(ctx, event[0], event[1], env)
+		if run.ExitCode != 0 || run.Error != "" {
+			ok = false
+		}
+		runs = append(runs, run)
+	}
+
+	var delivery *webhookDelivery
+	if hooks.Webhook != "" {
+		delivery = postWebhook(ctx, hooks.Webhook, webhookPayload{
+			Event:     "training." + request.Status,
+			Request:   request,
+			Artifacts: artifacts,
+			Hooks:     runs,
+		})
+		if delivery.Error != "" {
+			ok = false
+		}
+	}
+
+	record := jobRecord{RequestID: request.RequestID, DatasetID: request.DatasetID, SensoryID: request.SensoryID, CreatedAt: request.CreatedAt}
+	if store := deps.NewStorage(""); store.Init() == nil {
+		if existing, found := findJob(store, request.RequestID); found {
+			record = existing
+		}
+		store.Close()
+	}
+	record.Hooks = append(record.Hooks, runs...)
+	if delivery != nil {
+		record.Webhook = delivery
+	}
+	recordJob(record)
+	return ok
+}
+
+// finishedArtifacts lists the artifacts of a succeeded request with absolute
+// URLs, so hooks and webhooks can fetch them without knowing the server.
+func finishedArtifacts(ctx context.Context, authenticationService service.Authentication, request client.TrainingRequest) []client.TrainingArtifact {
+	artifacts := []client.TrainingArtifact{}
+	if request.Status != client.TrainingStatusSucceeded {
+		return artifacts
+	}
+	result, err := authenticationService.RequestArtifacts(ctx, request.RequestID)
+	if err == nil && result.ResponseCode != "00" {
+		err = errors.New(result.ResponseMessage)
+	}
+	if err != nil {
+		fmt.Fprintln(deps.Stderr, "Warning: could not list artifacts for hooks:", err)
+		return artifacts
+	}
+	base, _ := url.Parse(authenticationService.BaseURL())
+	for _, artifact := range result.Data {
+		if base != nil {
+			if resolved, err := base.Parse(artifact.URL); err == nil {
+				artifact.URL = resolved.String()
+			}
+		}
+		artifacts = append(artifacts, artifact)
+	}
+	return artifacts
+}
+
+func hookEnvironment(baseUrl string, request client.TrainingRequest, artifacts []client.TrainingArtifact) []string {
+	var urls []string
+	for _, artifact := range artifacts {
+		urls = append(urls, artifact.URL)
+	}
+	metrics, _ := json.Marshal(request.Metrics)
+	return append(os.Environ(),
+		"SYNEXIS_REQUEST_ID="+request.RequestID,
+		"SYNEXIS_STATUS="+request.Status,
+		"SYNEXIS_MESSAGE="+request.Message,
+		"SYNEXIS_DATASET_ID="+request.DatasetID,
+		"SYNEXIS_SENSORY_ID="+request.SensoryID,
+		"SYNEXIS_METRICS="+string(metrics),
+		"SYNEXIS_ARTIFACT_URLS="+strings.Join(urls, " "),
+		envBaseURL+"="+baseUrl,
+	)
+}
+
+// runHook runs command through the shell, passing its output through to the
+// terminal while keeping a copy for the registry.
+func runHook(ctx context.Context, event, command string, env []string) hookRun {
+	run := hookRun{Event: event, Command: command, StartedAt: deps.Now()}
+	var stdout, stderr cappedBuffer
+	hook := hookCommand(ctx, command)
+	hook.Env = append(env, "SYNEXIS_HOOK="+event)
+	hook.Stdout = io.MultiWriter(deps.Stdout, &stdout)
+	hook.Stderr = io.MultiWriter(deps.Stderr, &stderr)
+	fmt.Fprintf(deps.Stderr, "Running %s hook: %s\n", event, command)
+	err := hook.Run()
+	run.FinishedAt = deps.Now()
+	run.Stdout, run.Stderr = stdout.String(), stderr.String()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		run.ExitCode = exitErr.ExitCode()
+		fmt.Fprintf(deps.Stderr, "The %s hook exited with %d\n", event, run.ExitCode)
+	case err != nil:
+		run.ExitCode = -1
+		run.Error = err.Error()
+		fmt.Fprintf(deps.Stderr, "The %s hook could not run: %v\n", event, err)
+	}
+	return run
+}
+
+func postWebhook(ctx context.Context, webhookUrl string, payload webhookPayload) *webhookDelivery {
+	delivery := &webhookDelivery{URL: webhookUrl, DeliveredAt: deps.Now()}
+	fail := func(err error) *webhookDelivery {
+		delivery.Error = err.Error()
+		fmt.Fprintln(deps.Stderr, "Webhook failed:", err)
+		return delivery
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fail(err)
+	}
+	// the webhook is a third party, it gets neither the client certificate
+	// meant for the synexis server nor a place in its debug log and trace
+	httpClient, err := httpclient.New(httpclient.Config{
+		ConnectTimeout: httpConfig.ConnectTimeout,
+		ReadTimeout:    httpConfig.ReadTimeout,
+		Timeout:        httpConfig.Timeout,
+		CACertFile:     httpConfig.CACertFile,
+	})
+	if err != nil {
+		return fail(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookUrl, bytes.NewReader(raw))
+	if err != nil {
+		return fail(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode/100 != 2 {
+		return fail(fmt.Errorf("%s answered %s", webhookUrl, resp.Status))
+	}
+	fmt.Fprintln(deps.Stderr, "Webhook delivered to", webhookUrl)
+	return delivery
+}
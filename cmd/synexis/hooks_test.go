@@ -0,0 +1,148 @@
+//go:build !windows
+
+package synexis
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/synxms/synexis/pkg/client"
+	"github.com/synxms/synexis/pkg/mockserver"
+)
+
+func TestCappedBuffer(t *testing.T) {
+	var buf cappedBuffer
+	_, _ = buf.Write([]byte("start "))
+	n, err := buf.Write([]byte(strings.Repeat("x", hookOutputLimit)))
+	if n != hookOutputLimit || err != nil {
+		t.Errorf("write past the limit returned %d, %v, want all of it taken", n, err)
+	}
+	if buf.Len() != hookOutputLimit || !strings.HasSuffix(buf.String(), "\n[output truncated]") {
+		t.Errorf("buffer kept %d bytes, ending %q", buf.Len(), buf.String()[buf.Len()-10:])
+	}
+}
+
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	payloads []webhookPayload
+	headers  []http.Header
+}
+
+func newWebhookReceiver(t *testing.T, status int) (*webhookReceiver, string) {
+	t.Helper()
+	receiver := &webhookReceiver{status: status}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var payload webhookPayload
+		_ = json.Unmarshal(raw, &payload)
+		receiver.mu.Lock()
+		receiver.payloads = append(receiver.payloads, payload)
+		receiver.headers = append(receiver.headers, r.Header.Clone())
+		receiver.mu.Unlock()
+		w.WriteHeader(receiver.status)
+	}))
+	t.Cleanup(server.Close)
+	return receiver, server.URL + "/hooks/synexis"
+}
+
+func TestSubmitRunsHooks(t *testing.T) {
+	tests := []struct {
+		dataset    string
+		wantCode   int
+		wantStatus string
+		wantHooks  string
+	}{
+		{"train.csv", 0, client.TrainingStatusSucceeded, "on-success succeeded\non-complete succeeded\n"},
+		{"fail.csv", 1, client.TrainingStatusFailed, "on-failure failed\non-complete failed\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantStatus, func(t *testing.T) {
+			e := newCLIEnv(t, mockserver.WithTrainingStep(time.Microsecond))
+			e.login()
+			sensoryPath, datasetPath := e.uploadInputs(tt.dataset)
+			receiver, webhookURL := newWebhookReceiver(t, http.StatusNoContent)
+			hookLog := filepath.Join(t.TempDir(), "hooks.log")
+			tracePath := filepath.Join(t.TempDir(), "trace.har")
+			hook := `echo "$SYNEXIS_HOOK $SYNEXIS_STATUS" >> ` + hookLog + ` && test -n "$SYNEXIS_REQUEST_ID"`
+
+			result := e.run("service", "sentinel", "submit", "-s", sensoryPath, "-d", datasetPath,
+				"--on-success", hook, "--on-failure", hook, "--on-complete", hook, "--webhook", webhookURL,
+				"--debug-http", "--trace-file", tracePath)
+			if result.Code != tt.wantCode {
+				t.Fatalf("submit exited with %d, want %d\nstdout:\n%s\nstderr:\n%s", result.Code, tt.wantCode, result.Stdout, result.Stderr)
+			}
+			if ran, _ := os.ReadFile(hookLog); string(ran) != tt.wantHooks {
+				t.Errorf("hooks ran as\n%s\nwant\n%s", ran, tt.wantHooks)
+			}
+
+			if len(receiver.payloads) != 1 {
+				t.Fatalf("webhook received %d deliveries, want 1", len(receiver.payloads))
+			}
+			payload := receiver.payloads[0]
+			if payload.Event != "training."+tt.wantStatus || payload.Request.Status != tt.wantStatus || len(payload.Hooks) != 2 {
+				t.Errorf("webhook payload %+v", payload)
+			}
+			for _, artifact := range payload.Artifacts {
+				if !strings.HasPrefix(artifact.URL, e.server.URL+"/") {
+					t.Errorf("webhook artifact url %s is not absolute", artifact.URL)
+				}
+			}
+			if tt.wantStatus == client.TrainingStatusSucceeded && len(payload.Artifacts) != 2 {
+				t.Errorf("webhook lists %d artifacts, want 2", len(payload.Artifacts))
+			}
+
+			// the webhook is a third party, it stays out of credentials and
+			// of the traffic logged for the synexis server
+			if authorization := receiver.headers[0].Get("Authorization"); authorization != "" {
+				t.Errorf("webhook received credentials %q", authorization)
+			}
+			if strings.Contains(result.Stderr, "> POST "+webhookURL) {
+				t.Errorf("webhook delivery was logged by --debug-http:\n%s", result.Stderr)
+			}
+			if trace, _ := os.ReadFile(tracePath); strings.Contains(string(trace), webhookURL) {
+				t.Error("webhook delivery was recorded in the trace")
+			}
+		})
+	}
+}
+
+func TestSubmitHookFailures(t *testing.T) {
+	e := newCLIEnv(t, mockserver.WithTrainingStep(time.Microsecond))
+	e.login()
+	sensoryPath, datasetPath := e.uploadInputs("train.csv")
+	_, webhookURL := newWebhookReceiver(t, http.StatusInternalServerError)
+	t.Setenv(envWebhookURL, webhookURL)
+
+	result := e.run("service", "sentinel", "submit", "-s", sensoryPath, "-d", datasetPath, "--on-success", "echo partial; exit 3")
+	if result.Code != 1 || !strings.Contains(result.Stderr, "a completion hook failed") {
+		t.Fatalf("submit exited with %d\nstdout:\n%s\nstderr:\n%s", result.Code, result.Stdout, result.Stderr)
+	}
+	if !strings.Contains(result.Stderr, "The on-success hook exited with 3") || !strings.Contains(result.Stderr, "Webhook failed:") {
+		t.Errorf("submit reported\n%s\nwant the hook and webhook failures", result.Stderr)
+	}
+
+	store := deps.NewStorage("")
+	if err := store.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	job, ok := findJob(store, e.mock.Requests()[0].RequestID)
+	if !ok {
+		t.Fatal("submitted request is not in the job registry")
+	}
+	if len(job.Hooks) != 1 || job.Hooks[0].ExitCode != 3 || job.Hooks[0].Stdout != "partial\n" {
+		t.Errorf("registry recorded hooks %+v", job.Hooks)
+	}
+	if job.Webhook == nil || job.Webhook.URL != webhookURL || job.Webhook.StatusCode != http.StatusInternalServerError {
+		t.Errorf("registry recorded webhook %+v", job.Webhook)
+	}
+}
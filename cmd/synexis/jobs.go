@@ -0,0 +1,125 @@
+package synexis
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/synxms/synexis/pkg/storage"
+	"sort"
+	"strings"
+	"time"
+)
+
+// jobRecord is an entry of the local job registry, which remembers the
+// training requests created from this machine.
+type jobRecord struct {
+	RequestID string    `json:"requestId"`
+	DatasetID string    `json:"datasetId"`
+	SensoryID string    `json:"sensoryId"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Hooks and Webhook are the completion hooks run by watch and submit.
+	Hooks   []hookRun        `json:"hooks,omitempty"`
+	Webhook *webhookDelivery `json:"webhook,omitempty"`
+}
+
+// recordJob is best effort, a registry that cannot be written must not fail a
+// request that the server already accepted.
+func recordJob(record jobRecord) {
+	store := deps.NewStorage("")
+	if err := store.Init(); err != nil {
+		fmt.Fprintln(deps.Stderr, "Warning: could not record job locally:", err)
+		return
+	}
+	defer store.Close()
+	raw, err := json.Marshal(record)
+	if err == nil {
+		err = store.Set(storage.JobKeyPrefix+record.RequestID, string(raw))
+	}
+	if err != nil {
+		fmt.Fprintln(deps.Stderr, "Warning: could not record job locally:", err)
+	}
+}
+
+func findJob(store storage.Storage, requestID string) (jobRecord, bool) {
+	var record jobRecord
+	raw, err := store.Get(storage.JobKeyPrefix + requestID)
+	if err != nil || raw == "" || json.Unmarshal([]byte(raw), &record) != nil {
+		return record, false
+	}
+	return record, true
+}
+
+// listJobs returns the registry newest first.
+func listJobs(store storage.Storage) ([]jobRecord, error) {
+	keys, err := store.Keys()
+	if err != nil {
+		return nil, err
+	}
+	var jobs []jobRecord
+	for _, key := range keys {
+		if !strings.HasPrefix(key, storage.JobKeyPrefix) {
+			continue
+		}
+		raw, err := store.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		var record jobRecord
+		if json.Unmarshal([]byte(raw), &record) == nil {
+			jobs = append(jobs, record)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+// uploadRecord remembers a dataset or sensory file uploaded from this
+// machine, so its ID can be offered without keeping the ID file around.
+type uploadRecord struct {
+	ID         string    `json:"id"`
+	Kind       string    `json:"kind"`
+	File       string    `json:"file"`
+	UploadedAt time.Time `json:"uploadedAt"`
+}
+
+func uploadKey(kind, id string) string {
+	return storage.UploadKeyPrefix + kind + "/" + id
+}
+
+// recordUpload is best effort like recordJob.
+func recordUpload(store storage.Storage, record uploadRecord) {
+	raw, err := json.Marshal(record)
+	if err == nil {
+		err = store.Set(uploadKey(record.Kind, record.ID), string(raw))
+	}
+	if err != nil {
+		fmt.Fprintln(deps.Stderr, "Warning: could not record upload locally:", err)
+	}
+}
+
+// listUploads returns the uploads of kind newest first.
+func listUploads(store storage.Storage, kind string) ([]uploadRecord, error) {
+	keys, err := store.Keys()
+	if err != nil {
+		return nil, err
+	}
+	var uploads []uploadRecord
+	for _, key := range keys {
+		if !strings.HasPrefix(key, uploadKey(kind, "")) {
+			continue
+		}
+		raw, err := store.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		var record uploadRecord
+		if json.Unmarshal([]byte(raw), &record) == nil {
+			uploads = append(uploads, record)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].UploadedAt.After(uploads[j].UploadedAt)
+	})
+	return uploads, nil
+}
@@ -0,0 +1,94 @@
+package synexis
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/synxms/synexis/pkg/agent"
+	"github.com/synxms/synexis/pkg/client"
+	"github.com/synxms/synexis/pkg/storage"
+)
+
+func logout(cmd *cobra.Command, _ []string) error {
+	allProfiles, _ := cmd.Flags().GetBool("all-profiles")
+	purge, _ := cmd.Flags().GetBool("purge")
+
+	profiles := []string{deps.NewStorage("").Profile()}
+	if allProfiles {
+		store := deps.NewStorage("")
+		if err := store.Init(); err != nil {
+			fatalln("Failed to init storage:", err)
+		}
+		var err error
+		profiles, err = store.Profiles()
+		store.Close()
+		if err != nil {
+			fatalln("Failed to list profiles:", err)
+		}
+	}
+	for _, profile := range profiles {
+		logoutProfile(cmd, profile, purge)
+	}
+	return nil
+}
+
+func logoutProfile(cmd *cobra.Command, profile string, purge bool) {
+	store := deps.NewStorage(profile)
+	if err := store.Init(); err != nil {
+		fatalln("Failed to init storage:", err)
+	}
+	defer store.Close()
+	rt, err := store.Get("refresh_token")
+	if err != nil {
+		fatalln("Failed to get refresh token:", err)
+	}
+	baseUrl, err := store.Get("base_url")
+	if err != nil {
+		fatalln("Failed to get base url:", err)
+	}
+	if rt != "" && baseUrl != "" {
+		err := newAuthenticationService(baseUrl).RevokeRefreshToken(cmd.Context(), rt)
+		switch {
+		case err == nil:
+			fmt.Fprintf(deps.Stdout, "Refresh token revoked for profile %s.\n", profile)
+		case errors.Is(err, client.ErrNotSupported):
+		default:
+			exitIfCancelled(cmd.Context())
+			fmt.Fprintf(deps.Stderr, "Warning: could not revoke refresh token for profile %s, it stays valid on the server until it expires: %v\n", profile, err)
+		}
+	}
+
+	keys := []string{"access_token", "refresh_token"}
+	if purge {
+		stored, err := store.Keys()
+		if err != nil {
+			fatalln("Failed to list stored keys:", err)
+		}
+		for _, key := range stored {
+			if storage.IsDerivedKey(key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	for _, key := range keys {
+		if err := store.Delete(key); err != nil {
+			fatalln("Failed to delete "+key+":", err)
+		}
+	}
+	stopProfileAgent(cmd, profile)
+	fmt.Fprintf(deps.Stdout, "Logged out of profile %s.\n", profile)
+}
+
+// stopProfileAgent stops the credential agent of profile, which would
+// otherwise keep handing out its cached token after logout.
+func stopProfileAgent(cmd *cobra.Command, profile string) {
+	_, err := agent.Query(cmd.Context(), profileSocketPath(profile), agent.OpStop)
+	switch {
+	case err == nil:
+		fmt.Fprintf(deps.Stdout, "Credential agent of profile %s stopped.\n", profile)
+	case errors.Is(err, agent.ErrNotRunning):
+	default:
+		exitIfCancelled(cmd.Context())
+		fmt.Fprintf(deps.Stderr, "Warning: could not stop the credential agent of profile %s, stop it with synexis agent stop: %v\n", profile, err)
+	}
+}
@@ -0,0 +1,203 @@
+package synexis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/synxms/synexis/pkg/client"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// pluginPrefix names the executables on PATH that become subcommands, like
+// git-<name> for git: synexis-report runs as "synexis report".
+const pluginPrefix = "synexis-"
+
+// Variables passed to plugins on top of the environment of synexis.
+const (
+	envPluginProfile = "SYNEXIS_PROFILE"
+	envPluginCLI     = "SYNEXIS_CLI"
+)
+
+type plugin struct {
+	Name string
+	Path string
+	// ShadowedBy is the built-in command or earlier plugin that runs
+	// instead of this one.
+	ShadowedBy string
+}
+
+// findPlugin returns the plugin executable and its arguments when args name
+// no built-in command. Persistent flags before the plugin name are applied.
+func findPlugin(root *cobra.Command, args []string) (string, []string, bool) {
+	if _, _, err := root.Find(args); err == nil {
+		return "", nil, false
+	}
+	i := pluginNameIndex(root.PersistentFlags(), args)
+	if i < 0 || args[i] == "help" || strings.HasPrefix(args[i], "__") {
+		return "", nil, false
+	}
+	path, err := exec.LookPath(pluginPrefix + args[i])
+	if err != nil {
+		return "", nil, false
+	}
+	if err := root.PersistentFlags().Parse(args[:i]); err != nil {
+		return "", nil, false
+	}
+	return path, args[i+1:], true
+}
+
+// pluginNameIndex is the position of the first argument that is neither a
+// flag nor the value of one.
+func pluginNameIndex(flags *pflag.FlagSet, args []string) int {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var flag *pflag.Flag
+		switch {
+		case arg == "--" || arg == "-":
+			return -1
+		case strings.HasPrefix(arg, "--"):
+			name, _, hasValue := strings.Cut(arg[2:], "=")
+			if flag = flags.Lookup(name); hasValue {
+				continue
+			}
+		case len(arg) != 2 && strings.HasPrefix(arg, "-"):
+			continue
+		case strings.HasPrefix(arg, "-"):
+			flag = flags.ShorthandLookup(arg[1:])
+		default:
+			return i
+		}
+		if flag != nil && flag.NoOptDefVal == "" {
+			i++
+		}
+	}
+	return -1
+}
+
+// runPlugin runs a plugin in the foreground and exits with its exit code.
+// Interrupts reach the plugin directly from the terminal, synexis only waits.
+func runPlugin(ctx context.Context, path string, args []string) {
+	useStoreFlags()
+	plugin := exec.Command(path, args...)
+	plugin.Stdin = deps.Stdin
+	plugin.Stdout = deps.Stdout
+	plugin.Stderr = deps.Stderr
+	plugin.Env = pluginEnvironment(ctx)
+	err := plugin.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		deps.Exit(exitErr.ExitCode())
+	case err != nil:
+		fatalln("Failed to run plugin:", err)
+	}
+	deps.Exit(0)
+}
+
+// pluginEnvironment hands the plugin the active profile, base url and a
+// fresh access token, so it can call the API without its own sign in. The
+// token is left out when there is no server or no valid credential.
+func pluginEnvironment(ctx context.Context) []string {
+	env := os.Environ()
+	if executable, err := os.Executable(); err == nil {
+		env = append(env, envPluginCLI+"="+executable)
+	}
+	store := deps.NewStorage("")
+	if err := store.Init(); err != nil {
+		return env
+	}
+	defer store.Close()
+	env = append(env, envPluginProfile+"="+store.Profile())
+	baseUrl := os.Getenv(envBaseURL)
+	if baseUrl == "" {
+		baseUrl, _ = store.Get("base_url")
+	}
+	if baseUrl == "" {
+		return env
+	}
+	env = append(env, envBaseURL+"="+baseUrl)
+	authenticationService, _, _, err := connectService(store, httpConfig)
+	if err != nil {
+		return env
+	}
+	token, err := authenticationService.AccessToken(ctx)
+	if err != nil || !token.Valid() {
+		return env
+	}
+	if token.Type == client.TokenTypeAPIKey {
+		return append(env, client.EnvAPIKey+"="+token.AccessToken)
+	}
+	return append(env, client.EnvAccessToken+"="+token.AccessToken)
+}
+
+// discoverPlugins lists every plugin on PATH in PATH order, including those
+// that never run because a built-in command or an earlier one has the name.
+func discoverPlugins(root *cobra.Command) []plugin {
+	var plugins []plugin
+	seen := map[string]string{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := pluginName(entry.Name())
+			if !ok {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if info, err := os.Stat(path); err != nil || !isExecutable(info) {
+				continue
+			}
+			found := plugin{Name: name, Path: path}
+			if builtin, _, err := root.Find([]string{name}); err == nil && builtin != root {
+				found.ShadowedBy = "built-in command " + builtin.CommandPath()
+			} else if earlier, ok := seen[name]; ok {
+				found.ShadowedBy = earlier
+			} else {
+				seen[name] = path
+			}
+			plugins = append(plugins, found)
+		}
+	}
+	return plugins
+}
+
+func listPlugins(cmd *cobra.Command, _ []string) error {
+	plugins := discoverPlugins(cmd.Root())
+	if len(plugins) == 0 {
+		fmt.Fprintln(deps.Stdout, "No plugins found, install executables named "+pluginPrefix+"<name> on your PATH.")
+		return nil
+	}
+	table := tabwriter.NewWriter(deps.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tPATH\tNOTE")
+	for _, plugin := range plugins {
+		note := ""
+		if plugin.ShadowedBy != "" {
+			note = "shadowed by " + plugin.ShadowedBy
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\n", plugin.Name, plugin.Path, note)
+	}
+	return table.Flush()
+}
+
+func InitializePluginCmd(pluginCmd *cobra.Command) {
+	pluginCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List plugins found on PATH",
+		Long:  `List the ` + pluginPrefix + `<name> executables found on PATH, including those that do not run because a built-in command or an earlier PATH entry has the same name`,
+		Args:  cobra.NoArgs,
+		RunE:  listPlugins,
+	})
+}
+
+// pluginsHelp is shown with the root command.
+var pluginsHelp = `
+
+Executables named ` + pluginPrefix + `<name> on PATH run as "synexis <name>" with ` + envPluginProfile + `, ` + envBaseURL + `, ` + client.EnvAccessToken + ` and ` + envPluginCLI + ` set. Run "synexis plugin list" to see them.`
@@ -0,0 +1,52 @@
+package synexis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/synxms/synexis/pkg/client"
+)
+
+func lookupEnv(env []string, name string) (string, bool) {
+	value := ""
+	found := false
+	for _, entry := range env {
+		if v, ok := strings.CutPrefix(entry, name+"="); ok {
+			value, found = v, true
+		}
+	}
+	return value, found
+}
+
+func TestPluginEnvironment(t *testing.T) {
+	e := newCLIEnv(t)
+	e.login()
+	env := pluginEnvironment(context.Background())
+	if baseUrl, _ := lookupEnv(env, envBaseURL); baseUrl != e.server.URL {
+		t.Errorf("plugin gets base url %q, want %q", baseUrl, e.server.URL)
+	}
+	if token, _ := lookupEnv(env, client.EnvAccessToken); !strings.HasPrefix(token, "eyJ") {
+		t.Errorf("plugin gets access token %q, want the stored one", token)
+	}
+}
+
+// A server the CLI cannot talk to leaves the token out instead of ending
+// synexis before the plugin starts.
+func TestPluginEnvironmentWithoutService(t *testing.T) {
+	e := newCLIEnv(t)
+	e.login()
+	t.Setenv(envBaseURL, "not a url")
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("pluginEnvironment exited with %v", r)
+		}
+	}()
+	env := pluginEnvironment(context.Background())
+	if baseUrl, _ := lookupEnv(env, envBaseURL); baseUrl != "not a url" {
+		t.Errorf("plugin gets base url %q, want the one from the environment", baseUrl)
+	}
+	if token, ok := lookupEnv(env, client.EnvAccessToken); ok && token != "" {
+		t.Errorf("plugin gets access token %q without a usable server", token)
+	}
+}
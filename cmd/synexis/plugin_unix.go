@@ -0,0 +1,17 @@
+//go:build !windows
+
+package synexis
+
+import (
+	"io/fs"
+	"strings"
+)
+
+func pluginName(file string) (string, bool) {
+	name := strings.TrimPrefix(file, pluginPrefix)
+	return name, name != file && name != ""
+}
+
+func isExecutable(info fs.FileInfo) bool {
+	return info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0
+}
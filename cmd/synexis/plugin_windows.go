@@ -0,0 +1,34 @@
+package synexis
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pluginName strips the prefix and an extension listed in PATHEXT, so
+// synexis-report.exe and synexis-report.cmd both run as "synexis report".
+func pluginName(file string) (string, bool) {
+	name, found := strings.CutPrefix(strings.ToLower(file), pluginPrefix)
+	if !found {
+		return "", false
+	}
+	ext := filepath.Ext(name)
+	if ext == "" || !strings.Contains(";"+strings.ToLower(pathExt())+";", ";"+ext+";") {
+		return "", false
+	}
+	name = strings.TrimSuffix(name, ext)
+	return name, name != ""
+}
+
+func pathExt() string {
+	if value := os.Getenv("PATHEXT"); value != "" {
+		return value
+	}
+	return ".com;.exe;.bat;.cmd"
+}
+
+func isExecutable(info fs.FileInfo) bool {
+	return info.Mode().IsRegular()
+}
@@ -0,0 +1,150 @@
+package synexis
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/synxms/synexis/pkg/archive"
+	"time"
+)
+
+// sentinelEndpoints are the paths Sentinel is served at, the upload path
+// first since it also answers OPTIONS.
+var sentinelEndpoints = []string{
+	"/api/v1/sentinel/sessions/upload/dataset",
+	"/api/v1/sentinel/sessions/upload/sensory",
+	"/api/v1/sentinel/sessions/create/request",
+	"/api/v1/sentinel/sessions/requests",
+}
+
+func init() {
+	registerServiceModule(serviceModule{
+		Name:       "sentinel",
+		Short:      "Sentinel synexis service command console",
+		Endpoints:  sentinelEndpoints,
+		Initialize: initializeSentinelCmd,
+	})
+}
+
+func initializeSentinelCmd(sentinelCmd *cobra.Command) {
+	apiKeyCmd := &cobra.Command{
+		Use:   "apikey",
+		Short: "Sentinel API Key generate be careful with this command",
+		Long:  `Sentinel API Key generate be careful with this command`,
+		Args:  cobra.NoArgs,
+		RunE:  generateAPIKey,
+	}
+	apiKeyCmd.AddCommand(&cobra.Command{
+		Use:               "list [prefix]",
+		Short:             "List the API keys of your company by prefix",
+		Long:              `List the API keys of your company by prefix, optionally only those starting with prefix`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeAPIKeyPrefixes,
+		RunE:              listAPIKeys,
+	})
+	sentinelCmd.AddCommand(apiKeyCmd)
+	datasetCmd := &cobra.Command{
+		Use:               "dataset",
+		Short:             "Sentinel upload dataset for custom training",
+		Long:              `Sentinel upload dataset for custom training. A directory is packed into a tar.gz or zip archive while it is uploaded, in sorted order and with a MANIFEST.sha256 of its files. Patterns in a .synexisignore file at the root of the directory, and --exclude, leave files out`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeFilesWithExtension(datasetExtensions),
+		RunE:              uploadDatasetFile,
+	}
+	sensoryCmd := &cobra.Command{
+		Use:               "sensory",
+		Short:             "Sentinel upload sensory configuration for custom training",
+		Long:              `Sentinel upload sensory configuration for custom training`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeFilesWithExtension(sensoryExtensions),
+		RunE:              uploadSensoryFile,
+	}
+	requestCmd := &cobra.Command{
+		Use:   "request",
+		Short: "Sentinel request training custom model using selected dataset and sensory id",
+		Long:  `Sentinel request training custom model using selected dataset and sensory id`,
+		RunE:  createRequestTraining,
+	}
+	statusCmd := &cobra.Command{
+		Use:               "status [request-id]",
+		Short:             "Show the state of a training request",
+		Long:              `Show the state of a training request`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeRequestIDs,
+		RunE:              requestStatus,
+	}
+
+	datasetCmd.Flags().StringP("output", "o", "", "Path to output file for saving DatasetID")
+	datasetCmd.Flags().Bool("rerun-interrupted", false, "Upload the last interrupted dataset again from the start, with the same file and options")
+	datasetCmd.Flags().String("compress", compressNone, compressUsage)
+	_ = datasetCmd.RegisterFlagCompletionFunc("compress", completeCompress)
+	datasetCmd.Flags().String("format", string(archive.TarGz), "Archive format when uploading a directory, tar.gz or zip")
+	datasetCmd.Flags().StringSlice("include", nil, "When uploading a directory, only pack files matching these globs")
+	datasetCmd.Flags().StringSlice("exclude", nil, "When uploading a directory, skip files and directories matching these globs, in addition to "+archive.IgnoreFileName)
+	_ = datasetCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{string(archive.TarGz), string(archive.Zip)}, cobra.ShellCompDirectiveNoFileComp))
+	sentinelCmd.AddCommand(datasetCmd)
+	sensoryCmd.Flags().StringP("output", "o", "", "Path to output file for saving SensoryID")
+	sensoryCmd.Flags().Bool("rerun-interrupted", false, "Upload the last interrupted sensory again from the start, with the same file and options")
+	sensoryCmd.Flags().String("compress", compressNone, compressUsage)
+	_ = sensoryCmd.RegisterFlagCompletionFunc("compress", completeCompress)
+	sentinelCmd.AddCommand(sensoryCmd)
+	requestCmd.Flags().StringP("sensory", "s", "", "Sensory id or path to saved sensory id file")
+	requestCmd.Flags().StringP("dataset", "d", "", "Dataset id or path to saved dataset id file")
+	_ = requestCmd.RegisterFlagCompletionFunc("sensory", completeUploadIDs("sensory"))
+	_ = requestCmd.RegisterFlagCompletionFunc("dataset", completeUploadIDs("dataset"))
+	sentinelCmd.AddCommand(requestCmd)
+	sentinelCmd.AddCommand(statusCmd)
+	watchCmd := &cobra.Command{
+		Use:               "watch [request-id]",
+		Short:             "Wait for a training request to finish and run completion hooks",
+		Long:              `Wait for a training request to finish, printing each change of status, then run the completion hooks.` + watchHooksHelp,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeRequestIDs,
+		RunE:              watchRequest,
+	}
+	addWatchFlags(watchCmd)
+	sentinelCmd.AddCommand(watchCmd)
+	submitCmd := &cobra.Command{
+		Use:   "submit",
+		Short: "Create a training request and watch it until it finishes",
+		Long:  `Create a training request like request does, then wait for it like watch does and run the completion hooks.` + watchHooksHelp,
+		Args:  cobra.NoArgs,
+		RunE:  submitRequest,
+	}
+	submitCmd.Flags().StringP("sensory", "s", "", "Sensory id or path to saved sensory id file")
+	submitCmd.Flags().StringP("dataset", "d", "", "Dataset id or path to saved dataset id file")
+	_ = submitCmd.RegisterFlagCompletionFunc("sensory", completeUploadIDs("sensory"))
+	_ = submitCmd.RegisterFlagCompletionFunc("dataset", completeUploadIDs("dataset"))
+	addWatchFlags(submitCmd)
+	sentinelCmd.AddCommand(submitCmd)
+	dashboardCmd := &cobra.Command{
+		Use:   "dashboard",
+		Short: "Full-screen view of training requests with live status",
+		Long:  `Full-screen view of training requests with live status, details, logs and metrics, with keys to cancel, retry and download artifacts. Prints a plain refreshing table when the terminal is not interactive`,
+		Args:  cobra.NoArgs,
+		RunE:  sentinelDashboard,
+	}
+	dashboardCmd.Flags().Duration("interval", 5*time.Second, "How often the status is refreshed")
+	dashboardCmd.Flags().Bool("once", false, "Print the table once and exit")
+	dashboardCmd.Flags().String("output-dir", ".", "Directory artifacts are downloaded to, one sub directory per request")
+	sentinelCmd.AddCommand(dashboardCmd)
+	batchCmd := &cobra.Command{
+		Use:   "batch",
+		Short: "Submit many training requests from a manifest",
+		Long: `Submit a training request for every row of a CSV or YAML manifest. Each row names a dataset and a sensory, either by ID or by a file path relative to the manifest, files are uploaded once even when rows share them. The outcome of every row is written to a results file, and --continue submits the rows that have no request yet.
+
+CSV manifests need a header with dataset and sensory columns and an optional name column. YAML manifests are a list of mappings with the same keys, optionally under a top level key:
+
+  rows:
+    - name: baseline
+      dataset: data/train.csv
+      sensory: sensory.json`,
+		Args: cobra.NoArgs,
+		RunE: sentinelBatch,
+	}
+	batchCmd.Flags().StringP("file", "f", "", "Manifest file, .csv or .yaml")
+	batchCmd.Flags().IntP("parallel", "p", 4, "How many rows are uploaded and submitted at the same time")
+	batchCmd.Flags().String("results", "", "Results file, defaults to the manifest name with .results.csv")
+	batchCmd.Flags().Bool("continue", false, "Pick up the batch recorded in the results file, submitting rows without a request")
+	_ = batchCmd.RegisterFlagCompletionFunc("file", completeFilesWithExtension([]string{"csv", "yaml", "yml"}))
+	_ = batchCmd.RegisterFlagCompletionFunc("results", completeFilesWithExtension([]string{"csv"}))
+	sentinelCmd.AddCommand(batchCmd)
+}
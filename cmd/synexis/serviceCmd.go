@@ -1,44 +1,40 @@
 package synexis
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/spf13/cobra"
+	"github.com/synxms/synexis/pkg/archive"
+	"github.com/synxms/synexis/pkg/client"
 	"github.com/synxms/synexis/pkg/storage"
 	"github.com/synxms/synexis/pkg/utility"
 	"github.com/synxms/synexis/src/service"
-	"log"
+	"io"
+	"net/http"
 	"os"
+	"slices"
 	"strings"
+	"time"
 )
 
-func generateAPIKey(_ *cobra.Command, _ []string) error {
-	store := storage.NewStorage()
-	if err := store.Init(); err != nil {
-		log.Fatalln("Failed to init storage:", err)
-	}
-	defer store.Close()
-
-	// get base url
-	baseUrl, err := store.Get("base_url")
-	if err != nil {
-		log.Fatalln("Failed to get access token:", err)
-	}
-	authenticationService := service.NewAuthentication(baseUrl)
+func generateAPIKey(cmd *cobra.Command, _ []string) error {
+	authenticationService, store, closeStore := openAuthenticatedService(nil)
+	defer closeStore()
 
 	// get access token
-	accessToken, err := store.Get("access_token")
+	token, err := authenticationService.AccessToken(cmd.Context())
 	if err != nil {
-		log.Fatalln("Failed to get access token:", err)
+		exitIfCancelled(cmd.Context())
+		fatalln("Failed to get access token:", err)
 	}
 
-	// retrieve company id
-	parser := jwt.NewParser()
-	claims := jwt.MapClaims{}
-	_, _, err = parser.ParseUnverified(accessToken, claims)
+	// retrieve company id from a token whose signature checks out
+	claims, err := authenticationService.VerifyToken(cmd.Context(), token.AccessToken, store, verifyOptions)
 	if err != nil {
-		return errors.New("invalid token")
+		exitIfCancelled(cmd.Context())
+		return fmt.Errorf("access token rejected: %w", err)
 	}
 	companyId, ok := claims["companyId"].(string)
 	if !ok {
@@ -49,199 +45,394 @@ func generateAPIKey(_ *cobra.Command, _ []string) error {
 	prefix := utility.RandomStringUpperCase(3)
 	validationLayerOne := utility.RandomString(5)
 	validationLayerTwo := utility.RandomString(10)
-	result, err := authenticationService.GenerateAPIKeySentinel("SYX"+prefix, validationLayerOne, validationLayerTwo, accessToken)
+	result, err := authenticationService.GenerateAPIKeySentinel(cmd.Context(), "SYX"+prefix, validationLayerOne, validationLayerTwo)
 	if err != nil {
-		log.Fatalln(err.Error())
+		exitIfCancelled(cmd.Context())
+		fatalln(err.Error())
 	}
 	if result != nil {
 		if result.ResponseCode == "00" {
-			fmt.Println(fmt.Sprintf(apiKeyFormat, prefix, validationLayerOne, validationLayerTwo, companyId))
+			fmt.Fprintln(deps.Stdout, fmt.Sprintf(apiKeyFormat, prefix, validationLayerOne, validationLayerTwo, companyId))
 		} else {
-			fmt.Println("API Key generate failed failed.")
+			fmt.Fprintln(deps.Stdout, "API Key generate failed failed.")
 		}
 	}
 	return nil
 }
 
 func uploadDatasetFile(cmd *cobra.Command, args []string) error {
-	store := storage.NewStorage()
+	store := deps.NewStorage("")
 	if err := store.Init(); err != nil {
-		log.Fatalln("Failed to init storage:", err)
+		fatalln("Failed to init storage:", err)
 	}
 	defer store.Close()
-	// get base url
-	baseUrl, err := store.Get("base_url")
+	upload, err := resolvePendingUpload(cmd, store, "dataset", args)
 	if err != nil {
-		log.Fatalln("Failed to get base url:", err)
+		fatalln(err.Error())
 	}
-	// get access token
-	accessToken, err := store.Get("access_token")
-	if err != nil {
-		log.Fatalln("Failed to get access token:", err)
-	}
-	authenticationService := service.NewAuthentication(baseUrl)
-	result, err := authenticationService.UploadFileDatasetSentinel(args[0], accessToken)
+	authenticationService, _, _ := openAuthenticatedService(store)
+	result, err := uploadDataset(cmd.Context(), authenticationService, upload)
 	if err != nil {
-		log.Fatalln(err.Error())
+		if cmd.Context().Err() != nil {
+			savePendingUpload(store, upload)
+		}
+		exitIfCancelled(cmd.Context())
+		fatalln(err.Error())
 	}
+	clearPendingUpload(store, upload.Kind)
 	if result != nil {
 		if result.ResponseCode == "00" {
-			outputPath, _ := cmd.Flags().GetString("output")
+			recordUpload(store, uploadRecord{
+				ID:         result.Data.DatasetID,
+				Kind:       upload.Kind,
+				File:       upload.File,
+				UploadedAt: deps.Now(),
+			})
+			outputPath := upload.Output
 			if outputPath != "" {
 				err := os.WriteFile(outputPath, []byte(result.Data.DatasetID), 0644)
 				if err != nil {
-					log.Fatalln("Failed to write dataset ID to file:", err)
+					fatalln("Failed to write dataset ID to file:", err)
 				}
-				fmt.Println("Dataset ID saved to", outputPath)
+				fmt.Fprintln(deps.Stdout, "Dataset ID saved to", outputPath)
 			} else {
-				log.Fatalln("Use at the end '-o' to specify output file path")
+				fatalln("Use at the end '-o' to specify output file path")
 			}
 		} else {
-			fmt.Println("Upload dataset failed.")
-			fmt.Println("Upload dataset failed, reason : ", result.ResponseMessage)
+			fmt.Fprintln(deps.Stdout, "Upload dataset failed.")
+			fmt.Fprintln(deps.Stdout, "Upload dataset failed, reason : ", result.ResponseMessage)
 		}
 	}
 	return nil
 }
 
 func uploadSensoryFile(cmd *cobra.Command, args []string) error {
-	store := storage.NewStorage()
+	store := deps.NewStorage("")
 	if err := store.Init(); err != nil {
-		log.Fatalln("Failed to init storage:", err)
+		fatalln("Failed to init storage:", err)
 	}
 	defer store.Close()
-	// get base url
-	baseUrl, err := store.Get("base_url")
+	upload, err := resolvePendingUpload(cmd, store, "sensory", args)
 	if err != nil {
-		log.Fatalln("Failed to get base url:", err)
+		fatalln(err.Error())
 	}
-	// get access token
-	accessToken, err := store.Get("access_token")
+	authenticationService, _, _ := openAuthenticatedService(store)
+	result, err := uploadSensory(cmd.Context(), authenticationService, upload)
 	if err != nil {
-		log.Fatalln("Failed to get access token:", err)
-	}
-	authenticationService := service.NewAuthentication(baseUrl)
-	result, err := authenticationService.UploadFileSensorySentinel(args[0], accessToken)
-	if err != nil {
-		log.Fatalln(err.Error())
+		if cmd.Context().Err() != nil {
+			savePendingUpload(store, upload)
+		}
+		exitIfCancelled(cmd.Context())
+		fatalln(err.Error())
 	}
+	clearPendingUpload(store, upload.Kind)
 	if result != nil {
 		if result.ResponseCode == "00" {
-			outputPath, _ := cmd.Flags().GetString("output")
+			recordUpload(store, uploadRecord{
+				ID:         result.Data.SensoryID,
+				Kind:       upload.Kind,
+				File:       upload.File,
+				UploadedAt: deps.Now(),
+			})
+			outputPath := upload.Output
 			if outputPath != "" {
 				err := os.WriteFile(outputPath, []byte(result.Data.SensoryID), 0644)
 				if err != nil {
-					log.Fatalln("Failed to write sensory ID to file:", err)
+					fatalln("Failed to write sensory ID to file:", err)
 				}
-				fmt.Println("Sensory ID saved to", outputPath)
+				fmt.Fprintln(deps.Stdout, "Sensory ID saved to", outputPath)
 			} else {
-				log.Fatalln("Use at the end '-o' to specify output file path")
+				fatalln("Use at the end '-o' to specify output file path")
 			}
 		} else {
-			fmt.Println("Upload sensory failed.")
-			fmt.Println("Upload sensory failed, reason : ", result.ResponseMessage)
+			fmt.Fprintln(deps.Stdout, "Upload sensory failed.")
+			fmt.Fprintln(deps.Stdout, "Upload sensory failed, reason : ", result.ResponseMessage)
 		}
 	}
 	return nil
 }
 
-func createRequestTraining(cmd *cobra.Command, args []string) error {
-	store := storage.NewStorage()
-	if err := store.Init(); err != nil {
-		log.Fatalln("Failed to init storage:", err)
+// uploadDataset uploads a dataset file, or a directory packed into an
+// archive while it is sent.
+func uploadDataset(ctx context.Context, authenticationService service.Authentication, upload *pendingUpload) (*service.ResponseUploadDataset, error) {
+	send := func(opts ...client.UploadOption) (*service.ResponseUploadDataset, error) {
+		return authenticationService.UploadFileDatasetSentinel(ctx, upload.File, opts...)
 	}
-	defer store.Close()
-	// get base url
-	baseUrl, err := store.Get("base_url")
-	if err != nil {
-		log.Fatalln("Failed to get base url:", err)
+	if info, err := os.Stat(upload.File); err == nil && info.IsDir() {
+		format := archive.TarGz
+		if upload.Format != "" {
+			if format, err = archive.ParseFormat(upload.Format); err != nil {
+				return nil, err
+			}
+		}
+		files, err := archive.Files(upload.File, upload.Include, upload.Exclude)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", upload.File, err)
+		}
+		name := format.Name(upload.File)
+		fmt.Fprintf(deps.Stdout, "Packing %d files of %s into %s\n", len(files), upload.File, name)
+		send = func(opts ...client.UploadOption) (*service.ResponseUploadDataset, error) {
+			content, writer := io.Pipe()
+			defer content.Close()
+			go func() {
+				writer.CloseWithError(archive.Write(writer, upload.File, files, format))
+			}()
+			return authenticationService.UploadDatasetStreamSentinel(ctx, name, content, opts...)
+		}
 	}
-	// get access token
-	accessToken, err := store.Get("access_token")
+	opts := compressionOptions(ctx, authenticationService, upload)
+	result, err := send(opts...)
+	if opts != nil && encodingRefused(err) {
+		result, err = send()
+	}
+	return result, err
+}
+
+func uploadSensory(ctx context.Context, authenticationService service.Authentication, upload *pendingUpload) (*service.ResponseUploadSensory, error) {
+	opts := compressionOptions(ctx, authenticationService, upload)
+	result, err := authenticationService.UploadFileSensorySentinel(ctx, upload.File, opts...)
+	if opts != nil && encodingRefused(err) {
+		result, err = authenticationService.UploadFileSensorySentinel(ctx, upload.File)
+	}
+	return result, err
+}
+
+// compressionOptions returns the upload options for --compress, or none when
+// the server does not advertise support for compressed uploads.
+func compressionOptions(ctx context.Context, authenticationService service.Authentication, upload *pendingUpload) []client.UploadOption {
+	if upload.Compress == "" || upload.Compress == compressNone {
+		return nil
+	}
+	encodings, err := authenticationService.UploadEncodingsSentinel(ctx, upload.Kind)
 	if err != nil {
-		log.Fatalln("Failed to get access token:", err)
+		exitIfCancelled(ctx)
+		fmt.Fprintln(deps.Stderr, "Could not ask the server about compressed uploads, uploading uncompressed:", err)
+		return nil
+	}
+	if upload.Compress == client.EncodingAuto {
+		if !slices.Contains(encodings, client.EncodingGzip) && !slices.Contains(encodings, client.EncodingZstd) {
+			fmt.Fprintln(deps.Stderr, "The server does not accept compressed uploads, uploading uncompressed.")
+			return nil
+		}
+	} else if !slices.Contains(encodings, upload.Compress) {
+		fmt.Fprintf(deps.Stderr, "The server does not accept %s compressed uploads, uploading uncompressed.\n", upload.Compress)
+		return nil
+	}
+	return []client.UploadOption{client.WithContentEncoding(upload.Compress), client.WithAcceptedEncodings(encodings)}
+}
+
+// encodingRefused reports a server that advertised an encoding and then
+// answered 415 to it anyway.
+func encodingRefused(err error) bool {
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnsupportedMediaType {
+		fmt.Fprintln(deps.Stderr, "The server refused the compressed upload, uploading uncompressed.")
+		return true
+	}
+	return false
+}
+
+// checkCompress rejects unknown --compress values before anything is sent.
+func checkCompress(value string) error {
+	switch value {
+	case "", compressNone, client.EncodingGzip, client.EncodingZstd, client.EncodingAuto:
+		return nil
 	}
-	authenticationService := service.NewAuthentication(baseUrl)
+	return fmt.Errorf("unknown --compress %q, use none, gzip, zstd or auto", value)
+}
+
+func createRequestTraining(cmd *cobra.Command, args []string) error {
+	authenticationService, _, closeStore := openAuthenticatedService(nil)
+	defer closeStore()
 
 	sensoryIdPath, err := cmd.Flags().GetString("sensory")
 	if err != nil || sensoryIdPath == "" {
-		log.Fatalln("Failed to get sensory ID path from flag:", err)
+		fatalln("Failed to get sensory ID path from flag:", err)
 	}
 
 	datasetIdPath, err := cmd.Flags().GetString("dataset")
 	if err != nil || datasetIdPath == "" {
-		log.Fatalln("Failed to get dataset ID path from flag:", err)
+		fatalln("Failed to get dataset ID path from flag:", err)
 	}
 
-	sensoryId, err := os.ReadFile(sensoryIdPath)
+	sensoryIdString, err := readIDArgument(sensoryIdPath)
 	if err != nil {
-		log.Fatalln("Failed to read dataset ID file", err)
+		fatalln("Failed to read sensory ID file", err)
 	}
 
-	datasetId, err := os.ReadFile(datasetIdPath)
+	datasetIdString, err := readIDArgument(datasetIdPath)
 	if err != nil {
-		log.Fatalln("Failed to read dataset ID file", err)
+		fatalln("Failed to read dataset ID file", err)
 	}
+	fmt.Fprintln(deps.Stdout, "Sensory ID: ", sensoryIdString)
+	fmt.Fprintln(deps.Stdout, "Dataset ID: ", datasetIdString)
 
-	sensoryIdString := strings.TrimSuffix(strings.TrimPrefix(string(sensoryId), " "), " ")
-	datasetIdString := strings.TrimSuffix(strings.TrimPrefix(string(datasetId), " "), " ")
-	fmt.Println("Sensory ID: ", sensoryIdString)
-	fmt.Println("Dataset ID: ", datasetIdString)
-
-	result, err := authenticationService.CreateRequest(sensoryIdString, datasetIdString, accessToken)
+	result, err := authenticationService.CreateRequest(cmd.Context(), sensoryIdString, datasetIdString)
 	if err != nil {
-		log.Fatalln(err.Error())
+		exitIfCancelled(cmd.Context())
+		fatalln(err.Error())
 	}
 	if result != nil {
 		if result.ResponseCode == "00" {
-			fmt.Println("Create Request success please wait our operation to complete, you can check the status by the command line.")
+			recordJob(jobRecord{
+				RequestID: result.Data.RequestID,
+				DatasetID: datasetIdString,
+				SensoryID: sensoryIdString,
+				CreatedAt: deps.Now(),
+			})
+			fmt.Fprintln(deps.Stdout, "Create Request success please wait our operation to complete, you can check the status by the command line.")
 		} else {
-			fmt.Println("Upload sensory failed.")
-			fmt.Println("Upload sensory failed, reason : ", result.ResponseMessage)
+			fmt.Fprintln(deps.Stdout, "Upload sensory failed.")
+			fmt.Fprintln(deps.Stdout, "Upload sensory failed, reason : ", result.ResponseMessage)
 		}
 	}
 	return nil
 }
 
-func InitializeServiceCmd(serviceCmd *cobra.Command) {
-	sentinelCmd := &cobra.Command{
-		Use:   "sentinel",
-		Short: "Sentinel synexis service command console",
-		Long:  `Sentinel synexis service command console`,
-	}
-	sentinelCmd.AddCommand(&cobra.Command{
-		Use:   "apikey",
-		Short: "Sentinel API Key generate be careful with this command",
-		Long:  `Sentinel API Key generate be careful with this command`,
-		RunE:  generateAPIKey,
-	})
-	datasetCmd := &cobra.Command{
-		Use:   "dataset",
-		Short: "Sentinel upload dataset for custom training",
-		Long:  `Sentinel upload dataset for custom training`,
-		Args:  cobra.ExactArgs(1),
-		RunE:  uploadDatasetFile,
-	}
-	sensoryCmd := &cobra.Command{
-		Use:   "sensory",
-		Short: "Sentinel upload sensory configuration for custom training",
-		Long:  `Sentinel upload sensory configuration for custom training`,
-		Args:  cobra.ExactArgs(1),
-		RunE:  uploadSensoryFile,
-	}
-	requestCmd := &cobra.Command{
-		Use:   "request",
-		Short: "Sentinel request training custom model using selected dataset and sensory id",
-		Long:  `Sentinel request training custom model using selected dataset and sensory id`,
-		RunE:  createRequestTraining,
-	}
-
-	datasetCmd.Flags().StringP("output", "o", "", "Path to output file for saving DatasetID")
-	sentinelCmd.AddCommand(datasetCmd)
-	sensoryCmd.Flags().StringP("output", "o", "", "Path to output file for saving SensoryID")
-	sentinelCmd.AddCommand(sensoryCmd)
-	requestCmd.Flags().StringP("sensory", "s", "", "Path to saved sensory id file")
-	requestCmd.Flags().StringP("dataset", "d", "", "Path to saved dataset id file")
-	sentinelCmd.AddCommand(requestCmd)
-	serviceCmd.AddCommand(sentinelCmd)
+// readIDArgument accepts either a file written by an upload's --output or
+// the ID itself, as offered by shell completion.
+func readIDArgument(value string) (string, error) {
+	raw, err := os.ReadFile(value)
+	if errors.Is(err, os.ErrNotExist) && !strings.ContainsAny(value, `/\`) {
+		return value, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(string(raw), " "), " "), nil
 }
+
+func requestStatus(cmd *cobra.Command, args []string) error {
+	authenticationService, _, closeStore := openAuthenticatedService(nil)
+	defer closeStore()
+	result, err := authenticationService.RequestStatus(cmd.Context(), args[0])
+	if err != nil {
+		exitIfCancelled(cmd.Context())
+		fatalln(err.Error())
+	}
+	if result.ResponseCode != "00" {
+		fatalln("Request status failed, reason :", result.ResponseMessage)
+	}
+	request := result.Data
+	fmt.Fprintln(deps.Stdout, "Request ID: ", request.RequestID)
+	fmt.Fprintln(deps.Stdout, "Status:     ", request.Status)
+	if request.Message != "" {
+		fmt.Fprintln(deps.Stdout, "Message:    ", request.Message)
+	}
+	fmt.Fprintln(deps.Stdout, "Sensory ID: ", request.SensoryID)
+	fmt.Fprintln(deps.Stdout, "Dataset ID: ", request.DatasetID)
+	fmt.Fprintln(deps.Stdout, "Created at: ", request.CreatedAt.Local().Format(time.DateTime))
+	fmt.Fprintln(deps.Stdout, "Updated at: ", request.UpdatedAt.Local().Format(time.DateTime))
+	return nil
+}
+
+func listAPIKeys(cmd *cobra.Command, args []string) error {
+	authenticationService, _, closeStore := openAuthenticatedService(nil)
+	defer closeStore()
+	result, err := authenticationService.ListAPIKeys(cmd.Context())
+	if err != nil {
+		exitIfCancelled(cmd.Context())
+		fatalln(err.Error())
+	}
+	if result.ResponseCode != "00" {
+		fatalln("API Key list failed, reason :", result.ResponseMessage)
+	}
+	for _, key := range result.Data {
+		if len(args) == 1 && !strings.HasPrefix(key.Prefix, args[0]) {
+			continue
+		}
+		fmt.Fprintf(deps.Stdout, "%s\t%s\n", key.Prefix, key.CreatedAt.Local().Format(time.DateTime))
+	}
+	return nil
+}
+
+type pendingUpload struct {
+	Kind          string    `json:"kind"`
+	File          string    `json:"file"`
+	Output        string    `json:"output"`
+	Compress      string    `json:"compress,omitempty"`
+	Format        string    `json:"format,omitempty"`
+	Include       []string  `json:"include,omitempty"`
+	Exclude       []string  `json:"exclude,omitempty"`
+	InterruptedAt time.Time `json:"interruptedAt"`
+}
+
+func pendingUploadKey(kind string) string {
+	return "pending_upload_" + kind
+}
+
+// resolvePendingUpload returns the upload described by the command line, or
+// the one left behind by an interrupted run when --rerun-interrupted is
+// given. The server has no partial uploads, so that upload starts over from
+// the first byte.
+func resolvePendingUpload(cmd *cobra.Command, store storage.Storage, kind string, args []string) (*pendingUpload, error) {
+	outputPath, _ := cmd.Flags().GetString("output")
+	rerun, _ := cmd.Flags().GetBool("rerun-interrupted")
+	if !rerun {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("accepts 1 arg(s), received %d", len(args))
+		}
+		upload := &pendingUpload{Kind: kind, File: args[0], Output: outputPath}
+		upload.Compress, _ = cmd.Flags().GetString("compress")
+		upload.Format, _ = cmd.Flags().GetString("format")
+		upload.Include, _ = cmd.Flags().GetStringSlice("include")
+		upload.Exclude, _ = cmd.Flags().GetStringSlice("exclude")
+		return upload, checkCompress(upload.Compress)
+	}
+	raw, err := store.Get(pendingUploadKey(kind))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interrupted %s upload: %w", kind, err)
+	}
+	if raw == "" {
+		return nil, fmt.Errorf("no interrupted %s upload to run again", kind)
+	}
+	var upload pendingUpload
+	if err := json.Unmarshal([]byte(raw), &upload); err != nil {
+		return nil, fmt.Errorf("failed to read interrupted %s upload: %w", kind, err)
+	}
+	if len(args) == 1 {
+		upload.File = args[0]
+	}
+	if outputPath != "" {
+		upload.Output = outputPath
+	}
+	if cmd.Flags().Changed("compress") {
+		upload.Compress, _ = cmd.Flags().GetString("compress")
+	}
+	if cmd.Flags().Changed("format") {
+		upload.Format, _ = cmd.Flags().GetString("format")
+	}
+	if cmd.Flags().Changed("include") {
+		upload.Include, _ = cmd.Flags().GetStringSlice("include")
+	}
+	if cmd.Flags().Changed("exclude") {
+		upload.Exclude, _ = cmd.Flags().GetStringSlice("exclude")
+	}
+	if err := checkCompress(upload.Compress); err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(deps.Stdout, "Uploading", upload.File, "again from the start, the", kind, "upload was interrupted at", upload.InterruptedAt.Format(time.DateTime))
+	return &upload, nil
+}
+
+func savePendingUpload(store storage.Storage, upload *pendingUpload) {
+	upload.InterruptedAt = deps.Now()
+	raw, err := json.Marshal(upload)
+	if err != nil {
+		return
+	}
+	if err := store.Set(pendingUploadKey(upload.Kind), string(raw)); err == nil {
+		fmt.Fprintf(deps.Stderr, "Upload state saved, run again with --rerun-interrupted to upload %s again from the start.\n", upload.File)
+	}
+}
+
+func clearPendingUpload(store storage.Storage, kind string) {
+	_ = store.Delete(pendingUploadKey(kind))
+}
+
+const (
+	compressNone  = "none"
+	compressUsage = "Compress the upload: none, gzip, zstd, or auto to pick what the server accepts and skip files that are already compressed. Falls back to uncompressed when the server does not accept it"
+)
+
+var completeCompress = cobra.FixedCompletions([]string{compressNone, client.EncodingGzip, client.EncodingZstd, client.EncodingAuto}, cobra.ShellCompDirectiveNoFileComp)
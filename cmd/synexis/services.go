@@ -0,0 +1,199 @@
+package synexis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/synxms/synexis/pkg/client"
+	"github.com/synxms/synexis/src/service"
+	"text/tabwriter"
+)
+
+// Availability of a service as shown by "service list".
+const (
+	serviceAvailable   = "available"
+	serviceUnavailable = "unavailable"
+	serviceUnknown     = "unknown"
+)
+
+type (
+	// serviceModule is a Synexis service plugged into "synexis service". Each
+	// service registers itself from an init function in its own file, like
+	// sentinel.go. Its API calls still go through service.Authentication,
+	// so a new service adds its methods there as well.
+	serviceModule struct {
+		Name  string
+		Short string
+		Long  string
+		// Endpoints are the API paths the service calls.
+		Endpoints []string
+		// Available reports whether the connected server offers the service,
+		// asked when the server does not list its services. Nil probes
+		// Endpoints.
+		Available func(ctx context.Context, authenticationService service.Authentication) (bool, error)
+		// Initialize adds the subcommands to the command of the service.
+		Initialize func(cmd *cobra.Command)
+	}
+	serviceListing struct {
+		Name        string `json:"name"`
+		Status      string `json:"status"`
+		Version     string `json:"version,omitempty"`
+		Description string `json:"description,omitempty"`
+		// Probed is set when the server has no service list and the
+		// status comes from the capability check.
+		Probed    bool   `json:"probed,omitempty"`
+		Installed bool   `json:"installed"`
+		Error     string `json:"error,omitempty"`
+	}
+)
+
+var serviceModules []serviceModule
+
+// registerServiceModule adds a service, the name becomes its subcommand.
+func registerServiceModule(module serviceModule) {
+	if module.Name == "" || module.Name == "list" || module.Name == "help" || module.Initialize == nil {
+		panic(fmt.Sprintf("synexis: invalid service module %q", module.Name))
+	}
+	for _, registered := range serviceModules {
+		if registered.Name == module.Name {
+			panic(fmt.Sprintf("synexis: service module %q registered twice", module.Name))
+		}
+	}
+	serviceModules = append(serviceModules, module)
+}
+
+// available runs the capability check of the module. Without its own, the
+// service is there when the server routes any of its endpoints.
+func (m serviceModule) available(ctx context.Context, authenticationService service.Authentication) (bool, error) {
+	if m.Available != nil {
+		return m.Available(ctx, authenticationService)
+	}
+	var lastErr error
+	for _, endpoint := range m.Endpoints {
+		found, err := authenticationService.ProbeEndpoint(ctx, endpoint)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if found {
+			return true, nil
+		}
+	}
+	return false, lastErr
+}
+
+func listServices(cmd *cobra.Command, _ []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+	authenticationService, _, closeStore := openAuthenticatedService(nil)
+	defer closeStore()
+
+	var listings []serviceListing
+	probed := false
+	result, err := authenticationService.ListServices(cmd.Context())
+	switch {
+	case errors.Is(err, client.ErrNotSupported):
+		listings, probed = probeServices(cmd.Context(), authenticationService), true
+	case err != nil:
+		exitIfCancelled(cmd.Context())
+		fatalln("Failed to list services:", err)
+	case result.ResponseCode != "00":
+		fatalln("List services failed, reason :", result.ResponseMessage)
+	default:
+		listings = listedServices(result.Data)
+	}
+
+	if output == "json" {
+		encoder := json.NewEncoder(deps.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(listings)
+	}
+	table := tabwriter.NewWriter(deps.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tSTATUS\tVERSION\tDESCRIPTION\tNOTE")
+	for _, listing := range listings {
+		note := listing.Error
+		if !listing.Installed {
+			note = "no commands in this synexis version"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", listing.Name, listing.Status, listing.Version, listing.Description, note)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	if probed {
+		fmt.Fprintln(deps.Stderr, "The server does not list its services, availability was probed from their endpoints.")
+	}
+	return nil
+}
+
+// listedServices matches the services the server lists with the registered
+// modules, registered services the server left out are unavailable.
+func listedServices(offered []client.ServiceInfo) []serviceListing {
+	listings := make([]serviceListing, 0, len(serviceModules)+len(offered))
+	listed := map[string]client.ServiceInfo{}
+	for _, info := range offered {
+		listed[info.Name] = info
+	}
+	for _, module := range serviceModules {
+		listing := serviceListing{Name: module.Name, Status: serviceUnavailable, Description: module.Short, Installed: true}
+		if info, ok := listed[module.Name]; ok {
+			listing.Status, listing.Version = info.Status, info.Version
+			delete(listed, module.Name)
+		}
+		listings = append(listings, listing)
+	}
+	for _, info := range offered {
+		if _, ok := listed[info.Name]; ok {
+			listings = append(listings, serviceListing{Name: info.Name, Status: info.Status, Version: info.Version, Description: info.Description})
+		}
+	}
+	return listings
+}
+
+func probeServices(ctx context.Context, authenticationService service.Authentication) []serviceListing {
+	listings := make([]serviceListing, 0, len(serviceModules))
+	for _, module := range serviceModules {
+		listing := serviceListing{Name: module.Name, Status: serviceUnavailable, Description: module.Short, Probed: true, Installed: true}
+		found, err := module.available(ctx, authenticationService)
+		exitIfCancelled(ctx)
+		switch {
+		case err != nil:
+			listing.Status, listing.Error = serviceUnknown, err.Error()
+		case found:
+			listing.Status = serviceAvailable
+		}
+		listings = append(listings, listing)
+	}
+	return listings
+}
+
+// InitializeServiceCmd adds a command for every registered service module.
+func InitializeServiceCmd(serviceCmd *cobra.Command) {
+	for _, module := range serviceModules {
+		long := module.Long
+		if long == "" {
+			long = module.Short
+		}
+		moduleCmd := &cobra.Command{
+			Use:   module.Name,
+			Short: module.Short,
+			Long:  long,
+		}
+		module.Initialize(moduleCmd)
+		serviceCmd.AddCommand(moduleCmd)
+	}
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the services the connected server offers",
+		Long:  `List the services the connected server offers and whether this synexis version has commands for them. Servers without a service list are asked for each known service by probing its endpoints`,
+		Args:  cobra.NoArgs,
+		RunE:  listServices,
+	}
+	listCmd.Flags().StringP("output", "o", "text", "Output format, text or json")
+	_ = listCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"text", "json"}, cobra.ShellCompDirectiveNoFileComp))
+	serviceCmd.AddCommand(listCmd)
+}
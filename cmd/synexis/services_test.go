@@ -0,0 +1,31 @@
+package synexis
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/synxms/synexis/pkg/mockserver"
+)
+
+// Servers without a service list are probed at the endpoints each module
+// declares.
+func TestServiceListProbesModuleEndpoints(t *testing.T) {
+	e := newCLIEnv(t, mockserver.WithServices())
+	e.login()
+	result := e.mustRun("service", "list")
+	if !regexp.MustCompile(`(?m)^sentinel\s+available\b`).MatchString(result.Stdout) {
+		t.Errorf("service list printed\n%s\nwant sentinel available", result.Stdout)
+	}
+	if !strings.Contains(result.Stderr, "availability was probed") {
+		t.Errorf("service list reported\n%s\nwant a note that it probed", result.Stderr)
+	}
+	authenticationService, _, closeStore := openAuthenticatedService(nil)
+	defer closeStore()
+	for _, endpoint := range sentinelEndpoints {
+		found, err := authenticationService.ProbeEndpoint(t.Context(), endpoint)
+		if err != nil || !found {
+			t.Errorf("probing %s found %v, %v, want a sentinel endpoint", endpoint, found, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package synexis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/synxms/synexis/pkg/agent"
+	"github.com/synxms/synexis/pkg/client"
+	"github.com/synxms/synexis/pkg/httpclient"
+	"github.com/synxms/synexis/pkg/storage"
+	"github.com/synxms/synexis/src/service"
+	"os"
+	"strings"
+	"time"
+)
+
+const envBaseURL = "SYNEXIS_BASE_URL"
+
+var (
+	tokenCommand  string
+	verifyOptions client.VerifyOptions
+)
+
+// environmentTokenSource picks credentials supplied outside the local store,
+// in order: --token-command, SYNEXIS_TOKEN_COMMAND, SYNEXIS_API_KEY and
+// SYNEXIS_ACCESS_TOKEN.
+func environmentTokenSource() (client.TokenSource, bool) {
+	if tokenCommand != "" {
+		return client.CommandTokenSource(tokenCommand), true
+	}
+	if command := os.Getenv(client.EnvTokenCommand); command != "" {
+		return client.CommandTokenSource(command), true
+	}
+	if apiKey := os.Getenv(client.EnvAPIKey); apiKey != "" {
+		return client.APIKeyTokenSource(apiKey), true
+	}
+	if os.Getenv(client.EnvAccessToken) != "" {
+		return client.EnvTokenSource(), true
+	}
+	return nil, false
+}
+
+type tokenSourceFunc func(ctx context.Context) (*client.Token, error)
+
+func (f tokenSourceFunc) Token(ctx context.Context) (*client.Token, error) {
+	return f(ctx)
+}
+
+// agentTokenSource uses the credential agent of the active profile when one
+// is running for baseUrl. An agent serving another server is ignored, its
+// tokens are not meant for baseUrl.
+func agentTokenSource(baseUrl string) (client.TokenSource, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	socketPath := agentSocketPath()
+	status, err := agent.Query(ctx, socketPath, agent.OpStatus)
+	switch {
+	case errors.Is(err, agent.ErrUntrusted):
+		fmt.Fprintln(deps.Stderr, "Warning: ignoring the credential agent:", err)
+		return nil, false
+	case err != nil:
+		return nil, false
+	case strings.TrimSuffix(status.BaseURL, "/") != strings.TrimSuffix(baseUrl, "/"):
+		fmt.Fprintf(deps.Stderr, "Warning: ignoring the credential agent, it serves %s instead of %s\n", status.BaseURL, baseUrl)
+		return nil, false
+	}
+	return agent.TokenSource(socketPath), true
+}
+
+// openAuthenticatedService resolves the base url and credentials for calls
+// that need an access token. When the base url comes from the environment
+// and the credentials from the environment or a running agent, and no store
+// is passed in, the local store is never opened so CI can run without it.
+// The store in use, nil in that case, is returned along with a func that
+// releases anything opened here.
+func openAuthenticatedService(store storage.Storage) (service.Authentication, storage.Storage, func()) {
+	authenticationService, store, closeStore, err := connectService(store, httpConfig)
+	if err != nil {
+		fatalln(err)
+	}
+	return authenticationService, store, closeStore
+}
+
+// connectService is openAuthenticatedService for callers that must not
+// exit, such as shell completions and plugin launches. config is used in
+// place of the flag-configured http client settings.
+func connectService(store storage.Storage, config httpclient.Config) (service.Authentication, storage.Storage, func(), error) {
+	closeStore := func() {}
+	openStore := func() error {
+		if store != nil {
+			return nil
+		}
+		opened := deps.NewStorage("")
+		if err := opened.Init(); err != nil {
+			return fmt.Errorf("failed to init storage: %w", err)
+		}
+		store, closeStore = opened, opened.Close
+		return nil
+	}
+	baseUrl := os.Getenv(envBaseURL)
+	if baseUrl == "" {
+		if err := openStore(); err != nil {
+			return nil, nil, nil, err
+		}
+		var err error
+		if baseUrl, err = store.Get("base_url"); err != nil {
+			closeStore()
+			return nil, nil, nil, fmt.Errorf("failed to get base url: %w", err)
+		}
+	}
+	tokenSource, fromEnvironment := environmentTokenSource()
+	if !fromEnvironment {
+		tokenSource, fromEnvironment = agentTokenSource(baseUrl)
+	}
+	tokenOption := client.WithTokenSource(tokenSource)
+	if !fromEnvironment {
+		if err := openStore(); err != nil {
+			return nil, nil, nil, err
+		}
+		tokenOption = client.WithStoredTokens(store)
+	}
+	authenticationService, err := buildAuthenticationService(baseUrl, config, tokenOption)
+	if err != nil {
+		closeStore()
+		return nil, nil, nil, err
+	}
+	return authenticationService, store, closeStore, nil
+}
@@ -0,0 +1,303 @@
+package synexis
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/synxms/synexis/pkg/storage"
+	"os"
+	"sort"
+	"strings"
+)
+
+const envStorePassphrase = "SYNEXIS_STORE_PASSPHRASE"
+
+func openStore() storage.Storage {
+	store := deps.NewStorage("")
+	if err := store.Init(); err != nil {
+		fatalln("Failed to init storage:", err)
+	}
+	return store
+}
+
+// selectedProfiles is the active profile, or every profile with --all-profiles.
+func selectedProfiles(cmd *cobra.Command, store storage.Storage) []string {
+	if all, _ := cmd.Flags().GetBool("all-profiles"); !all {
+		return []string{store.Profile()}
+	}
+	profiles, err := store.Profiles()
+	if err != nil {
+		fatalln("Failed to list profiles:", err)
+	}
+	return profiles
+}
+
+// storePassphrase reads the export passphrase from --passphrase-file or
+// SYNEXIS_STORE_PASSPHRASE, it is never taken from the command line.
+func storePassphrase(cmd *cobra.Command) string {
+	if path, _ := cmd.Flags().GetString("passphrase-file"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			fatalln("Failed to read passphrase file:", err)
+		}
+		return strings.TrimRight(string(raw), "\r\n")
+	}
+	return os.Getenv(envStorePassphrase)
+}
+
+func storePath(_ *cobra.Command, _ []string) error {
+	path, err := storage.Path()
+	if err != nil {
+		fatalln("Failed to resolve store path:", err)
+	}
+	configPath, err := storage.ConfigPath()
+	if err != nil {
+		fatalln("Failed to resolve store path:", err)
+	}
+	fmt.Fprintln(deps.Stdout, path)
+	if configPath != path {
+		fmt.Fprintln(deps.Stdout, configPath)
+	}
+	return nil
+}
+
+func storeList(cmd *cobra.Command, _ []string) error {
+	store := openStore()
+	defer store.Close()
+	for _, profile := range selectedProfiles(cmd, store) {
+		profileStore := deps.NewStorage(profile)
+		if err := profileStore.Init(); err != nil {
+			fatalln("Failed to init storage:", err)
+		}
+		keys, err := profileStore.Keys()
+		if err != nil {
+			fatalln("Failed to list keys:", err)
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(deps.Stdout, "[%s]\n", profile)
+		for _, key := range keys {
+			value, err := profileStore.Get(key)
+			if err != nil {
+				fatalln("Failed to read "+key+":", err)
+			}
+			if storage.IsSecretKey(key) {
+				value = "(secret, " + fmt.Sprint(len(value)) + " bytes)"
+			} else if len(value) > 80 {
+				value = value[:77] + "..."
+			}
+			fmt.Fprintf(deps.Stdout, "  %s = %s\n", key, value)
+		}
+	}
+	return nil
+}
+
+func storeGet(_ *cobra.Command, args []string) error {
+	store := openStore()
+	defer store.Close()
+	value, err := store.Get(args[0])
+	if err != nil {
+		fatalln("Failed to read "+args[0]+":", err)
+	}
+	fmt.Fprintln(deps.Stdout, value)
+	return nil
+}
+
+func storeDelete(_ *cobra.Command, args []string) error {
+	store := openStore()
+	defer store.Close()
+	if err := store.Delete(args[0]); err != nil {
+		fatalln("Failed to delete "+args[0]+":", err)
+	}
+	fmt.Fprintln(deps.Stdout, "Deleted", args[0])
+	return nil
+}
+
+func storeExport(cmd *cobra.Command, _ []string) error {
+	includeSecrets, _ := cmd.Flags().GetBool("include-secrets")
+	outputPath, _ := cmd.Flags().GetString("output")
+	passphrase := ""
+	if includeSecrets {
+		if passphrase = storePassphrase(cmd); passphrase == "" {
+			fatalln("Exporting secrets needs a passphrase, set " + envStorePassphrase + " or use --passphrase-file")
+		}
+	}
+	store := openStore()
+	defer store.Close()
+	archive := storage.NewArchive()
+	for _, profile := range selectedProfiles(cmd, store) {
+		profileStore := deps.NewStorage(profile)
+		if err := profileStore.Init(); err != nil {
+			fatalln("Failed to init storage:", err)
+		}
+		if err := archive.AddProfile(profileStore, passphrase); err != nil {
+			fatalln("Failed to export profile "+profile+":", err)
+		}
+	}
+	raw, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		fatalln("Failed to encode export:", err)
+	}
+	if outputPath == "" || outputPath == "-" {
+		fmt.Fprintln(deps.Stdout, string(raw))
+		return nil
+	}
+	if err := os.WriteFile(outputPath, append(raw, '\n'), 0600); err != nil {
+		fatalln("Failed to write export:", err)
+	}
+	fmt.Fprintf(deps.Stderr, "Exported %d profile(s) to %s\n", len(archive.Profiles), outputPath)
+	return nil
+}
+
+func storeImport(cmd *cobra.Command, args []string) error {
+	raw, err := os.ReadFile(args[0])
+	if err != nil {
+		fatalln("Failed to read export:", err)
+	}
+	archive, err := storage.ParseArchive(raw)
+	if err != nil {
+		fatalln(err.Error())
+	}
+	passphrase := storePassphrase(cmd)
+	names := make([]string, 0, len(archive.Profiles))
+	for name := range archive.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		profileStore := deps.NewStorage(name)
+		if err := profileStore.Init(); err != nil {
+			fatalln("Failed to init storage:", err)
+		}
+		written, err := archive.Profiles[name].Restore(profileStore, passphrase)
+		if err != nil {
+			fatalln("Failed to import profile "+name+":", err)
+		}
+		fmt.Fprintf(deps.Stdout, "Imported %d key(s) into profile %s.\n", written, name)
+	}
+	return nil
+}
+
+func storeReset(cmd *cobra.Command, _ []string) error {
+	if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+		fatalln("This deletes every stored key including tokens, run again with --yes to confirm")
+	}
+	store := openStore()
+	defer store.Close()
+	for _, profile := range selectedProfiles(cmd, store) {
+		profileStore := deps.NewStorage(profile)
+		if err := profileStore.Init(); err != nil {
+			fatalln("Failed to init storage:", err)
+		}
+		if err := profileStore.Reset(); err != nil {
+			fatalln("Failed to reset profile "+profile+":", err)
+		}
+		fmt.Fprintf(deps.Stdout, "Profile %s reset.\n", profile)
+	}
+	return nil
+}
+
+func storeMigrate(cmd *cobra.Command, _ []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	current, pending, err := storage.MigrationStatus()
+	if err != nil {
+		fatalln("Failed to read store schema:", err)
+	}
+	fmt.Fprintf(deps.Stdout, "Store schema version %d, this synexis writes version %d.\n", current, storage.SchemaVersion)
+	if len(pending) == 0 {
+		fmt.Fprintln(deps.Stdout, "Nothing to migrate.")
+		return nil
+	}
+	for _, migration := range pending {
+		fmt.Fprintf(deps.Stdout, "  %d: %s\n", migration.Version, migration.Description)
+	}
+	if dryRun {
+		fmt.Fprintln(deps.Stdout, "Dry run, nothing changed.")
+		return nil
+	}
+	result, err := storage.Migrate()
+	if err != nil {
+		fatalln("Failed to migrate store:", err)
+	}
+	if result.BackupPath != "" {
+		fmt.Fprintln(deps.Stdout, "Backup saved to", result.BackupPath)
+	}
+	fmt.Fprintf(deps.Stdout, "Store migrated to schema version %d.\n", result.To)
+	return nil
+}
+
+func InitializeStoreCmd(storeCmd *cobra.Command) {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade the local store to the current schema version",
+		Long:  `Upgrade the local store to the current schema version, a backup is written before anything changes`,
+		Args:  cobra.NoArgs,
+		RunE:  storeMigrate,
+	}
+	migrateCmd.Flags().Bool("dry-run", false, "Only list the migrations that would run")
+	storeCmd.AddCommand(migrateCmd)
+	storeCmd.AddCommand(&cobra.Command{
+		Use:   "path",
+		Short: "Print the location of the local store",
+		Long:  `Print the location of the local store, tokens and caches first, then settings and profiles when they are kept in a separate file`,
+		Args:  cobra.NoArgs,
+		RunE:  storePath,
+	})
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List stored keys, secrets are masked",
+		Long:  `List stored keys, secrets are masked`,
+		Args:  cobra.NoArgs,
+		RunE:  storeList,
+	}
+	listCmd.Flags().Bool("all-profiles", false, "List every profile")
+	storeCmd.AddCommand(listCmd)
+	storeCmd.AddCommand(&cobra.Command{
+		Use:   "get [key]",
+		Short: "Print a stored value",
+		Long:  `Print a stored value`,
+		Args:  cobra.ExactArgs(1),
+		RunE:  storeGet,
+
+		ValidArgsFunction: completeStoreKeys,
+	})
+	storeCmd.AddCommand(&cobra.Command{
+		Use:   "delete [key]",
+		Short: "Delete a stored value",
+		Long:  `Delete a stored value`,
+		Args:  cobra.ExactArgs(1),
+		RunE:  storeDelete,
+
+		ValidArgsFunction: completeStoreKeys,
+	})
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export settings and the job registry to a portable JSON archive",
+		Long:  `Export settings and the job registry to a portable JSON archive, tokens are only included encrypted with --include-secrets`,
+		Args:  cobra.NoArgs,
+		RunE:  storeExport,
+	}
+	exportCmd.Flags().StringP("output", "o", "", "Path to write the archive to, defaults to stdout")
+	exportCmd.Flags().Bool("include-secrets", false, "Include tokens encrypted with a passphrase from "+envStorePassphrase+" or --passphrase-file")
+	exportCmd.Flags().String("passphrase-file", "", "File holding the passphrase used to encrypt secrets")
+	exportCmd.Flags().Bool("all-profiles", false, "Export every profile")
+	storeCmd.AddCommand(exportCmd)
+	importCmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Merge a JSON archive written by store export into the local store",
+		Long:  `Merge a JSON archive written by store export into the local store, keys present in both are overwritten`,
+		Args:  cobra.ExactArgs(1),
+		RunE:  storeImport,
+	}
+	importCmd.Flags().String("passphrase-file", "", "File holding the passphrase used to decrypt secrets")
+	storeCmd.AddCommand(importCmd)
+	resetCmd := &cobra.Command{
+		Use:   "reset",
+		Short: "Delete everything in the local store",
+		Long:  `Delete everything in the active profile of the local store, or in every profile with --all-profiles`,
+		Args:  cobra.NoArgs,
+		RunE:  storeReset,
+	}
+	resetCmd.Flags().Bool("yes", false, "Confirm the reset")
+	resetCmd.Flags().Bool("all-profiles", false, "Reset every profile")
+	storeCmd.AddCommand(resetCmd)
+}
@@ -1,38 +1,140 @@
 package synexis
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/synxms/synexis/pkg/client"
+	"github.com/synxms/synexis/pkg/httpclient"
 	"github.com/synxms/synexis/pkg/storage"
 	"github.com/synxms/synexis/pkg/utility"
 	"github.com/synxms/synexis/src/service"
-	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
-func synexisAuthenticate(_ *cobra.Command, _ []string) error {
-	store := storage.NewStorage()
+const (
+	exitCodeCheckFailed = 1
+	exitCodeTimeout     = 124
+	exitCodeInterrupted = 130
+)
+
+var (
+	commandTimeout time.Duration
+	cancelTimeout  context.CancelFunc = func() {}
+)
+
+// exitIfCancelled stops the command with a distinct exit code when it was
+// interrupted or ran past --timeout, so scripts can tell it from a failure.
+func exitIfCancelled(ctx context.Context) {
+	exitOnContextError(ctx.Err())
+}
+
+func exitOnContextError(err error) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		fmt.Fprintln(deps.Stderr, "Command timed out.")
+		deps.Exit(exitCodeTimeout)
+	case errors.Is(err, context.Canceled):
+		fmt.Fprintln(deps.Stderr, "Interrupted.")
+		deps.Exit(exitCodeInterrupted)
+	}
+}
+
+var (
+	profile   string
+	storeFile string
+)
+
+func useStoreFlags() {
+	if profile != "" {
+		storage.UseProfile(profile)
+	}
+	if storeFile != "" {
+		storage.UsePath(storeFile)
+	}
+}
+
+func prepareCommand(cmd *cobra.Command, _ []string) {
+	useStoreFlags()
+	if commandTimeout > 0 {
+		var ctx context.Context
+		ctx, cancelTimeout = context.WithTimeout(cmd.Context(), commandTimeout)
+		cmd.SetContext(ctx)
+	}
+}
+
+var (
+	httpConfig = httpclient.DefaultConfig()
+	debugHTTP  bool
+)
+
+var errNoBaseURL = errors.New("please provide base url before continue")
+
+func newAuthenticationService(baseUrl string, opts ...client.Option) service.Authentication {
+	authenticationService, err := buildAuthenticationService(baseUrl, httpConfig, opts...)
+	if err != nil {
+		fatalln(err)
+	}
+	return authenticationService
+}
+
+// buildAuthenticationService is newAuthenticationService for callers that
+// must not exit, config is used in place of the flag-configured one.
+func buildAuthenticationService(baseUrl string, config httpclient.Config, opts ...client.Option) (service.Authentication, error) {
+	if !utility.IsValidURL(baseUrl) {
+		return nil, errNoBaseURL
+	}
+	if debugHTTP || debugEnabled("http") {
+		config.DebugOutput = deps.Stderr
+	}
+	httpClient, err := httpclient.New(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure http client: %w", err)
+	}
+	return deps.NewClient(baseUrl, httpClient, opts...), nil
+}
+
+// debugEnabled reports whether SYNEXIS_DEBUG lists the given facility,
+// e.g. SYNEXIS_DEBUG=http or SYNEXIS_DEBUG=http,body.
+func debugEnabled(facility string) bool {
+	for _, value := range strings.Split(os.Getenv("SYNEXIS_DEBUG"), ",") {
+		if strings.TrimSpace(value) == facility {
+			return true
+		}
+	}
+	return false
+}
+
+func synexisAuthenticate(cmd *cobra.Command, _ []string) error {
+	store := deps.NewStorage("")
 	if err := store.Init(); err != nil {
-		log.Fatalln("Failed to init storage:", err)
+		fatalln("Failed to init storage:", err)
 	}
 	defer store.Close()
 	// get base url
 	baseUrl, err := store.Get("base_url")
 	if err != nil {
-		log.Fatalln("Failed to get access token:", err)
+		fatalln("Failed to get access token:", err)
 	}
-	authenticationService := service.NewAuthentication(baseUrl)
-	result, err := authenticationService.GenerateLoginWithGoogle()
+	authenticationService := newAuthenticationService(baseUrl)
+	result, err := authenticationService.GenerateLoginWithGoogle(cmd.Context())
 	if err != nil {
-		log.Fatalln(err.Error())
+		exitIfCancelled(cmd.Context())
+		fatalln(err.Error())
 	}
 	if result != nil {
 		if result.ResponseCode == "00" {
 			err := authenticationService.OpenDefaultBrowser(result.RedirectURL)
 			if err != nil {
-				log.Fatalln(err.Error())
+				fatalln(err.Error())
 			}
 		} else {
-			fmt.Println("Authentication failed.")
+			fmt.Fprintln(deps.Stdout, "Authentication failed.")
 		}
 	}
 	return nil
@@ -40,61 +142,160 @@ func synexisAuthenticate(_ *cobra.Command, _ []string) error {
 
 func synexisServerBaseURL(_ *cobra.Command, args []string) error {
 	if !utility.IsValidURL(args[0]) {
-		log.Fatalln("please provide valid base url before continue")
+		fatalln("please provide valid base url before continue")
 	}
-	store := storage.NewStorage()
+	store := deps.NewStorage("")
 	if err := store.Init(); err != nil {
-		log.Fatalln("Failed to init storage:", err)
+		fatalln("Failed to init storage:", err)
 	}
 	defer store.Close()
 	if err := store.Set("base_url", args[0]); err != nil {
-		log.Fatalln("Failed to store server base url:", err)
+		fatalln("Failed to store server base url:", err)
 	}
 	return nil
 }
 
-var (
-	rootCmd = &cobra.Command{
+var rootCmd *cobra.Command
+
+func Initialize() {
+	rootCmd = NewRootCommand(DefaultDependencies())
+}
+
+// NewRootCommand builds the whole command tree on top of dependencies. Flag
+// values live in package state, so only the tree built last can be run.
+func NewRootCommand(dependencies Dependencies) *cobra.Command {
+	deps = dependencies
+	httpConfig = httpclient.DefaultConfig()
+	storage.UseNotices(deps.Stderr)
+	rootCmd := &cobra.Command{
 		Use:   "synexis",
 		Short: "Authentication tools for synexis",
-		Long:  `Authentication tools for synexis`,
+		Long:  `Authentication tools for synexis` + pluginsHelp,
+
+		PersistentPreRun: prepareCommand,
 	}
-	authenticateCmd = &cobra.Command{
+	authenticateCmd := &cobra.Command{
 		Use:   "authenticate",
 		Short: "Authentication to register or login into synexis account",
 		Long:  `Authentication to register or login into synexis account`,
 		RunE:  synexisAuthenticate,
 	}
-	serverCmd = &cobra.Command{
+	serverCmd := &cobra.Command{
 		Use:   "server-base-url",
 		Short: "First command should be executed to start using this cli tool",
 		Long:  `First command should be executed to start using this cli tool`,
 		Args:  cobra.ExactArgs(1),
 		RunE:  synexisServerBaseURL,
 	}
-	tokenCmd = &cobra.Command{
+	tokenCmd := &cobra.Command{
 		Use:   "token",
 		Short: "Token management after authentication",
 		Long:  `Token management after authentication`,
 	}
-	serviceCmd = &cobra.Command{
+	logoutCmd := &cobra.Command{
+		Use:   "logout",
+		Short: "Sign out by revoking and deleting stored tokens",
+		Long:  `Sign out by revoking the refresh token on the server when possible and deleting stored tokens`,
+		Args:  cobra.NoArgs,
+		RunE:  logout,
+	}
+	whoamiCmd := &cobra.Command{
+		Use:   "whoami",
+		Short: "Show the account, company and profile this machine is logged in as",
+		Long:  `Show the account, company and profile this machine is logged in as, confirmed with the server when reachable`,
+		Args:  cobra.NoArgs,
+		RunE:  whoami,
+	}
+	agentCmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Background credential helper serving fresh tokens to local tools",
+		Long:  `Background credential helper serving fresh tokens to local tools`,
+	}
+	storeCmd := &cobra.Command{
+		Use:   "store",
+		Short: "Local store maintenance",
+		Long:  `Local store maintenance`,
+	}
+	devCmd := &cobra.Command{
+		Use:   "dev",
+		Short: "Tools for developing against synexis",
+		Long:  `Tools for developing against synexis`,
+	}
+	serviceCmd := &cobra.Command{
 		Use:   "service",
 		Short: "Sub command for holds synexis services",
 		Long:  `Sub command for holds synexis services`,
 	}
-)
 
-func Initialize() {
+	rootCmd.SetIn(deps.Stdin)
+	rootCmd.SetOut(deps.Stdout)
+	rootCmd.SetErr(deps.Stderr)
+	flags := rootCmd.PersistentFlags()
+	flags.DurationVar(&httpConfig.ConnectTimeout, "connect-timeout", httpConfig.ConnectTimeout, "Timeout for establishing connections to the server")
+	flags.DurationVar(&httpConfig.ReadTimeout, "read-timeout", httpConfig.ReadTimeout, "Timeout for waiting on a server response")
+	flags.DurationVar(&httpConfig.Timeout, "http-timeout", httpConfig.Timeout, "Overall timeout for a single http request, 0 means no limit")
+	flags.IntVar(&httpConfig.MaxRetries, "retries", httpConfig.MaxRetries, "Maximum retries for idempotent or throttled requests")
+	flags.StringVar(&httpConfig.CACertFile, "ca-cert", "", "Path to PEM bundle of additional trusted certificate authorities")
+	flags.StringVar(&httpConfig.ClientCertFile, "client-cert", "", "Path to PEM client certificate for mTLS")
+	flags.StringVar(&httpConfig.ClientKeyFile, "client-key", "", "Path to PEM client private key for mTLS")
+	flags.StringVar(&profile, "profile", "", "Profile in the local store to use, defaults to $SYNEXIS_PROFILE or default")
+	flags.StringVar(&storeFile, "store-path", "", "Path of a single file holding the whole local store, defaults to $"+storage.EnvHome+"/synexis.db or the platform state and config directories")
+	flags.DurationVar(&commandTimeout, "timeout", 0, "Deadline for the whole command, 0 means no limit")
+	flags.BoolVar(&httpConfig.InsecureSkipVerify, "insecure-skip-verify", false, "Skip TLS certificate verification, never use in production")
+	flags.StringVar(&tokenCommand, "token-command", "", "Command that prints an access token, used instead of stored tokens")
+	flags.BoolVar(&verifyOptions.Offline, "offline", false, "Verify token signatures with cached signing keys only")
+	flags.StringVar(&verifyOptions.Issuer, "jwt-issuer", "", "Expected issuer of verified tokens")
+	flags.StringVar(&verifyOptions.Audience, "jwt-audience", "", "Expected audience of verified tokens")
+	flags.BoolVar(&debugHTTP, "debug-http", false, "Log http requests and responses to stderr with credentials redacted")
+	flags.BoolVar(&httpConfig.DebugBodies, "debug-http-body", debugEnabled("body"), "Also log small JSON bodies when --debug-http is set")
+	flags.StringVar(&httpConfig.TraceFile, "trace-file", "", "Write a HAR trace of http traffic to this file for support tickets")
+	_ = rootCmd.RegisterFlagCompletionFunc("profile", completeProfiles)
+	_ = rootCmd.RegisterFlagCompletionFunc("store-path", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return []string{"db"}, cobra.ShellCompDirectiveFilterFileExt
+	})
 	InitializeTokenCmd(tokenCmd)
 	InitializeServiceCmd(serviceCmd)
+	InitializeAgentCmd(agentCmd)
+	InitializeStoreCmd(storeCmd)
+	InitializeDevCmd(devCmd)
+	pluginCmd := &cobra.Command{
+		Use:   "plugin",
+		Short: "Inspect synexis plugins found on PATH",
+		Long:  `Inspect synexis plugins found on PATH`,
+	}
+	InitializePluginCmd(pluginCmd)
 	rootCmd.AddCommand(authenticateCmd)
 	rootCmd.AddCommand(serverCmd)
+	logoutCmd.Flags().Bool("all-profiles", false, "Sign out of every profile in the local store")
+	logoutCmd.Flags().Bool("purge", false, "Also wipe derived caches such as signing keys, interrupted uploads and the job registry")
+	rootCmd.AddCommand(logoutCmd)
+	whoamiCmd.Flags().StringP("output", "o", "text", "Output format, text or json")
+	rootCmd.AddCommand(whoamiCmd)
 	rootCmd.AddCommand(tokenCmd)
 	rootCmd.AddCommand(serviceCmd)
+	rootCmd.AddCommand(agentCmd)
+	rootCmd.AddCommand(storeCmd)
+	rootCmd.AddCommand(devCmd)
+	rootCmd.AddCommand(pluginCmd)
+	return rootCmd
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// a second signal falls through to the default handler and kills us
+		<-ctx.Done()
+		stop()
+	}()
+	if path, args, ok := findPlugin(rootCmd, os.Args[1:]); ok {
+		runPlugin(ctx, path, args)
+		return
+	}
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+	if err != nil {
+		exitOnContextError(err)
 		panic(err)
 	}
 }
@@ -1,154 +1,272 @@
 package synexis
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/synxms/synexis/pkg/client"
 	"github.com/synxms/synexis/pkg/storage"
 	"github.com/synxms/synexis/src/service"
-	"log"
+	"sort"
+	"strings"
+	"time"
 )
 
 func setAccessToken(_ *cobra.Command, args []string) error {
-	store := storage.NewStorage()
+	store := deps.NewStorage("")
 	if err := store.Init(); err != nil {
-		log.Fatalln("Failed to init storage:", err)
+		fatalln("Failed to init storage:", err)
 	}
 	defer store.Close()
 	if err := store.Set("access_token", args[0]); err != nil {
-		log.Fatalln("Failed to store access token:", err)
+		fatalln("Failed to store access token:", err)
 	}
-	fmt.Println("Access token saved.")
+	fmt.Fprintln(deps.Stdout, "Access token saved.")
 	return nil
 }
 
 func setRefreshToken(_ *cobra.Command, args []string) error {
-	store := storage.NewStorage()
+	store := deps.NewStorage("")
 	if err := store.Init(); err != nil {
-		log.Fatalln("Failed to init storage:", err)
+		fatalln("Failed to init storage:", err)
 	}
 	defer store.Close()
 	if err := store.Set("refresh_token", args[0]); err != nil {
-		log.Fatalln("Failed to store refresh token:", err)
+		fatalln("Failed to store refresh token:", err)
 	}
-	fmt.Println("Refresh token saved.")
+	fmt.Fprintln(deps.Stdout, "Refresh token saved.")
 	return nil
 }
 
 func getAccessToken(_ *cobra.Command, _ []string) error {
-	store := storage.NewStorage()
+	store := deps.NewStorage("")
 	if err := store.Init(); err != nil {
-		log.Fatalln("Failed to init storage:", err)
+		fatalln("Failed to init storage:", err)
 	}
 	defer store.Close()
 	result, err := store.Get("access_token")
 	if err != nil {
-		log.Fatalln("Failed to store access token:", err)
+		fatalln("Failed to store access token:", err)
 	}
-	fmt.Println(result)
+	fmt.Fprintln(deps.Stdout, result)
 	return nil
 }
 
 func getRefreshToken(_ *cobra.Command, _ []string) error {
-	store := storage.NewStorage()
+	store := deps.NewStorage("")
 	if err := store.Init(); err != nil {
-		log.Fatalln("Failed to init storage:", err)
+		fatalln("Failed to init storage:", err)
 	}
 	defer store.Close()
 	result, err := store.Get("refresh_token")
 	if err != nil {
-		log.Fatalln("Failed to store access token:", err)
+		fatalln("Failed to store access token:", err)
 	}
-	fmt.Println(result)
+	fmt.Fprintln(deps.Stdout, result)
 	return nil
 }
 
-func refreshToken(_ *cobra.Command, _ []string) error {
-	store := storage.NewStorage()
+func refreshToken(cmd *cobra.Command, _ []string) error {
+	store := deps.NewStorage("")
 	if err := store.Init(); err != nil {
-		log.Fatalln("Failed to init storage:", err)
+		fatalln("Failed to init storage:", err)
 	}
 	defer store.Close()
 	rt, err := store.Get("refresh_token")
 	if err != nil {
-		log.Fatalln("Failed to store access token:", err)
+		fatalln("Failed to store access token:", err)
 	}
 	// get base url
 	baseUrl, err := store.Get("base_url")
 	if err != nil {
-		log.Fatalln("Failed to get access token:", err)
+		fatalln("Failed to get access token:", err)
 	}
-	authenticationService := service.NewAuthentication(baseUrl)
-	result, err := authenticationService.GenerateAccessAndRefreshToken(rt)
+	authenticationService := newAuthenticationService(baseUrl)
+	result, err := authenticationService.GenerateAccessAndRefreshToken(cmd.Context(), rt)
 	if err != nil {
-		log.Fatalln(err.Error())
+		exitIfCancelled(cmd.Context())
+		fatalln(err.Error())
 	}
 	if result != nil {
 		if result.ResponseCode == "00" {
 			if err := store.Set("refresh_token", result.Refresh); err != nil {
-				log.Fatalln("Failed to store refresh token:", err)
+				fatalln("Failed to store refresh token:", err)
 			}
 			if err := store.Set("access_token", result.Access); err != nil {
-				log.Fatalln("Failed to store access token:", err)
+				fatalln("Failed to store access token:", err)
 			}
-			fmt.Println("Renewed Refresh token saved.")
-			fmt.Println("Renewed Access token saved.")
+			fmt.Fprintln(deps.Stdout, "Renewed Refresh token saved.")
+			fmt.Fprintln(deps.Stdout, "Renewed Access token saved.")
 		} else {
-			fmt.Println("Refresh Token failed.")
+			fmt.Fprintln(deps.Stdout, "Refresh Token failed.")
 		}
 	}
 	return nil
 }
 
-func checkRefreshToken(_ *cobra.Command, _ []string) error {
-	store := storage.NewStorage()
+func checkRefreshToken(cmd *cobra.Command, _ []string) error {
+	store := deps.NewStorage("")
 	if err := store.Init(); err != nil {
-		log.Fatalln("Failed to init storage:", err)
+		fatalln("Failed to init storage:", err)
 	}
 	defer store.Close()
 	rt, err := store.Get("refresh_token")
 	if err != nil {
-		log.Fatalln("Failed to store refresh token:", err)
+		fatalln("Failed to store refresh token:", err)
 	}
 	at, err := store.Get("access_token")
 	if err != nil {
-		log.Fatalln("Failed to store access token:", err)
+		fatalln("Failed to store access token:", err)
 	}
 	// get base url
 	baseUrl, err := store.Get("base_url")
 	if err != nil {
-		log.Fatalln("Failed to get access token:", err)
+		fatalln("Failed to get access token:", err)
 	}
-	authenticationService := service.NewAuthentication(baseUrl)
-	refreshTokenRemaining, refreshTokenExpiredAt, err := authenticationService.IsExpired(rt)
-	if err != nil {
-		if errors.Is(err, errors.New("token is expired")) {
-			fmt.Println("Refresh token expired")
-		}
-		if !errors.Is(err, errors.New("token is expired")) {
-			fmt.Println("Refresh token checking error")
-		}
+	authenticationService := newAuthenticationService(baseUrl)
+	leeway, _ := cmd.Flags().GetDuration("leeway")
+	minRemaining, _ := cmd.Flags().GetDuration("min-remaining")
+	refreshOk := reportSignature(cmd, authenticationService, store, "Refresh", rt)
+	accessOk := reportSignature(cmd, authenticationService, store, "Access", at)
+	refreshOk = reportExpiry(authenticationService, "Refresh", rt, leeway, minRemaining) && refreshOk
+	accessOk = reportExpiry(authenticationService, "Access", at, leeway, minRemaining) && accessOk
+	if !refreshOk || !accessOk {
+		deps.Exit(exitCodeCheckFailed)
 	}
-	if refreshTokenRemaining != nil && refreshTokenExpiredAt != nil {
-		fmt.Println("Refresh token remaining: " + *refreshTokenRemaining)
-		fmt.Println("Refresh token expired at: " + *refreshTokenExpiredAt)
+	return nil
+}
+
+// reportExpiry prints the token lifetime and reports whether it is still
+// usable for at least minRemaining.
+func reportExpiry(authenticationService service.Authentication, name, token string, leeway, minRemaining time.Duration) bool {
+	remaining, expiredAt, err := authenticationService.IsExpired(token, deps.Now(), leeway)
+	switch {
+	case errors.Is(err, service.ErrTokenExpired):
+		fmt.Fprintln(deps.Stdout, name+" token expired")
+	case errors.Is(err, service.ErrTokenNotValid):
+		fmt.Fprintln(deps.Stdout, name+" token not valid yet")
+	case err != nil:
+		fmt.Fprintln(deps.Stdout, name+" token checking error: "+err.Error())
+	}
+	if remaining != nil && expiredAt != nil {
+		fmt.Fprintln(deps.Stdout, name+" token remaining: "+*remaining)
+		fmt.Fprintln(deps.Stdout, name+" token expired at: "+*expiredAt)
 	}
-	accessTokenRemaining, accessTokenExpiredAt, err := authenticationService.IsExpired(at)
 	if err != nil {
-		if errors.Is(err, errors.New("token is expired")) {
-			fmt.Println("Access token expired")
+		return false
+	}
+	details, _ := service.InspectToken(token)
+	if minRemaining > 0 && details.ExpiresAt.Sub(deps.Now()) < minRemaining {
+		fmt.Fprintln(deps.Stdout, name+" token expires within "+minRemaining.String())
+		return false
+	}
+	return true
+}
+
+func inspectToken(cmd *cobra.Command, args []string) error {
+	target := "accesstoken"
+	if len(args) == 1 {
+		target = args[0]
+	}
+	raw := target
+	if target == "accesstoken" || target == "refreshtoken" {
+		store := deps.NewStorage("")
+		if err := store.Init(); err != nil {
+			fatalln("Failed to init storage:", err)
 		}
-		if !errors.Is(err, errors.New("token is expired")) {
-			fmt.Println("Access token checking error")
+		defer store.Close()
+		key := strings.Replace(target, "token", "_token", 1)
+		value, err := store.Get(key)
+		if err != nil {
+			fatalln("Failed to get token:", err)
 		}
+		if value == "" {
+			fatalln("No " + strings.Replace(key, "_", " ", 1) + " stored.")
+		}
+		raw = value
 	}
-	if accessTokenRemaining != nil && accessTokenExpiredAt != nil {
-		fmt.Println("Access token remaining: " + *accessTokenRemaining)
-		fmt.Println("Access token expired at: " + *accessTokenExpiredAt)
+	details, err := service.InspectToken(raw)
+	if err != nil {
+		fatalln(err.Error())
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(deps.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(details)
+	case "text":
+	default:
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+
+	fmt.Fprintln(deps.Stdout, "Header:")
+	printClaims(details.Header)
+	fmt.Fprintln(deps.Stdout, "Claims:")
+	printClaims(details.Claims)
+	fmt.Fprintln(deps.Stdout, "Times:")
+	printClaimTime("Issued at", details.IssuedAt)
+	printClaimTime("Not before", details.NotBefore)
+	printClaimTime("Expires at", details.ExpiresAt)
+	for _, name := range details.MissingClaims {
+		fmt.Fprintln(deps.Stdout, "Warning: missing expected claim "+name)
 	}
 	return nil
 }
 
+func printClaims(values map[string]interface{}) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, err := json.Marshal(values[name])
+		if err != nil {
+			value = []byte(fmt.Sprint(values[name]))
+		}
+		fmt.Fprintf(deps.Stdout, "  %s: %s\n", name, value)
+	}
+}
+
+func printClaimTime(label string, t *time.Time) {
+	if t == nil {
+		fmt.Fprintf(deps.Stdout, "  %s: -\n", label)
+		return
+	}
+	fmt.Fprintf(deps.Stdout, "  %s: %s (%s)\n", label, t.Local().Format(time.DateTime), relativeTime(*t))
+}
+
+func relativeTime(t time.Time) string {
+	d := t.Sub(deps.Now()).Round(time.Second)
+	if d < 0 {
+		return (-d).String() + " ago"
+	}
+	return "in " + d.String()
+}
+
+func reportSignature(cmd *cobra.Command, authenticationService service.Authentication, store storage.Storage, name, token string) bool {
+	_, err := authenticationService.VerifyToken(cmd.Context(), token, store, verifyOptions)
+	switch {
+	case err == nil:
+		fmt.Fprintln(deps.Stdout, name+" token signature valid")
+		return true
+	case errors.Is(err, client.ErrKeysUnavailable), errors.Is(err, client.ErrNoCachedKeys):
+		exitIfCancelled(cmd.Context())
+		// keys being unavailable says nothing about the token itself
+		fmt.Fprintln(deps.Stdout, name+" token signature could not be verified: "+err.Error())
+		return true
+	case errors.Is(err, client.ErrIssuerMismatch), errors.Is(err, client.ErrAudienceMismatch):
+		fmt.Fprintln(deps.Stdout, name+" token signature valid but "+err.Error())
+	default:
+		reason := strings.TrimPrefix(err.Error(), client.ErrInvalidSignature.Error()+": ")
+		fmt.Fprintln(deps.Stdout, name+" token signature invalid: "+reason)
+	}
+	return false
+}
+
 func InitializeTokenCmd(tokenCmd *cobra.Command) {
 	tokenSetCmd := &cobra.Command{
 		Use:   "set",
@@ -194,10 +312,23 @@ func InitializeTokenCmd(tokenCmd *cobra.Command) {
 		Long:  `Refresh access token and refresh token`,
 		RunE:  refreshToken,
 	})
-	tokenCmd.AddCommand(&cobra.Command{
+	inspectCmd := &cobra.Command{
+		Use:       "inspect [accesstoken|refreshtoken|<raw>]",
+		Short:     "Decode and print token header, claims and expiry",
+		Long:      `Decode and print token header, claims and expiry without verifying the signature`,
+		Args:      cobra.MaximumNArgs(1),
+		ValidArgs: []string{"accesstoken", "refreshtoken"},
+		RunE:      inspectToken,
+	}
+	inspectCmd.Flags().StringP("output", "o", "text", "Output format, text or json")
+	tokenCmd.AddCommand(inspectCmd)
+	checkCmd := &cobra.Command{
 		Use:   "check",
 		Short: "Refresh access token and Refresh token expired check",
-		Long:  `Refresh access token and Refresh token expired check`,
+		Long:  `Refresh access token and Refresh token expired check, exits non-zero when either token is invalid, expired or close to expiry`,
 		RunE:  checkRefreshToken,
-	})
+	}
+	checkCmd.Flags().Duration("leeway", 0, "Clock skew tolerated when checking expiry, e.g. 30s")
+	checkCmd.Flags().Duration("min-remaining", 0, "Fail when a token expires within this duration, e.g. 10m")
+	tokenCmd.AddCommand(checkCmd)
 }
@@ -0,0 +1,154 @@
+package synexis
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/synxms/synexis/pkg/client"
+	"github.com/synxms/synexis/src/service"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// watchMaxErrors is how many status checks in a row may fail before watch
+// gives up, single failures are common over hours of training.
+const watchMaxErrors = 5
+
+func watchRequest(cmd *cobra.Command, args []string) error {
+	hooks := readHookFlags(cmd)
+	authenticationService, _, closeStore := openAuthenticatedService(nil)
+	defer closeStore()
+	return watchAndRunHooks(cmd, authenticationService, args[0], hooks)
+}
+
+func submitRequest(cmd *cobra.Command, _ []string) error {
+	hooks := readHookFlags(cmd)
+	sensoryIdPath, _ := cmd.Flags().GetString("sensory")
+	datasetIdPath, _ := cmd.Flags().GetString("dataset")
+	if sensoryIdPath == "" || datasetIdPath == "" {
+		fatalln("Use '-s' and '-d' to specify the sensory and dataset")
+	}
+	sensoryId, err := readIDArgument(sensoryIdPath)
+	if err != nil {
+		fatalln("Failed to read sensory ID file", err)
+	}
+	datasetId, err := readIDArgument(datasetIdPath)
+	if err != nil {
+		fatalln("Failed to read dataset ID file", err)
+	}
+	authenticationService, _, closeStore := openAuthenticatedService(nil)
+	defer closeStore()
+	result, err := authenticationService.CreateRequest(cmd.Context(), sensoryId, datasetId)
+	if err != nil {
+		exitIfCancelled(cmd.Context())
+		fatalln(err.Error())
+	}
+	if result.ResponseCode != "00" {
+		fatalln("Create request failed, reason :", result.ResponseMessage)
+	}
+	recordJob(jobRecord{
+		RequestID: result.Data.RequestID,
+		DatasetID: datasetId,
+		SensoryID: sensoryId,
+		CreatedAt: deps.Now(),
+	})
+	fmt.Fprintln(deps.Stdout, "Request ID: ", result.Data.RequestID)
+	return watchAndRunHooks(cmd, authenticationService, result.Data.RequestID, hooks)
+}
+
+// watchAndRunHooks follows the request until it finishes and runs the
+// completion hooks. The command fails when training or a hook did.
+func watchAndRunHooks(cmd *cobra.Command, authenticationService service.Authentication, requestID string, hooks completionHooks) error {
+	interval, _ := cmd.Flags().GetDuration("interval")
+	request := waitForRequest(cmd.Context(), authenticationService, requestID, max(interval, time.Second))
+	printRequestOutcome(request)
+	hooksOk := runCompletionHooks(cmd.Context(), authenticationService, request, hooks)
+	exitIfCancelled(cmd.Context())
+	if request.Status != client.TrainingStatusSucceeded {
+		fatalln("Training", request.Status+":", request.Message)
+	}
+	if !hooksOk {
+		fatalln("Training succeeded but a completion hook failed")
+	}
+	return nil
+}
+
+// waitForRequest polls until the request is finished, printing every change
+// of status.
+func waitForRequest(ctx context.Context, authenticationService service.Authentication, requestID string, interval time.Duration) client.TrainingRequest {
+	var status string
+	failures := 0
+	for {
+		result, err := authenticationService.RequestStatus(ctx, requestID)
+		exitIfCancelled(ctx)
+		switch {
+		case err != nil:
+			failures++
+			if failures >= watchMaxErrors {
+				fatalln("Failed to get request status:", err)
+			}
+			fmt.Fprintln(deps.Stderr, "Warning: status check failed, trying again:", err)
+		case result.ResponseCode != "00":
+			fatalln("Request status failed, reason :", result.ResponseMessage)
+		default:
+			failures = 0
+			if result.Data.Status != status {
+				status = result.Data.Status
+				fmt.Fprintf(deps.Stdout, "%s  %s  %s\n", deps.Now().Format(time.DateTime), requestID, status)
+			}
+			if result.Data.Finished() {
+				return result.Data
+			}
+		}
+		select {
+		case <-ctx.Done():
+			exitIfCancelled(ctx)
+		case <-time.After(interval):
+		}
+	}
+}
+
+func printRequestOutcome(request client.TrainingRequest) {
+	if request.Message != "" {
+		fmt.Fprintln(deps.Stdout, "Message:    ", request.Message)
+	}
+	if len(request.Metrics) == 0 {
+		return
+	}
+	names := make([]string, 0, len(request.Metrics))
+	for name := range request.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	metrics := make([]string, 0, len(names))
+	for _, name := range names {
+		metrics = append(metrics, fmt.Sprintf("%s=%g", name, request.Metrics[name]))
+	}
+	fmt.Fprintln(deps.Stdout, "Metrics:    ", strings.Join(metrics, " "))
+}
+
+func readHookFlags(cmd *cobra.Command) completionHooks {
+	var hooks completionHooks
+	hooks.OnSuccess, _ = cmd.Flags().GetString("on-success")
+	hooks.OnFailure, _ = cmd.Flags().GetString("on-failure")
+	hooks.OnComplete, _ = cmd.Flags().GetString("on-complete")
+	hooks.Webhook, _ = cmd.Flags().GetString("webhook")
+	if hooks.Webhook == "" {
+		hooks.Webhook = os.Getenv(envWebhookURL)
+	}
+	return hooks
+}
+
+func addWatchFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("interval", 10*time.Second, "How often the status is checked")
+	cmd.Flags().String("on-success", "", "Shell command to run when training succeeds")
+	cmd.Flags().String("on-failure", "", "Shell command to run when training fails or is cancelled")
+	cmd.Flags().String("on-complete", "", "Shell command to run when training finishes, after --on-success or --on-failure")
+	cmd.Flags().String("webhook", "", "URL to POST a JSON summary to when training finishes, defaults to $"+envWebhookURL)
+}
+
+const watchHooksHelp = `
+
+Hooks run through the shell with SYNEXIS_REQUEST_ID, SYNEXIS_STATUS, SYNEXIS_MESSAGE, SYNEXIS_DATASET_ID, SYNEXIS_SENSORY_ID, SYNEXIS_METRICS (JSON), SYNEXIS_ARTIFACT_URLS (space separated), SYNEXIS_BASE_URL and SYNEXIS_HOOK set. Their output is kept in the local job registry. The command exits with 1 when training does not succeed or a hook fails.`
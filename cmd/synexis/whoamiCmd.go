@@ -0,0 +1,153 @@
+package synexis
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/synxms/synexis/src/service"
+	"net/url"
+	"time"
+)
+
+type accountStatus struct {
+	UserID     string     `json:"userId,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	Email      string     `json:"email,omitempty"`
+	CompanyID  string     `json:"companyId,omitempty"`
+	Profile    string     `json:"profile"`
+	BaseURL    string     `json:"baseUrl"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	Verified   bool       `json:"verifiedWithServer"`
+	Mismatches []string   `json:"mismatches,omitempty"`
+}
+
+func whoami(cmd *cobra.Command, _ []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+	authenticationService, _, closeStore := openAuthenticatedService(nil)
+	defer closeStore()
+	token, err := authenticationService.AccessToken(cmd.Context())
+	if err != nil {
+		exitIfCancelled(cmd.Context())
+		fatalln("Failed to get access token:", err)
+	}
+
+	status := accountStatus{
+		Profile: deps.NewStorage("").Profile(),
+		BaseURL: authenticationService.BaseURL(),
+	}
+	details, err := service.InspectToken(token.AccessToken)
+	if err == nil {
+		status.UserID, _ = details.Claims["sub"].(string)
+		status.Name, _ = details.Claims["name"].(string)
+		status.Email, _ = details.Claims["email"].(string)
+		status.CompanyID, _ = details.Claims["companyId"].(string)
+		status.ExpiresAt = details.ExpiresAt
+		status.Mismatches = append(status.Mismatches, baseURLMismatches(details, status.BaseURL)...)
+	}
+
+	account, err := authenticationService.Account(cmd.Context())
+	if err != nil {
+		exitIfCancelled(cmd.Context())
+		fmt.Fprintln(deps.Stderr, "Warning: server not reachable, showing local token claims only:", err)
+	} else if account.ResponseCode == "00" {
+		status.Verified = true
+		status.Mismatches = append(status.Mismatches, mergeAccount("userId", &status.UserID, account.Data.UserID)...)
+		status.Mismatches = append(status.Mismatches, mergeAccount("name", &status.Name, account.Data.Name)...)
+		status.Mismatches = append(status.Mismatches, mergeAccount("email", &status.Email, account.Data.Email)...)
+		status.Mismatches = append(status.Mismatches, mergeAccount("companyId", &status.CompanyID, account.Data.CompanyID)...)
+	} else {
+		fmt.Fprintln(deps.Stderr, "Warning: server rejected the account lookup:", account.ResponseMessage)
+	}
+
+	if output == "json" {
+		encoder := json.NewEncoder(deps.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(status)
+	}
+	printField("User", status.UserID)
+	printField("Name", status.Name)
+	printField("Email", status.Email)
+	printField("Company ID", status.CompanyID)
+	printField("Profile", status.Profile)
+	printField("Base URL", status.BaseURL)
+	if status.ExpiresAt != nil {
+		printField("Token expires", status.ExpiresAt.Local().Format(time.DateTime)+" ("+relativeTime(*status.ExpiresAt)+")")
+	}
+	if status.Verified {
+		printField("Server", "confirmed")
+	} else {
+		printField("Server", "not confirmed")
+	}
+	for _, mismatch := range status.Mismatches {
+		fmt.Fprintln(deps.Stdout, "Warning: "+mismatch)
+	}
+	return nil
+}
+
+// mergeAccount fills a field the token did not carry from the server's answer
+// and reports when both are present but disagree.
+func mergeAccount(name string, local *string, remote string) []string {
+	switch {
+	case remote == "":
+		return nil
+	case *local == "":
+		*local = remote
+		return nil
+	case *local != remote:
+		return []string{fmt.Sprintf("token %s %q differs from server %q", name, *local, remote)}
+	}
+	return nil
+}
+
+// baseURLMismatches flags tokens whose issuer or audience names a host other
+// than the configured base url.
+func baseURLMismatches(details *service.TokenDetails, baseUrl string) []string {
+	base, err := url.Parse(baseUrl)
+	if err != nil {
+		return nil
+	}
+	var mismatches []string
+	for _, claim := range []struct{ name, label string }{{"iss", "issuer"}, {"aud", "audience"}} {
+		values := claimStrings(details.Claims[claim.name])
+		matched, comparable := false, false
+		for _, value := range values {
+			u, err := url.Parse(value)
+			if err != nil || u.Host == "" {
+				continue
+			}
+			comparable = true
+			matched = matched || u.Host == base.Host
+		}
+		if comparable && !matched {
+			mismatches = append(mismatches, fmt.Sprintf("token %s %v was issued for a different base url than %s", claim.label, values, baseUrl))
+		}
+	}
+	return mismatches
+}
+
+// claimStrings reads a claim that holds a string or a list of strings.
+func claimStrings(claim interface{}) []string {
+	switch claim := claim.(type) {
+	case string:
+		return []string{claim}
+	case []interface{}:
+		var values []string
+		for _, value := range claim {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func printField(label, value string) {
+	if value == "" {
+		value = "-"
+	}
+	fmt.Fprintf(deps.Stdout, "%-14s %s\n", label+":", value)
+}
@@ -0,0 +1,33 @@
+package synexis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/synxms/synexis/src/service"
+)
+
+func TestBaseURLMismatches(t *testing.T) {
+	const baseUrl = "https://api.synexis.test"
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		want   []string
+	}{
+		{"matching", map[string]interface{}{"iss": "https://api.synexis.test/auth", "aud": "https://api.synexis.test"}, nil},
+		{"one of several audiences", map[string]interface{}{"aud": []interface{}{"https://other.test", "https://api.synexis.test"}}, nil},
+		{"not urls", map[string]interface{}{"iss": "synexis", "aud": []interface{}{"cli"}}, nil},
+		{"both differ", map[string]interface{}{"aud": "https://other.test", "iss": "https://other.test"}, []string{
+			"token issuer [https://other.test] was issued for a different base url than " + baseUrl,
+			"token audience [https://other.test] was issued for a different base url than " + baseUrl,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := baseURLMismatches(&service.TokenDetails{Claims: tt.claims}, baseUrl)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,11 +5,9 @@ go 1.24.2
 require (
 	github.com/golang-jwt/jwt/v4 v4.5.2
 	github.com/spf13/cobra v1.9.1
+	github.com/spf13/pflag v1.0.6
 	go.etcd.io/bbolt v1.4.0
+	golang.org/x/sys v0.29.0
 )
 
-require (
-	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.6 // indirect
-	golang.org/x/sys v0.29.0 // indirect
-)
+require github.com/inconshreveable/mousetrap v1.1.0 // indirect
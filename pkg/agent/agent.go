@@ -0,0 +1,277 @@
+// Package agent implements the credential helper daemon that keeps access
+// tokens fresh in memory and hands them out over a Unix socket to processes
+// of the same user.
+package agent
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/synxms/synexis/pkg/client"
+)
+
+const (
+	OpToken  = "token"
+	OpStatus = "status"
+	OpStop   = "stop"
+
+	refreshInterval = 15 * time.Second
+	requestTimeout  = 5 * time.Second
+)
+
+var (
+	ErrNotRunning = errors.New("agent is not running")
+	// ErrUntrusted is returned when the socket, its directory or the process
+	// answering on it does not belong to the current user.
+	ErrUntrusted = errors.New("agent socket is not trusted")
+)
+
+type (
+	Request struct {
+		Op string `json:"op"`
+	}
+	Response struct {
+		AccessToken string    `json:"accessToken,omitempty"`
+		TokenType   string    `json:"tokenType,omitempty"`
+		Expiry      time.Time `json:"expiry,omitempty"`
+		BaseURL     string    `json:"baseUrl,omitempty"`
+		Profile     string    `json:"profile,omitempty"`
+		PID         int       `json:"pid,omitempty"`
+		StartedAt   time.Time `json:"startedAt,omitempty"`
+		Error       string    `json:"error,omitempty"`
+	}
+	Server struct {
+		mu        sync.Mutex
+		source    client.TokenSource
+		token     *client.Token
+		baseURL   string
+		profile   string
+		startedAt time.Time
+		stop      context.CancelFunc
+		logf      func(format string, args ...interface{})
+	}
+	agentTokenSource struct {
+		socketPath string
+	}
+)
+
+// SocketPath is the per-user socket location of a profile in the store at
+// storePath, inside a directory only the user can enter. Profiles of the
+// same name in different stores get agents of their own.
+func SocketPath(storePath, profile string) string {
+	base := os.Getenv("XDG_RUNTIME_DIR")
+	if base == "" {
+		base = filepath.Join(os.TempDir(), fmt.Sprintf("synexis-%d", os.Getuid()))
+	} else {
+		base = filepath.Join(base, "synexis")
+	}
+	sum := sha256.Sum256([]byte(storePath))
+	return filepath.Join(base, "agent-"+profile+"-"+hex.EncodeToString(sum[:4])+".sock")
+}
+
+func NewServer(source client.TokenSource, baseURL, profile string, logf func(format string, args ...interface{})) *Server {
+	if logf == nil {
+		logf = func(string, ...interface{}) {}
+	}
+	return &Server{source: source, baseURL: baseURL, profile: profile, logf: logf}
+}
+
+// ListenAndServe serves on socketPath until ctx is done or a stop request
+// arrives. A stale socket left by a crashed agent is replaced.
+func (s *Server) ListenAndServe(ctx context.Context, socketPath string) error {
+	if !Supported {
+		return errors.New("the credential agent is not supported on this platform")
+	}
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0700); err != nil {
+		return err
+	}
+	if err := os.Chmod(filepath.Dir(socketPath), 0700); err != nil {
+		return err
+	}
+	if err := checkDirectory(filepath.Dir(socketPath)); err != nil {
+		return err
+	}
+	if _, err := Query(ctx, socketPath, OpStatus); err == nil {
+		return errors.New("agent is already running")
+	}
+	_ = os.Remove(socketPath)
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socketPath)
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		_ = listener.Close()
+		return err
+	}
+
+	ctx, s.stop = context.WithCancel(ctx)
+	s.startedAt = time.Now()
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+	go s.keepFresh(ctx)
+	s.logf("agent listening on %s", socketPath)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go s.handle(ctx, conn.(*net.UnixConn))
+	}
+}
+
+func (s *Server) keepFresh(ctx context.Context) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.currentToken(ctx); err != nil && ctx.Err() == nil {
+			s.logf("token refresh failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) currentToken(ctx context.Context) (*client.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token.Valid() {
+		return s.token, nil
+	}
+	token, err := s.source.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.token = token
+	return token, nil
+}
+
+func (s *Server) handle(ctx context.Context, conn *net.UnixConn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+	encoder := json.NewEncoder(conn)
+	if err := checkPeer(conn); err != nil {
+		s.logf("rejected connection: %v", err)
+		_ = encoder.Encode(Response{Error: "permission denied"})
+		return
+	}
+	var req Request
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		_ = encoder.Encode(Response{Error: "malformed request"})
+		return
+	}
+	resp := Response{BaseURL: s.baseURL, Profile: s.profile, PID: os.Getpid(), StartedAt: s.startedAt}
+	switch req.Op {
+	case OpToken:
+		token, err := s.currentToken(ctx)
+		if err != nil {
+			resp.Error = err.Error()
+			break
+		}
+		resp.AccessToken, resp.TokenType, resp.Expiry = token.AccessToken, token.Type, token.Expiry
+	case OpStatus:
+		s.mu.Lock()
+		if s.token != nil {
+			resp.Expiry = s.token.Expiry
+		}
+		s.mu.Unlock()
+	case OpStop:
+		s.logf("stop requested")
+		defer s.stop()
+	default:
+		resp.Error = fmt.Sprintf("unknown operation %q", req.Op)
+	}
+	_ = encoder.Encode(resp)
+}
+
+// Query sends a single request to the agent listening on socketPath. The
+// socket, its directory and the answering process must all belong to the
+// current user, anyone else could hand out tokens of their choosing.
+func Query(ctx context.Context, socketPath, op string) (*Response, error) {
+	if err := checkSocket(socketPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotRunning
+		}
+		return nil, err
+	}
+	dialer := net.Dialer{Timeout: time.Second}
+	conn, err := dialer.DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		return nil, ErrNotRunning
+	}
+	defer conn.Close()
+	if err := checkPeer(conn.(*net.UnixConn)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUntrusted, err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+	if err := json.NewEncoder(conn).Encode(Request{Op: op}); err != nil {
+		return nil, err
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read agent response: %w", err)
+	}
+	if resp.Error != "" {
+		return &resp, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// checkDirectory requires dir to be a real directory of the current user
+// that nobody else can enter. Under /tmp another user may have created it.
+func checkDirectory(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() || info.Mode().Perm() != 0700 || !ownedByUser(info) {
+		return fmt.Errorf("%w: %s must be a directory owned by uid %d with mode 0700", ErrUntrusted, dir, os.Getuid())
+	}
+	return nil
+}
+
+func checkSocket(socketPath string) error {
+	if err := checkDirectory(filepath.Dir(socketPath)); err != nil {
+		return err
+	}
+	info, err := os.Lstat(socketPath)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type() != os.ModeSocket || !ownedByUser(info) {
+		return fmt.Errorf("%w: %s must be a socket owned by uid %d", ErrUntrusted, socketPath, os.Getuid())
+	}
+	return nil
+}
+
+// TokenSource fetches tokens from the agent listening on socketPath.
+func TokenSource(socketPath string) client.TokenSource {
+	return &agentTokenSource{socketPath: socketPath}
+}
+
+func (s *agentTokenSource) Token(ctx context.Context) (*client.Token, error) {
+	resp, err := Query(ctx, s.socketPath, OpToken)
+	if err != nil {
+		return nil, err
+	}
+	return &client.Token{AccessToken: resp.AccessToken, Type: resp.TokenType, Expiry: resp.Expiry}, nil
+}
@@ -0,0 +1,238 @@
+//go:build linux || darwin || freebsd
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/synxms/synexis/pkg/client"
+)
+
+// countingSource hands out the token it holds and counts how often it was
+// asked, like a stored source that refreshes on every call.
+type countingSource struct {
+	mu    sync.Mutex
+	token *client.Token
+	err   error
+	calls int
+}
+
+func (s *countingSource) Token(context.Context) (*client.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	token := *s.token
+	return &token, nil
+}
+
+func (s *countingSource) set(token *client.Token, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token, s.err = token, err
+}
+
+func (s *countingSource) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// startServer serves source on a fresh socket and returns its path along
+// with a func that waits for ListenAndServe to return.
+func startServer(t *testing.T, source client.TokenSource) (string, func(time.Duration) (error, bool)) {
+	t.Helper()
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	socketPath := SocketPath("/tmp/synexis.db", "test")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	var serveErr error
+	go func() {
+		serveErr = NewServer(source, "https://synexis.example", "test", t.Logf).ListenAndServe(ctx, socketPath)
+		close(done)
+	}()
+	wait := func(timeout time.Duration) (error, bool) {
+		select {
+		case <-done:
+			return serveErr, true
+		case <-time.After(timeout):
+			return nil, false
+		}
+	}
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	deadline := time.After(5 * time.Second)
+	for {
+		if _, err := Query(context.Background(), socketPath, OpStatus); err == nil {
+			return socketPath, wait
+		}
+		select {
+		case <-done:
+			t.Fatalf("agent stopped before serving: %v", serveErr)
+		case <-deadline:
+			t.Fatal("agent did not come up")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestSocketPath(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	path := SocketPath("/home/user/synexis.db", "default")
+	if filepath.Dir(path) != filepath.Join(runtimeDir, "synexis") {
+		t.Errorf("socket %s is not in the runtime directory %s", path, runtimeDir)
+	}
+	if path != SocketPath("/home/user/synexis.db", "default") {
+		t.Error("socket path is not stable")
+	}
+	for _, other := range []string{SocketPath("/srv/synexis.db", "default"), SocketPath("/home/user/synexis.db", "staging")} {
+		if other == path {
+			t.Errorf("another store or profile shares socket %s", path)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	source := &countingSource{token: &client.Token{AccessToken: "access", Type: client.TokenTypeBearer, Expiry: expiry}}
+	socketPath, wait := startServer(t, source)
+
+	status, err := Query(context.Background(), socketPath, OpStatus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Profile != "test" || status.BaseURL != "https://synexis.example" || status.PID != os.Getpid() || status.StartedAt.IsZero() {
+		t.Errorf("status = %+v", status)
+	}
+
+	token, err := TokenSource(socketPath).Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "access" || token.Type != client.TokenTypeBearer || !token.Expiry.Equal(expiry) {
+		t.Errorf("token = %+v", token)
+	}
+
+	if _, err := Query(context.Background(), socketPath, "dump"); err == nil || !strings.Contains(err.Error(), `unknown operation "dump"`) {
+		t.Errorf("unknown operation: got %v", err)
+	}
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = conn.Write([]byte("not json\n"))
+	reply := make([]byte, 128)
+	n, _ := conn.Read(reply)
+	conn.Close()
+	if !strings.Contains(string(reply[:n]), "malformed request") {
+		t.Errorf("malformed request answered %q", reply[:n])
+	}
+
+	if _, err := Query(context.Background(), socketPath, OpStop); err != nil {
+		t.Fatal(err)
+	}
+	if err, stopped := wait(5 * time.Second); !stopped {
+		t.Fatal("agent kept running after stop")
+	} else if err != nil {
+		t.Errorf("agent stopped with %v", err)
+	}
+	if _, err := Query(context.Background(), socketPath, OpStatus); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("after stop: got %v, want %v", err, ErrNotRunning)
+	}
+}
+
+func TestQueryUntrusted(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Query(context.Background(), filepath.Join(dir, "agent.sock"), OpStatus); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("missing socket: got %v, want %v", err, ErrNotRunning)
+	}
+
+	file := filepath.Join(dir, "file.sock")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Query(context.Background(), file, OpStatus); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("regular file: got %v, want %v", err, ErrUntrusted)
+	}
+
+	socketPath, _ := startServer(t, &countingSource{token: &client.Token{AccessToken: "access"}})
+	if err := os.Chmod(filepath.Dir(socketPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Query(context.Background(), socketPath, OpToken); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("directory others can enter: got %v, want %v", err, ErrUntrusted)
+	}
+	_ = os.Chmod(filepath.Dir(socketPath), 0700)
+}
+
+func TestRefresh(t *testing.T) {
+	source := &countingSource{token: &client.Token{AccessToken: "first", Expiry: time.Now().Add(time.Hour)}}
+	server := NewServer(source, "https://synexis.example", "test", nil)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if token, err := server.currentToken(ctx); err != nil || token.AccessToken != "first" {
+			t.Fatalf("got %v, %v", token, err)
+		}
+	}
+	if source.count() != 1 {
+		t.Errorf("valid token fetched %d times, want once", source.count())
+	}
+
+	server.token.Expiry = time.Now().Add(-time.Minute)
+	source.set(&client.Token{AccessToken: "second", Expiry: time.Now().Add(time.Hour)}, nil)
+	if token, err := server.currentToken(ctx); err != nil || token.AccessToken != "second" {
+		t.Fatalf("expired token: got %v, %v, want the refreshed one", token, err)
+	}
+
+	server.token.Expiry = time.Now().Add(-time.Minute)
+	source.set(nil, errors.New("refresh token revoked"))
+	if _, err := server.currentToken(ctx); err == nil {
+		t.Fatal("refresh failure was not reported")
+	}
+	if server.token.AccessToken != "second" {
+		t.Errorf("failed refresh replaced the token with %v", server.token)
+	}
+}
+
+func TestKeepFresh(t *testing.T) {
+	source := &countingSource{token: &client.Token{AccessToken: "access", Expiry: time.Now().Add(time.Hour)}}
+	server := NewServer(source, "https://synexis.example", "test", nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		server.keepFresh(ctx)
+		close(done)
+	}()
+	deadline := time.After(5 * time.Second)
+	for source.count() == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("agent did not fetch a token on start")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("keepFresh kept running after cancel")
+	}
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const envPeerDial = "SYNEXIS_AGENT_TEST_DIAL"
+
+// TestMain doubles as the client dialing from another user, see
+// TestCheckPeerOtherUser.
+func TestMain(m *testing.M) {
+	if socketPath := os.Getenv(envPeerDial); socketPath != "" {
+		conn, err := net.Dial("unix", socketPath)
+		if err != nil {
+			os.Exit(1)
+		}
+		_, _ = io.Copy(io.Discard, conn)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// peerListener listens in a directory every user can reach, so a client
+// running as another user can connect.
+func peerListener(t *testing.T) (string, *net.UnixListener) {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "synexis-agent-peer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if err := os.Chmod(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	socketPath := filepath.Join(dir, "peer.sock")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	if err := os.Chmod(socketPath, 0777); err != nil {
+		t.Fatal(err)
+	}
+	return socketPath, listener
+}
+
+func TestCheckPeer(t *testing.T) {
+	socketPath, listener := peerListener(t)
+	client, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	conn, err := listener.AcceptUnix()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := checkPeer(conn); err != nil {
+		t.Errorf("peer of the same user rejected: %v", err)
+	}
+	if err := checkPeer(client.(*net.UnixConn)); err != nil {
+		t.Errorf("server of the same user rejected: %v", err)
+	}
+}
+
+func TestCheckPeerOtherUser(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("running a client as another user needs root")
+	}
+	socketPath, listener := peerListener(t)
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary, err := os.ReadFile(executable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the test binary may sit in a directory the other user cannot enter
+	helper := filepath.Join(filepath.Dir(socketPath), "agent.test")
+	if err := os.WriteFile(helper, binary, 0755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(helper, "-test.run=^$")
+	cmd.Env = append(os.Environ(), envPeerDial+"="+socketPath)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Credential: &syscall.Credential{Uid: 65534, Gid: 65534}}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot run a client as another user: %v", err)
+	}
+	defer cmd.Wait()
+	_ = listener.SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := listener.AcceptUnix()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := checkPeer(conn); err == nil || !strings.Contains(err.Error(), "peer uid 65534") {
+		t.Errorf("peer of uid 65534: got %v, want it rejected", err)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux && !darwin && !freebsd
+
+package agent
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// Supported is false where peer credentials cannot be checked, the agent
+// refuses to serve tokens there.
+const Supported = false
+
+func checkPeer(_ *net.UnixConn) error {
+	return errors.New("peer credential checks are not supported on this platform")
+}
+
+func ownedByUser(_ os.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,141 @@
+// Package archive packs a directory into a tar.gz or zip stream, so that
+// multi-file datasets can be uploaded without archiving them by hand. Files
+// are written in sorted order and followed by a manifest of their SHA-256
+// hashes, so the same directory always yields the same listing.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	TarGz Format = "tar.gz"
+	Zip   Format = "zip"
+
+	// ManifestName is the last entry of every archive, in the format of
+	// sha256sum.
+	ManifestName = "MANIFEST.sha256"
+)
+
+func ParseFormat(value string) (Format, error) {
+	switch Format(strings.TrimPrefix(strings.ToLower(value), ".")) {
+	case TarGz, "tgz":
+		return TarGz, nil
+	case Zip:
+		return Zip, nil
+	}
+	return "", fmt.Errorf("unknown archive format %q, use tar.gz or zip", value)
+}
+
+// Name is the file name of the archive of dir.
+func (f Format) Name(dir string) string {
+	return filepath.Base(filepath.Clean(dir)) + "." + string(f)
+}
+
+type entryWriter interface {
+	create(file File) (io.Writer, error)
+	Close() error
+}
+
+// Write packs files of dir, as returned by Files, into w.
+func Write(w io.Writer, dir string, files []File, format Format) error {
+	var archive entryWriter
+	switch format {
+	case TarGz:
+		archive = newTarWriter(w)
+	case Zip:
+		archive = &zipWriter{zip.NewWriter(w)}
+	default:
+		return fmt.Errorf("unknown archive format %q", format)
+	}
+	var manifest strings.Builder
+	var latest time.Time
+	for _, file := range files {
+		sum, err := writeEntry(archive, dir, file)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&manifest, "%s  %s\n", sum, file.Path)
+		if file.ModTime.After(latest) {
+			latest = file.ModTime
+		}
+	}
+	entry, err := archive.create(File{Path: ManifestName, Size: int64(manifest.Len()), ModTime: latest})
+	if err == nil {
+		_, err = io.WriteString(entry, manifest.String())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return archive.Close()
+}
+
+func writeEntry(archive entryWriter, dir string, file File) (string, error) {
+	source, err := os.Open(filepath.Join(dir, filepath.FromSlash(file.Path)))
+	if err != nil {
+		return "", err
+	}
+	defer source.Close()
+	entry, err := archive.create(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to add %s: %w", file.Path, err)
+	}
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(entry, hash), io.LimitReader(source, file.Size))
+	if err != nil {
+		return "", fmt.Errorf("failed to add %s: %w", file.Path, err)
+	}
+	if written != file.Size {
+		return "", fmt.Errorf("%s changed while it was packed", file.Path)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+type tarWriter struct {
+	gzip *gzip.Writer
+	tar  *tar.Writer
+}
+
+func newTarWriter(w io.Writer) *tarWriter {
+	compressed := gzip.NewWriter(w)
+	return &tarWriter{gzip: compressed, tar: tar.NewWriter(compressed)}
+}
+
+func (t *tarWriter) create(file File) (io.Writer, error) {
+	err := t.tar.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     file.Path,
+		Size:     file.Size,
+		Mode:     0644,
+		ModTime:  file.ModTime.UTC().Truncate(time.Second),
+		Format:   tar.FormatPAX,
+	})
+	return t.tar, err
+}
+
+func (t *tarWriter) Close() error {
+	if err := t.tar.Close(); err != nil {
+		return err
+	}
+	return t.gzip.Close()
+}
+
+type zipWriter struct{ *zip.Writer }
+
+func (z *zipWriter) create(file File) (io.Writer, error) {
+	header := &zip.FileHeader{Name: file.Path, Method: zip.Deflate, Modified: file.ModTime.UTC().Truncate(time.Second)}
+	header.SetMode(0644)
+	return z.CreateHeader(header)
+}
@@ -0,0 +1,163 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func pack(t *testing.T, dir string, format Format) []byte {
+	t.Helper()
+	files, err := Files(dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, dir, files, format); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// entries reads an archive back into its entry names and contents, in the
+// order they were written.
+func entries(t *testing.T, packed []byte, format Format) ([]string, map[string]string) {
+	t.Helper()
+	var names []string
+	contents := map[string]string{}
+	switch format {
+	case TarGz:
+		compressed, err := gzip.NewReader(bytes.NewReader(packed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		archive := tar.NewReader(compressed)
+		for {
+			header, err := archive.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, _ := io.ReadAll(archive)
+			names = append(names, header.Name)
+			contents[header.Name] = string(content)
+		}
+	case Zip:
+		archive, err := zip.NewReader(bytes.NewReader(packed), int64(len(packed)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range archive.File {
+			entry, err := file.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, _ := io.ReadAll(entry)
+			entry.Close()
+			names = append(names, file.Name)
+			contents[file.Name] = string(content)
+		}
+	}
+	return names, contents
+}
+
+func TestWriteDeterministic(t *testing.T) {
+	paths := []string{"train.csv", "b/labels.json", "a/images/0001.png", "a/images/0002.png"}
+	stamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, format := range []Format{TarGz, Zip} {
+		t.Run(string(format), func(t *testing.T) {
+			dir := writeTree(t, paths...)
+			// the same tree created in another order
+			reversed := make([]string, len(paths))
+			for i, path := range paths {
+				reversed[len(paths)-1-i] = path
+			}
+			copied := writeTree(t, reversed...)
+			for _, root := range []string{dir, copied} {
+				for _, path := range paths {
+					if err := os.Chtimes(filepath.Join(root, filepath.FromSlash(path)), stamp, stamp); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			first := pack(t, dir, format)
+			if second := pack(t, dir, format); !bytes.Equal(first, second) {
+				t.Error("packing the same directory twice gave different archives")
+			}
+			if again := pack(t, copied, format); !bytes.Equal(first, again) {
+				t.Error("packing a copy of the directory gave a different archive")
+			}
+		})
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	for _, format := range []Format{TarGz, Zip} {
+		t.Run(string(format), func(t *testing.T) {
+			dir := writeTree(t, "train.csv", "images/a.png", "images/b.png")
+			names, contents := entries(t, pack(t, dir, format), format)
+			if want := []string{"images/a.png", "images/b.png", "train.csv", ManifestName}; strings.Join(names, ",") != strings.Join(want, ",") {
+				t.Fatalf("archive holds %v, want %v", names, want)
+			}
+			var want strings.Builder
+			for _, name := range names[:3] {
+				if contents[name] != name {
+					t.Errorf("%s holds %q", name, contents[name])
+				}
+				sum := sha256.Sum256([]byte(name))
+				fmt.Fprintf(&want, "%s  %s\n", hex.EncodeToString(sum[:]), name)
+			}
+			if contents[ManifestName] != want.String() {
+				t.Errorf("manifest is\n%s\nwant\n%s", contents[ManifestName], want.String())
+			}
+		})
+	}
+}
+
+func TestWriteChangedFile(t *testing.T) {
+	dir := writeTree(t, "train.csv")
+	files, err := Files(dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "train.csv"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(io.Discard, dir, files, TarGz); err == nil || !strings.Contains(err.Error(), "changed while it was packed") {
+		t.Errorf("got %v, want the shrunk file reported", err)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Format
+		wantErr bool
+	}{
+		{"tar.gz", TarGz, false},
+		{".TGZ", TarGz, false},
+		{"zip", Zip, false},
+		{"rar", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.value)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseFormat(%q) = %q, %v", tt.value, got, err)
+		}
+	}
+	if name := Zip.Name("/data/run-1/"); name != "run-1.zip" {
+		t.Errorf("archive of /data/run-1/ is named %s", name)
+	}
+}
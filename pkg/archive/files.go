@@ -0,0 +1,184 @@
+package archive
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IgnoreFileName is read from the root of a packed directory. It holds one
+// pattern per line like .gitignore: # starts a comment, ! re-includes what an
+// earlier pattern excluded, a trailing / only matches directories, and a
+// pattern without a / matches at any depth.
+const IgnoreFileName = ".synexisignore"
+
+var ErrNoFiles = errors.New("no files to pack")
+
+type (
+	// File is a regular file of a packed directory, Path is relative to the
+	// directory and uses forward slashes.
+	File struct {
+		Path    string
+		Size    int64
+		ModTime time.Time
+	}
+	rule struct {
+		pattern  string
+		negate   bool
+		dirsOnly bool
+	}
+)
+
+// Files lists the files of dir to pack, sorted by path. Files must match one
+// of include when it is not empty, and excluded directories are not entered.
+// Symbolic links to files are followed, links to directories are not.
+func Files(dir string, include, exclude []string) ([]File, error) {
+	rules, err := readIgnoreFile(filepath.Join(dir, IgnoreFileName))
+	if err != nil {
+		return nil, err
+	}
+	for _, pattern := range exclude {
+		rules = append(rules, parseRule(pattern))
+	}
+	for _, patterns := range [][]string{include, exclude} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+
+	var files []File
+	err = filepath.WalkDir(dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if ignored(rules, rel, entry.IsDir()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || rel == IgnoreFileName {
+			return nil
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || (len(include) > 0 && !matchesAny(include, rel)) {
+			return nil
+		}
+		files = append(files, File{Path: rel, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, ErrNoFiles
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+func readIgnoreFile(name string) ([]rule, error) {
+	file, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var rules []rule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rules = append(rules, parseRule(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFileName, err)
+	}
+	return rules, nil
+}
+
+func parseRule(pattern string) rule {
+	var r rule
+	if strings.HasPrefix(pattern, "!") {
+		r.negate = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		r.dirsOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	r.pattern = pattern
+	return r
+}
+
+// ignored applies rules in order, the last matching rule decides.
+func ignored(rules []rule, rel string, isDir bool) bool {
+	ignore := false
+	for _, r := range rules {
+		if (!r.dirsOnly || isDir) && match(r.pattern, rel) {
+			ignore = !r.negate
+		}
+	}
+	return ignore
+}
+
+func matchesAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if match(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether rel matches pattern, where ** stands for any number
+// of directories. A pattern without a slash matches the base name at any
+// depth, a leading slash anchors it to the root.
+func match(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	return matchSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(segments); skip++ {
+				if matchSegments(pattern[1:], segments[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
@@ -0,0 +1,104 @@
+package archive
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeTree creates files under a fresh directory, content is the path.
+func writeTree(t *testing.T, paths ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, rel := range paths {
+		name := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(rel), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func filePaths(files []File) []string {
+	paths := []string{}
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	return paths
+}
+
+func TestFiles(t *testing.T) {
+	tree := []string{"train.csv", "test.csv", "notes.md", "images/a.png", "images/b.png", "images/raw/c.tiff", "cache/tmp.csv", "logs/run.log"}
+	tests := []struct {
+		name    string
+		ignore  string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{"everything sorted", "", nil, nil, []string{"cache/tmp.csv", "images/a.png", "images/b.png", "images/raw/c.tiff", "logs/run.log", "notes.md", "test.csv", "train.csv"}},
+		{"include at any depth", "", []string{"*.csv"}, nil, []string{"cache/tmp.csv", "test.csv", "train.csv"}},
+		{"include anchored", "", []string{"/*.csv"}, nil, []string{"test.csv", "train.csv"}},
+		{"include with **", "", []string{"images/**"}, nil, []string{"images/a.png", "images/b.png", "images/raw/c.tiff"}},
+		{"exclude directory", "", nil, []string{"images/", "cache"}, []string{"logs/run.log", "notes.md", "test.csv", "train.csv"}},
+		{"include and exclude", "", []string{"*.csv", "*.png"}, []string{"b.png", "cache/"}, []string{"images/a.png", "test.csv", "train.csv"}},
+		{"ignore file", "# scratch files\n\ncache/\n*.log\n", nil, nil, []string{"images/a.png", "images/b.png", "images/raw/c.tiff", "notes.md", "test.csv", "train.csv"}},
+		{"ignore file negation", "*.png\n!a.png\nimages/raw/\n", nil, nil, []string{"cache/tmp.csv", "images/a.png", "logs/run.log", "notes.md", "test.csv", "train.csv"}},
+		{"last matching rule wins", "!train.csv\n*.csv\n", nil, nil, []string{"images/a.png", "images/b.png", "images/raw/c.tiff", "logs/run.log", "notes.md"}},
+		{"negation cannot reach into an ignored directory", "cache/\n!cache/tmp.csv\n", []string{"*.csv"}, nil, []string{"test.csv", "train.csv"}},
+		{"exclude after the ignore file", "*.md\n", nil, []string{"*.csv", "!train.csv"}, []string{"images/a.png", "images/b.png", "images/raw/c.tiff", "logs/run.log", "train.csv"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeTree(t, tree...)
+			if tt.ignore != "" {
+				if err := os.WriteFile(filepath.Join(dir, IgnoreFileName), []byte(tt.ignore), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			files, err := Files(dir, tt.include, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := filePaths(files); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("packed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilesErrors(t *testing.T) {
+	dir := writeTree(t, "train.csv")
+	if _, err := Files(dir, []string{"*.json"}, nil); !errors.Is(err, ErrNoFiles) {
+		t.Errorf("nothing included: got %v, want %v", err, ErrNoFiles)
+	}
+	if _, err := Files(dir, nil, []string{"[a-"}); err == nil {
+		t.Error("invalid exclude pattern was accepted")
+	}
+	if _, err := Files(dir, []string{"[a-"}, nil); err == nil {
+		t.Error("invalid include pattern was accepted")
+	}
+}
+
+func TestFilesSymlinks(t *testing.T) {
+	outside := writeTree(t, "shared.csv", "more/extra.csv")
+	dir := writeTree(t, "train.csv")
+	if err := os.Symlink(filepath.Join(outside, "shared.csv"), filepath.Join(dir, "shared.csv")); err != nil {
+		t.Skipf("cannot create symbolic links: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "more"), filepath.Join(dir, "more")); err != nil {
+		t.Fatal(err)
+	}
+	files, err := Files(dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := filePaths(files), []string{"shared.csv", "train.csv"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("packed %v, want %v, links to files followed and links to directories skipped", got, want)
+	}
+}
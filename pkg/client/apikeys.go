@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const (
+	createAPIKeyPath = "/api/v1/authentication/create/apikey"
+	listAPIKeysPath  = "/api/v1/authentication/apikeys"
+)
+
+type (
+	APIKeysService      struct{ service }
+	CreateAPIKeyRequest struct {
+		Prefix             string `json:"prefix"`
+		ValidationLayerOne string `json:"validationLayerOne"`
+		ValidationLayerTwo string `json:"validationLayerTwo"`
+	}
+	APIKeyResponse struct {
+		ResponseCode    string `json:"responseCode"`
+		ResponseMessage string `json:"responseMessage"`
+	}
+	APIKey struct {
+		Prefix    string    `json:"prefix"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+	ListAPIKeysResponse struct {
+		ResponseCode    string   `json:"responseCode"`
+		ResponseMessage string   `json:"responseMessage"`
+		Data            []APIKey `json:"data"`
+	}
+)
+
+func (s *APIKeysService) Create(ctx context.Context, request CreateAPIKeyRequest) (*APIKeyResponse, error) {
+	req, err := s.client.newJSONRequest(ctx, http.MethodPost, createAPIKeyPath, request)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.client.authorize(req); err != nil {
+		return nil, err
+	}
+	var apiKeyResp APIKeyResponse
+	if err := s.client.do(req, &apiKeyResp); err != nil {
+		return nil, err
+	}
+	return &apiKeyResp, nil
+}
+
+// List returns the API keys of the caller's company. Only prefixes are
+// returned, the secret parts are never sent back by the server.
+func (s *APIKeysService) List(ctx context.Context) (*ListAPIKeysResponse, error) {
+	req, err := s.client.newRequest(ctx, http.MethodGet, listAPIKeysPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.client.authorize(req); err != nil {
+		return nil, err
+	}
+	var listResp ListAPIKeysResponse
+	if err := s.client.do(req, &listResp); err != nil {
+		return nil, err
+	}
+	return &listResp, nil
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const (
+	loginPath   = "/api/v1/authentication/login"
+	refreshPath = "/api/v1/authentication/refresh"
+	revokePath  = "/api/v1/authentication/logout"
+	mePath      = "/api/v1/authentication/me"
+)
+
+type (
+	AuthService   struct{ service }
+	LoginResponse struct {
+		ResponseCode    string `json:"responseCode"`
+		ResponseMessage string `json:"responseMessage"`
+		RedirectURL     string `json:"redirectUrl"`
+	}
+	RefreshResponse struct {
+		ResponseCode    string `json:"responseCode"`
+		ResponseMessage string `json:"responseMessage"`
+		Refresh         string `json:"refresh"`
+		Access          string `json:"access"`
+	}
+	MeResponse struct {
+		ResponseCode    string `json:"responseCode"`
+		ResponseMessage string `json:"responseMessage"`
+		Data            struct {
+			UserID    string `json:"userId"`
+			Name      string `json:"name"`
+			Email     string `json:"email"`
+			CompanyID string `json:"companyId"`
+		} `json:"data"`
+	}
+)
+
+// LoginWithGoogle asks the server for the Google sign-in page to open in a
+// browser.
+func (s *AuthService) LoginWithGoogle(ctx context.Context) (*LoginResponse, error) {
+	req, err := s.client.newJSONRequest(ctx, http.MethodPost, loginPath, struct{}{})
+	if err != nil {
+		return nil, err
+	}
+	var loginResp LoginResponse
+	if err := s.client.do(req, &loginResp); err != nil {
+		return nil, err
+	}
+	return &loginResp, nil
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token pair.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*RefreshResponse, error) {
+	req, err := s.client.newJSONRequest(ctx, http.MethodPost, refreshPath, struct{}{})
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+refreshToken)
+	var refreshResp RefreshResponse
+	if err := s.client.do(req, &refreshResp); err != nil {
+		return nil, err
+	}
+	return &refreshResp, nil
+}
+
+// Revoke invalidates the refresh token on the server. ErrNotSupported is
+// returned when the server has no revocation endpoint.
+func (s *AuthService) Revoke(ctx context.Context, refreshToken string) error {
+	req, err := s.client.newJSONRequest(ctx, http.MethodPost, revokePath, struct{}{})
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+refreshToken)
+	resp, err := s.client.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to contact server: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusMethodNotAllowed, resp.StatusCode == http.StatusNotImplemented:
+		return ErrNotSupported
+	}
+	return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+}
+
+// Me returns the account the current credentials belong to.
+func (s *AuthService) Me(ctx context.Context) (*MeResponse, error) {
+	req, err := s.client.newRequest(ctx, http.MethodGet, mePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.client.authorize(req); err != nil {
+		return nil, err
+	}
+	var meResp MeResponse
+	if err := s.client.do(req, &meResp); err != nil {
+		return nil, err
+	}
+	return &meResp, nil
+}
@@ -0,0 +1,215 @@
+// Package client is a Go SDK for the Synexis API.
+//
+//	c, err := client.New(
+//		client.WithBaseURL("https://api.synexis.example"),
+//		client.WithTokenSource(client.StaticTokenSource("access-token")),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	upload, err := c.Datasets.UploadFile(ctx, "./train.csv")
+//
+// Nothing in this package logs or exits the process, every failure is
+// returned to the caller.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const defaultUserAgent = "synexis-go"
+
+type (
+	Client struct {
+		baseURL     *url.URL
+		httpClient  *http.Client
+		tokenSource TokenSource
+		tokenStore  TokenStore
+		userAgent   string
+
+		Auth     *AuthService
+		APIKeys  *APIKeysService
+		Datasets *DatasetsService
+		Sensory  *SensoryService
+		Training *TrainingService
+		Services *ServicesService
+	}
+	Option func(*Client) error
+	// service is embedded by every domain group to reach the shared client.
+	service struct {
+		client *Client
+	}
+)
+
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) error {
+		u, err := url.ParseRequestURI(baseURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid base url %q", baseURL)
+		}
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		c.baseURL = u
+		return nil
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) error {
+		if httpClient == nil {
+			return errors.New("http client must not be nil")
+		}
+		c.httpClient = httpClient
+		return nil
+	}
+}
+
+func WithTokenSource(tokenSource TokenSource) Option {
+	return func(c *Client) error {
+		c.tokenSource = tokenSource
+		return nil
+	}
+}
+
+// WithStoredTokens authenticates with tokens kept in store, refreshing them
+// through the client's own Auth service when they expire.
+func WithStoredTokens(store TokenStore) Option {
+	return func(c *Client) error {
+		c.tokenStore = store
+		return nil
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) error {
+		c.userAgent = userAgent
+		return nil
+	}
+}
+
+func New(opts ...Option) (*Client, error) {
+	c := &Client{
+		httpClient: http.DefaultClient,
+		userAgent:  defaultUserAgent,
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.baseURL == nil {
+		return nil, errors.New("base url is required")
+	}
+	c.initServices()
+	if c.tokenStore != nil {
+		c.tokenSource = StoredTokenSource(c.tokenStore, c.Auth)
+	}
+	return c, nil
+}
+
+// WithTokenSource returns a copy of the client that authenticates with
+// tokenSource, sharing the underlying http client.
+func (c *Client) WithTokenSource(tokenSource TokenSource) *Client {
+	clone := *c
+	clone.tokenSource = tokenSource
+	clone.tokenStore = nil
+	clone.initServices()
+	return &clone
+}
+
+// Token returns the credentials the client would send on the next call.
+func (c *Client) Token(ctx context.Context) (*Token, error) {
+	if c.tokenSource == nil {
+		return nil, ErrNoTokenSource
+	}
+	return c.tokenSource.Token(ctx)
+}
+
+func (c *Client) BaseURL() string {
+	return c.baseURL.String()
+}
+
+func (c *Client) initServices() {
+	shared := service{client: c}
+	c.Auth = &AuthService{shared}
+	c.APIKeys = &APIKeysService{shared}
+	c.Datasets = &DatasetsService{shared}
+	c.Sensory = &SensoryService{shared}
+	c.Training = &TrainingService{shared}
+	c.Services = &ServicesService{shared}
+}
+
+func (c *Client) endpoint(path string) string {
+	return c.baseURL.String() + path
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint(path), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+func (c *Client) newJSONRequest(ctx context.Context, method, path string, payload interface{}) (*http.Request, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := c.newRequest(ctx, method, path, bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// authorize attaches credentials from the configured token source.
+func (c *Client) authorize(req *http.Request) error {
+	token, err := c.Token(req.Context())
+	if err != nil {
+		if errors.Is(err, ErrNoTokenSource) {
+			return err
+		}
+		return fmt.Errorf("failed to obtain token: %w", err)
+	}
+	token.SetAuthHeader(req)
+	return nil
+}
+
+// do sends req and decodes the JSON response into v. Responses that are not
+// JSON are reported as *APIError.
+func (c *Client) do(req *http.Request, v interface{}) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to contact server: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	// a refused encoding is reported as an error even with a JSON body, so
+	// that callers can send the content again uncompressed
+	refused := resp.StatusCode == http.StatusUnsupportedMediaType && req.Header.Get("Content-Encoding") != ""
+	if err := json.Unmarshal(raw, v); err != nil || refused {
+		return &APIError{StatusCode: resp.StatusCode, Body: truncate(string(raw), 512)}
+	}
+	return nil
+}
+
+func truncate(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	return value[:limit] + "..."
+}
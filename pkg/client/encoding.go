@@ -0,0 +1,144 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/synxms/synexis/pkg/zstd"
+)
+
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+	// EncodingAuto compresses with the best encoding the server accepts,
+	// see WithAcceptedEncodings, unless the content already is compressed,
+	// judged by its leading bytes.
+	EncodingAuto = "auto"
+)
+
+type (
+	UploadOption  func(*uploadOptions)
+	uploadOptions struct {
+		encoding string
+		accepted []string
+	}
+)
+
+// WithContentEncoding compresses the upload body while it is written and
+// labels it with a Content-Encoding header. Use the service's Encodings to
+// learn whether the server accepts it.
+func WithContentEncoding(encoding string) UploadOption {
+	return func(o *uploadOptions) {
+		o.encoding = encoding
+	}
+}
+
+// WithAcceptedEncodings tells EncodingAuto which encodings the server
+// accepts, as returned by Encodings. zstd is picked over gzip when both are,
+// gzip is assumed without this option.
+func WithAcceptedEncodings(encodings []string) UploadOption {
+	return func(o *uploadOptions) {
+		o.accepted = encodings
+	}
+}
+
+// compressedSignatures are the leading bytes of formats that do not shrink
+// when compressed again.
+var compressedSignatures = [][]byte{
+	{0x1f, 0x8b},                     // gzip
+	{'P', 'K', 0x03, 0x04},           // zip
+	{0x28, 0xb5, 0x2f, 0xfd},         // zstd
+	{'B', 'Z', 'h'},                  // bzip2
+	{0xfd, '7', 'z', 'X', 'Z', 0x00}, // xz
+	{0x04, 0x22, 0x4d, 0x18},         // lz4
+	{'P', 'A', 'R', '1'},             // parquet
+	{0x89, 'P', 'N', 'G'},            // png
+	{0xff, 0xd8, 0xff},               // jpeg
+	{'G', 'I', 'F', '8'},             // gif
+	{'R', 'I', 'F', 'F'},             // webp
+}
+
+func newUploadOptions(opts []UploadOption) *uploadOptions {
+	options := &uploadOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// resolve settles EncodingAuto by looking at the start of content, and
+// returns a reader that still yields all of it.
+func (o *uploadOptions) resolve(content io.Reader) (string, io.Reader, error) {
+	switch encoding := o.encoding; encoding {
+	case "", "identity", "none":
+		return "", content, nil
+	case EncodingGzip, EncodingZstd:
+		return encoding, content, nil
+	case EncodingAuto:
+		buffered := bufio.NewReader(content)
+		head, _ := buffered.Peek(8)
+		for _, signature := range compressedSignatures {
+			if bytes.HasPrefix(head, signature) {
+				return "", buffered, nil
+			}
+		}
+		if slices.Contains(o.accepted, EncodingZstd) {
+			return EncodingZstd, buffered, nil
+		}
+		return EncodingGzip, buffered, nil
+	default:
+		return "", nil, fmt.Errorf("%q: %w", encoding, ErrUnsupportedEncoding)
+	}
+}
+
+// encodeBody wraps w so that what is written to it is compressed with
+// encoding. The returned close flushes the compressor without closing w.
+func encodeBody(w io.Writer, encoding string) (io.Writer, func() error) {
+	switch encoding {
+	case EncodingGzip:
+		compressor := gzip.NewWriter(w)
+		return compressor, compressor.Close
+	case EncodingZstd:
+		compressor := zstd.NewWriter(w)
+		return compressor, compressor.Close
+	}
+	return w, func() error { return nil }
+}
+
+// encodings asks the server which request encodings path accepts, from the
+// Accept-Encoding header of its OPTIONS response. A server that does not
+// answer OPTIONS accepts none.
+func (c *Client) encodings(ctx context.Context, path string) ([]string, error) {
+	req, err := c.newRequest(ctx, http.MethodOptions, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to contact server: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode/100 != 2 {
+		return nil, nil
+	}
+	var encodings []string
+	for _, value := range resp.Header.Values("Accept-Encoding") {
+		for _, item := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(item, ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" || strings.ReplaceAll(strings.TrimSpace(params), " ", "") == "q=0" {
+				continue
+			}
+			encodings = append(encodings, name)
+		}
+	}
+	return encodings, nil
+}
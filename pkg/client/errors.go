@@ -0,0 +1,29 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrNoTokenSource = errors.New("client has no token source configured")
+	ErrNotSupported  = errors.New("not supported by the server")
+	// ErrUnsupportedEncoding is returned for content encodings this client
+	// cannot produce.
+	ErrUnsupportedEncoding = errors.New("content encoding not available in this client")
+)
+
+// APIError is returned when the server answers with something other than the
+// expected JSON document.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("unexpected response from server: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("unexpected response from server: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
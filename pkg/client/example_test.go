@@ -0,0 +1,188 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/synxms/synexis/pkg/client"
+	"github.com/synxms/synexis/pkg/mockserver"
+)
+
+// startMock serves a mock Synexis API and returns its base URL with an access
+// token for it. The examples run against it instead of the real server.
+func startMock() (mock *mockserver.Server, baseURL, accessToken string, stop func()) {
+	mock = mockserver.New()
+	server := httptest.NewServer(mock)
+	accessToken, _, err := mock.IssueTokens(server.URL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return mock, server.URL, accessToken, server.Close
+}
+
+// newExampleClient returns a client signed in to a fresh mock server.
+func newExampleClient() (*client.Client, *mockserver.Server, func()) {
+	mock, baseURL, accessToken, stop := startMock()
+	c, err := client.New(client.WithBaseURL(baseURL), client.WithTokenSource(client.StaticTokenSource(accessToken)))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return c, mock, stop
+}
+
+func ExampleNew() {
+	_, baseURL, accessToken, stop := startMock()
+	defer stop()
+
+	c, err := client.New(
+		client.WithBaseURL(baseURL),
+		client.WithTokenSource(client.StaticTokenSource(accessToken)),
+		client.WithUserAgent("example/1.0"),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	me, err := c.Auth.Me(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(me.Data.Email)
+	// Output: mock@synexis.test
+}
+
+// An API key can stand in for a signed in user, for example on a build
+// server.
+func ExampleWithTokenSource() {
+	c, _, stop := newExampleClient()
+	defer stop()
+	ctx := context.Background()
+
+	key := client.CreateAPIKeyRequest{Prefix: "ci", ValidationLayerOne: "one", ValidationLayerTwo: "two"}
+	if _, err := c.APIKeys.Create(ctx, key); err != nil {
+		log.Fatal(err)
+	}
+	me, err := c.Auth.Me(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	apiKey := strings.Join([]string{key.Prefix, key.ValidationLayerOne, key.ValidationLayerTwo, me.Data.CompanyID}, "-")
+	ci, err := client.New(client.WithBaseURL(c.BaseURL()), client.WithTokenSource(client.APIKeyTokenSource(apiKey)))
+	if err != nil {
+		log.Fatal(err)
+	}
+	me, err = ci.Auth.Me(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(me.Data.CompanyID)
+	// Output: company-mock
+}
+
+func ExampleAPIKeysService_List() {
+	c, _, stop := newExampleClient()
+	defer stop()
+	ctx := context.Background()
+
+	if _, err := c.APIKeys.Create(ctx, client.CreateAPIKeyRequest{Prefix: "ci", ValidationLayerOne: "one", ValidationLayerTwo: "two"}); err != nil {
+		log.Fatal(err)
+	}
+	keys, err := c.APIKeys.List(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, key := range keys.Data {
+		fmt.Println(key.Prefix)
+	}
+	// Output: ci
+}
+
+// Compress the upload with the best encoding the server accepts.
+func ExampleDatasetsService_UploadFile() {
+	c, mock, stop := newExampleClient()
+	defer stop()
+	ctx := context.Background()
+
+	dir, err := os.MkdirTemp("", "synexis-example")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "train.csv")
+	if err := os.WriteFile(path, []byte(strings.Repeat("x,y\n1,2\n", 1000)), 0o600); err != nil {
+		log.Fatal(err)
+	}
+
+	encodings, err := c.Datasets.Encodings(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	upload, err := c.Datasets.UploadFile(ctx, path,
+		client.WithContentEncoding(client.EncodingAuto),
+		client.WithAcceptedEncodings(encodings),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	received := mock.Uploads()[0]
+	fmt.Println(received.ID == upload.Data.DatasetID, received.ContentEncoding, received.Size)
+	// Output: true zstd 8000
+}
+
+func ExampleSensoryService_Upload() {
+	c, mock, stop := newExampleClient()
+	defer stop()
+
+	config := strings.NewReader(`{"sensors": ["camera", "lidar"]}`)
+	upload, err := c.Sensory.Upload(context.Background(), "sensory.json", config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	received := mock.Uploads()[0]
+	fmt.Println(received.ID == upload.Data.SensoryID, received.FileName)
+	// Output: true sensory.json
+}
+
+func ExampleTrainingService_Create() {
+	c, _, stop := newExampleClient()
+	defer stop()
+	ctx := context.Background()
+
+	sensory, err := c.Sensory.Upload(ctx, "sensory.json", strings.NewReader("{}"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	dataset, err := c.Datasets.Upload(ctx, "train.csv", strings.NewReader("x,y\n1,2\n"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	created, err := c.Training.Create(ctx, sensory.Data.SensoryID, dataset.Data.DatasetID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	request, err := c.Training.Get(ctx, created.Data.RequestID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(request.Data.Status, request.Data.Finished())
+	// Output: pending false
+}
+
+func ExampleServicesService_List() {
+	c, _, stop := newExampleClient()
+	defer stop()
+
+	services, err := c.Services.List(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, service := range services.Data {
+		fmt.Println(service.Name, service.Status)
+	}
+	// Output: sentinel available
+}
@@ -0,0 +1,282 @@
+package client
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	jwksPath       = "/.well-known/jwks.json"
+	jwksCacheKey   = "jwks"
+	defaultKeysTTL = 24 * time.Hour
+)
+
+var (
+	ErrInvalidSignature  = errors.New("token signature is invalid")
+	ErrUnknownSigningKey = errors.New("token is signed with an unknown key")
+	ErrKeyMismatch       = errors.New("token algorithm does not match its signing key")
+	ErrNoCachedKeys      = errors.New("no cached signing keys available offline")
+	ErrKeysUnavailable   = errors.New("signing keys are unavailable")
+	ErrIssuerMismatch    = errors.New("token issuer does not match")
+	ErrAudienceMismatch  = errors.New("token audience does not match")
+)
+
+type (
+	// KeyCache persists the server's signing keys between runs.
+	KeyCache interface {
+		Get(key string) (string, error)
+		Set(key, value string) error
+	}
+	VerifyOptions struct {
+		Issuer   string
+		Audience string
+		// Offline never contacts the server and relies on cached keys only.
+		Offline bool
+		// KeysTTL is how long cached keys are used before being refetched.
+		KeysTTL time.Duration
+	}
+	JSONWebKey struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		Alg string `json:"alg,omitempty"`
+		Use string `json:"use,omitempty"`
+		N   string `json:"n,omitempty"`
+		E   string `json:"e,omitempty"`
+		Crv string `json:"crv,omitempty"`
+		X   string `json:"x,omitempty"`
+		Y   string `json:"y,omitempty"`
+	}
+	JSONWebKeySet struct {
+		Keys []JSONWebKey `json:"keys"`
+	}
+	cachedKeySet struct {
+		FetchedAt time.Time     `json:"fetchedAt"`
+		KeySet    JSONWebKeySet `json:"keySet"`
+	}
+	// Verifier checks token signatures against the server's JWKS.
+	Verifier struct {
+		mu      sync.Mutex
+		client  *Client
+		cache   KeyCache
+		options VerifyOptions
+	}
+)
+
+// FetchJWKS downloads the signing keys published by the server.
+func (c *Client) FetchJWKS(ctx context.Context) (*JSONWebKeySet, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, jwksPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	var keySet JSONWebKeySet
+	if err := c.do(req, &keySet); err != nil {
+		return nil, err
+	}
+	if len(keySet.Keys) == 0 {
+		return nil, errors.New("server published no signing keys")
+	}
+	return &keySet, nil
+}
+
+// NewVerifier returns a Verifier that caches keys in cache, which may be nil.
+func NewVerifier(c *Client, cache KeyCache, options VerifyOptions) *Verifier {
+	if options.KeysTTL <= 0 {
+		options.KeysTTL = defaultKeysTTL
+	}
+	return &Verifier{client: c, cache: cache, options: options}
+}
+
+// Verify checks the signature, issuer and audience of tokenString and returns
+// its claims. Time based claims are left to the caller so that an expired
+// token can be told apart from a forged one. Every failure is
+// ErrInvalidSignature except keys that could not be had, ErrKeysUnavailable
+// and ErrNoCachedKeys, which say nothing about the token.
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(
+		jwt.WithoutClaimsValidation(),
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+	)
+	_, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.key(ctx, kid, token.Method.Alg())
+	})
+	if err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && validationErr.Inner != nil {
+			if errors.Is(validationErr.Inner, ErrKeysUnavailable) || errors.Is(validationErr.Inner, ErrNoCachedKeys) {
+				return nil, validationErr.Inner
+			}
+			err = validationErr.Inner
+		}
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+	if v.options.Issuer != "" && !claims.VerifyIssuer(v.options.Issuer, true) {
+		return nil, ErrIssuerMismatch
+	}
+	if v.options.Audience != "" && !claims.VerifyAudience(v.options.Audience, true) {
+		return nil, ErrAudienceMismatch
+	}
+	return claims, nil
+}
+
+// key looks kid up in the cached key set, refetching it when the cache is
+// stale or the key is unknown, which is how rotated keys are picked up.
+func (v *Verifier) key(ctx context.Context, kid, alg string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	cached := v.loadCache()
+	fresh := cached != nil && time.Since(cached.FetchedAt) < v.options.KeysTTL
+	if cached != nil && (fresh || v.options.Offline) {
+		if key, err := cached.KeySet.lookup(kid, alg); err == nil || v.options.Offline {
+			return key, err
+		}
+	}
+	if v.options.Offline {
+		return nil, ErrNoCachedKeys
+	}
+	keySet, err := v.client.FetchJWKS(ctx)
+	if err != nil {
+		if cached != nil {
+			// server unreachable, fall back to what we have
+			return cached.KeySet.lookup(kid, alg)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrKeysUnavailable, err)
+	}
+	v.storeCache(&cachedKeySet{FetchedAt: time.Now(), KeySet: *keySet})
+	return keySet.lookup(kid, alg)
+}
+
+func (v *Verifier) loadCache() *cachedKeySet {
+	if v.cache == nil {
+		return nil
+	}
+	raw, err := v.cache.Get(jwksCacheKey)
+	if err != nil || raw == "" {
+		return nil
+	}
+	var cached cachedKeySet
+	if err := json.Unmarshal([]byte(raw), &cached); err != nil {
+		return nil
+	}
+	return &cached
+}
+
+func (v *Verifier) storeCache(cached *cachedKeySet) {
+	if v.cache == nil {
+		return
+	}
+	if raw, err := json.Marshal(cached); err == nil {
+		_ = v.cache.Set(jwksCacheKey, string(raw))
+	}
+}
+
+// lookup returns the key a token with header kid and alg is signed with. A
+// token without kid only matches a set holding a single signing key.
+func (s *JSONWebKeySet) lookup(kid, alg string) (crypto.PublicKey, error) {
+	var signing []JSONWebKey
+	for _, key := range s.Keys {
+		if key.Use == "" || key.Use == "sig" {
+			signing = append(signing, key)
+		}
+	}
+	var match *JSONWebKey
+	for i := range signing {
+		if signing[i].Kid == kid {
+			match = &signing[i]
+			break
+		}
+	}
+	if match == nil && kid == "" && len(signing) == 1 {
+		match = &signing[0]
+	}
+	if match == nil {
+		return nil, ErrUnknownSigningKey
+	}
+	if (match.Alg != "" && match.Alg != alg) || match.Kty != keyType(alg) {
+		return nil, ErrKeyMismatch
+	}
+	return match.PublicKey()
+}
+
+// keyType is the JWK key type that signs with alg.
+func keyType(alg string) string {
+	switch {
+	case strings.HasPrefix(alg, "RS"), strings.HasPrefix(alg, "PS"):
+		return "RSA"
+	case strings.HasPrefix(alg, "ES"):
+		return "EC"
+	case alg == "EdDSA":
+		return "OKP"
+	}
+	return ""
+}
+
+func (k *JSONWebKey) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(raw) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func newSigningKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func publicJWK(key *ecdsa.PrivateKey, kid, alg string) JSONWebKey {
+	return JSONWebKey{
+		Kid: kid,
+		Kty: "EC",
+		Alg: alg,
+		Use: "sig",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.PublicKey.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.PublicKey.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, kid string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{"sub": "user", "exp": time.Now().Add(time.Hour).Unix()})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// newJWKSClient serves keys as the server's JWKS.
+func newJWKSClient(t *testing.T, keys ...JSONWebKey) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != jwksPath {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(JSONWebKeySet{Keys: keys})
+	}))
+	t.Cleanup(server.Close)
+	c, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+type memoryCache map[string]string
+
+func (c memoryCache) Get(key string) (string, error) {
+	return c[key], nil
+}
+
+func (c memoryCache) Set(key, value string) error {
+	c[key] = value
+	return nil
+}
+
+func TestVerify(t *testing.T) {
+	server, other, attacker := newSigningKey(t), newSigningKey(t), newSigningKey(t)
+	twoKeys := []JSONWebKey{publicJWK(server, "server", "ES256"), publicJWK(other, "other", "ES256")}
+	tests := []struct {
+		name     string
+		keys     []JSONWebKey
+		token    string
+		wantErr  error
+		wantAlso error
+	}{
+		{"signed by the server", twoKeys, signES256(t, server, "server"), nil, nil},
+		{"unknown kid", twoKeys, signES256(t, attacker, "attacker"), ErrInvalidSignature, ErrUnknownSigningKey},
+		{"attacker key with the server's kid", twoKeys, signES256(t, attacker, "server"), ErrInvalidSignature, nil},
+		{"no kid with several keys", twoKeys, signES256(t, server, ""), ErrInvalidSignature, ErrUnknownSigningKey},
+		{"no kid with a single key", twoKeys[:1], signES256(t, server, ""), nil, nil},
+		{"key published for another alg", []JSONWebKey{publicJWK(server, "server", "ES384")}, signES256(t, server, "server"), ErrInvalidSignature, ErrKeyMismatch},
+		{"key of another type", []JSONWebKey{{Kid: "server", Kty: "RSA", N: "AQAB", E: "AQAB"}}, signES256(t, server, "server"), ErrInvalidSignature, ErrKeyMismatch},
+		{"malformed", twoKeys, "not.a.jwt", ErrInvalidSignature, nil},
+		{"unsigned", twoKeys, "eyJhbGciOiJub25lIn0.eyJzdWIiOiJ1c2VyIn0.", ErrInvalidSignature, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewVerifier(newJWKSClient(t, tt.keys...), memoryCache{}, VerifyOptions{})
+			claims, err := verifier.Verify(context.Background(), tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantAlso != nil && !errors.Is(err, tt.wantAlso) {
+				t.Errorf("got error %v, want it to wrap %v", err, tt.wantAlso)
+			}
+			if err == nil && claims["sub"] != "user" {
+				t.Errorf("got claims %v", claims)
+			}
+		})
+	}
+}
+
+func TestVerifyWithoutKeys(t *testing.T) {
+	key := newSigningKey(t)
+	token := signES256(t, key, "server")
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	c, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewVerifier(c, memoryCache{}, VerifyOptions{}).Verify(context.Background(), token); !errors.Is(err, ErrKeysUnavailable) {
+		t.Errorf("unreachable server: got %v, want %v", err, ErrKeysUnavailable)
+	}
+	if _, err := NewVerifier(c, memoryCache{}, VerifyOptions{Offline: true}).Verify(context.Background(), token); !errors.Is(err, ErrNoCachedKeys) {
+		t.Errorf("offline without cache: got %v, want %v", err, ErrNoCachedKeys)
+	}
+
+	// keys cached earlier still verify, and still reject a forgery
+	cache := memoryCache{}
+	verifier := NewVerifier(newJWKSClient(t, publicJWK(key, "server", "ES256")), cache, VerifyOptions{})
+	if _, err := verifier.Verify(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+	offline := NewVerifier(c, cache, VerifyOptions{Offline: true})
+	if _, err := offline.Verify(context.Background(), token); err != nil {
+		t.Errorf("offline with cached keys: %v", err)
+	}
+	if _, err := offline.Verify(context.Background(), signES256(t, newSigningKey(t), "forged")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("offline forgery: got %v, want %v", err, ErrInvalidSignature)
+	}
+}
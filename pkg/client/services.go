@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const listServicesPath = "/api/v1/services"
+
+// Service states reported by the server.
+const (
+	ServiceStatusAvailable   = "available"
+	ServiceStatusUnavailable = "unavailable"
+)
+
+type (
+	ServicesService struct{ service }
+	// ServiceInfo describes a service the server offers.
+	ServiceInfo struct {
+		Name        string `json:"name"`
+		Version     string `json:"version,omitempty"`
+		Status      string `json:"status"`
+		Description string `json:"description,omitempty"`
+	}
+	ListServicesResponse struct {
+		ResponseCode    string        `json:"success"`
+		ResponseMessage string        `json:"messages"`
+		Data            []ServiceInfo `json:"data"`
+	}
+)
+
+// List returns the services the server offers. It needs no credentials.
+// ErrNotSupported is returned when the server has no discovery endpoint.
+func (s *ServicesService) List(ctx context.Context) (*ListServicesResponse, error) {
+	req, err := s.client.newRequest(ctx, http.MethodGet, listServicesPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to contact server: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return nil, ErrNotSupported
+	}
+	var listResp ListServicesResponse
+	if err := json.Unmarshal(raw, &listResp); err != nil {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: truncate(string(raw), 512)}
+	}
+	return &listResp, nil
+}
+
+// Probe reports whether the server routes path at all, by sending OPTIONS.
+// Any answer but 404 or 501 means it does, whatever methods it allows.
+func (s *ServicesService) Probe(ctx context.Context, path string) (bool, error) {
+	req, err := s.client.newRequest(ctx, http.MethodOptions, path, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := s.client.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to contact server: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	return resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusNotImplemented, nil
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	TokenTypeBearer = "Bearer"
+	TokenTypeAPIKey = "ApiKey"
+
+	// expiryDelta refreshes tokens slightly early so they do not expire
+	// while a request is in flight.
+	expiryDelta = 30 * time.Second
+)
+
+type (
+	Token struct {
+		AccessToken string
+		Type        string
+		Expiry      time.Time
+	}
+	// TokenSource supplies credentials for authenticated calls. Implementations
+	// must be safe for concurrent use.
+	TokenSource interface {
+		Token(ctx context.Context) (*Token, error)
+	}
+	staticTokenSource struct {
+		token *Token
+	}
+)
+
+func (t *Token) SetAuthHeader(req *http.Request) {
+	if t.Type == TokenTypeAPIKey {
+		req.Header.Set("X-Api-Key", t.AccessToken)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+t.AccessToken)
+}
+
+// Valid reports whether the token is present and not about to expire.
+// Tokens without a known expiry are always considered valid.
+func (t *Token) Valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(expiryDelta).Before(t.Expiry)
+}
+
+// newBearerToken reads the expiry from the JWT claims when there is one.
+func newBearerToken(accessToken string) *Token {
+	token := &Token{AccessToken: accessToken, Type: TokenTypeBearer}
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(accessToken, claims); err == nil {
+		if exp, ok := claims["exp"].(float64); ok {
+			token.Expiry = time.Unix(int64(exp), 0)
+		}
+	}
+	return token
+}
+
+// StaticTokenSource always returns the same access token.
+func StaticTokenSource(accessToken string) TokenSource {
+	return &staticTokenSource{token: newBearerToken(accessToken)}
+}
+
+// APIKeyTokenSource authenticates with a Synexis API key instead of a JWT.
+func APIKeyTokenSource(apiKey string) TokenSource {
+	return &staticTokenSource{token: &Token{AccessToken: apiKey, Type: TokenTypeAPIKey}}
+}
+
+func (s *staticTokenSource) Token(_ context.Context) (*Token, error) {
+	if s.token.AccessToken == "" {
+		return nil, errors.New("access token is empty")
+	}
+	return s.token, nil
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const (
+	EnvAccessToken  = "SYNEXIS_ACCESS_TOKEN"
+	EnvAPIKey       = "SYNEXIS_API_KEY"
+	EnvTokenCommand = "SYNEXIS_TOKEN_COMMAND"
+
+	accessTokenKey  = "access_token"
+	refreshTokenKey = "refresh_token"
+)
+
+type (
+	// TokenStore is the subset of a key/value store the stored token source
+	// needs, satisfied by the CLI's local storage.
+	TokenStore interface {
+		Get(key string) (string, error)
+		Set(key, value string) error
+	}
+	Refresher interface {
+		Refresh(ctx context.Context, refreshToken string) (*RefreshResponse, error)
+	}
+	storedTokenSource struct {
+		mu        sync.Mutex
+		store     TokenStore
+		refresher Refresher
+	}
+	envTokenSource struct {
+		name string
+	}
+	commandTokenSource struct {
+		mu      sync.Mutex
+		command string
+		token   *Token
+	}
+)
+
+// StoredTokenSource reads the access token from store and, once it is about
+// to expire, exchanges the stored refresh token for a new pair and persists it.
+func StoredTokenSource(store TokenStore, refresher Refresher) TokenSource {
+	return &storedTokenSource{store: store, refresher: refresher}
+}
+
+func (s *storedTokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	accessToken, err := s.store.Get(accessTokenKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+	token := newBearerToken(accessToken)
+	if token.Valid() {
+		return token, nil
+	}
+	refreshToken, err := s.store.Get(refreshTokenKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	if refreshToken == "" {
+		if accessToken == "" {
+			return nil, errors.New("no access token stored, please authenticate first")
+		}
+		return token, nil
+	}
+	refreshed, err := s.refresher.Refresh(ctx, refreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh access token: %w", err)
+	}
+	if refreshed.ResponseCode != "00" || refreshed.Access == "" {
+		return nil, fmt.Errorf("failed to refresh access token: %s", refreshed.ResponseMessage)
+	}
+	if err := s.store.Set(refreshTokenKey, refreshed.Refresh); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	if err := s.store.Set(accessTokenKey, refreshed.Access); err != nil {
+		return nil, fmt.Errorf("failed to store access token: %w", err)
+	}
+	return newBearerToken(refreshed.Access), nil
+}
+
+// EnvTokenSource reads the access token from SYNEXIS_ACCESS_TOKEN on every
+// call.
+func EnvTokenSource() TokenSource {
+	return &envTokenSource{name: EnvAccessToken}
+}
+
+func (s *envTokenSource) Token(_ context.Context) (*Token, error) {
+	accessToken := os.Getenv(s.name)
+	if accessToken == "" {
+		return nil, fmt.Errorf("%s is not set", s.name)
+	}
+	return newBearerToken(accessToken), nil
+}
+
+// CommandTokenSource runs command through the system shell and uses its
+// trimmed stdout as the access token. The result is reused until it expires.
+func CommandTokenSource(command string) TokenSource {
+	return &commandTokenSource{command: command}
+}
+
+func (s *commandTokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token.Valid() && !s.token.Expiry.IsZero() {
+		return s.token, nil
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", s.command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", s.command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Stdin = nil
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("token command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	accessToken := strings.TrimSpace(stdout.String())
+	if accessToken == "" {
+		return nil, errors.New("token command printed no token")
+	}
+	s.token = newBearerToken(accessToken)
+	return s.token, nil
+}
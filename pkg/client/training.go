@@ -0,0 +1,215 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	createTrainingRequestPath = "/api/v1/sentinel/sessions/create/request"
+	trainingRequestPath       = "/api/v1/sentinel/sessions/request/"
+	listTrainingRequestsPath  = "/api/v1/sentinel/sessions/requests"
+)
+
+// Training request states reported by the server.
+const (
+	TrainingStatusPending   = "pending"
+	TrainingStatusRunning   = "running"
+	TrainingStatusSucceeded = "succeeded"
+	TrainingStatusFailed    = "failed"
+	TrainingStatusCancelled = "cancelled"
+)
+
+type (
+	TrainingService       struct{ service }
+	CreateRequestResponse struct {
+		ResponseCode    string `json:"success"`
+		ResponseMessage string `json:"messages"`
+		Data            struct {
+			RequestID string `json:"request_id"`
+		} `json:"data"`
+	}
+	TrainingRequest struct {
+		RequestID string    `json:"request_id"`
+		SensoryID string    `json:"sensory_id"`
+		DatasetID string    `json:"dataset_id"`
+		Status    string    `json:"status"`
+		Message   string    `json:"message,omitempty"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+		// Metrics are the latest training metrics, such as loss or accuracy.
+		Metrics map[string]float64 `json:"metrics,omitempty"`
+	}
+	TrainingRequestResponse struct {
+		ResponseCode    string          `json:"success"`
+		ResponseMessage string          `json:"messages"`
+		Data            TrainingRequest `json:"data"`
+	}
+	ListTrainingRequestsResponse struct {
+		ResponseCode    string            `json:"success"`
+		ResponseMessage string            `json:"messages"`
+		Data            []TrainingRequest `json:"data"`
+	}
+	TrainingLogEntry struct {
+		Time    time.Time `json:"time"`
+		Message string    `json:"message"`
+	}
+	TrainingLogsResponse struct {
+		ResponseCode    string             `json:"success"`
+		ResponseMessage string             `json:"messages"`
+		Data            []TrainingLogEntry `json:"data"`
+	}
+	// TrainingArtifact is a file produced by training. URL is either relative
+	// to the base url or points at external storage.
+	TrainingArtifact struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+		Size int64  `json:"size"`
+	}
+	TrainingArtifactsResponse struct {
+		ResponseCode    string             `json:"success"`
+		ResponseMessage string             `json:"messages"`
+		Data            []TrainingArtifact `json:"data"`
+	}
+)
+
+// Finished reports whether the request reached a state it will not leave.
+func (r *TrainingRequest) Finished() bool {
+	switch r.Status {
+	case TrainingStatusSucceeded, TrainingStatusFailed, TrainingStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// Create requests training of a custom model from an uploaded sensory
+// configuration and dataset.
+func (s *TrainingService) Create(ctx context.Context, sensoryID, datasetID string) (*CreateRequestResponse, error) {
+	payload := map[string]string{
+		"sensory_id": sensoryID,
+		"dataset_id": datasetID,
+	}
+	req, err := s.client.newJSONRequest(ctx, http.MethodPost, createTrainingRequestPath, payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.client.authorize(req); err != nil {
+		return nil, err
+	}
+	var createResp CreateRequestResponse
+	if err := s.client.do(req, &createResp); err != nil {
+		return nil, err
+	}
+	return &createResp, nil
+}
+
+// Get returns the current state of a training request.
+func (s *TrainingService) Get(ctx context.Context, requestID string) (*TrainingRequestResponse, error) {
+	var getResp TrainingRequestResponse
+	if err := s.get(ctx, trainingRequestPath+url.PathEscape(requestID), &getResp); err != nil {
+		return nil, err
+	}
+	return &getResp, nil
+}
+
+// List returns the training requests of the caller's company, newest first.
+func (s *TrainingService) List(ctx context.Context) (*ListTrainingRequestsResponse, error) {
+	var listResp ListTrainingRequestsResponse
+	if err := s.get(ctx, listTrainingRequestsPath, &listResp); err != nil {
+		return nil, err
+	}
+	return &listResp, nil
+}
+
+// Logs returns the training log of a request, oldest entry first.
+func (s *TrainingService) Logs(ctx context.Context, requestID string) (*TrainingLogsResponse, error) {
+	var logsResp TrainingLogsResponse
+	if err := s.get(ctx, trainingRequestPath+url.PathEscape(requestID)+"/logs", &logsResp); err != nil {
+		return nil, err
+	}
+	return &logsResp, nil
+}
+
+// Cancel stops a request that has not finished yet.
+func (s *TrainingService) Cancel(ctx context.Context, requestID string) (*TrainingRequestResponse, error) {
+	var cancelResp TrainingRequestResponse
+	if err := s.post(ctx, trainingRequestPath+url.PathEscape(requestID)+"/cancel", &cancelResp); err != nil {
+		return nil, err
+	}
+	return &cancelResp, nil
+}
+
+// Retry submits a failed or cancelled request again with the same inputs,
+// the new request ID is returned.
+func (s *TrainingService) Retry(ctx context.Context, requestID string) (*CreateRequestResponse, error) {
+	var retryResp CreateRequestResponse
+	if err := s.post(ctx, trainingRequestPath+url.PathEscape(requestID)+"/retry", &retryResp); err != nil {
+		return nil, err
+	}
+	return &retryResp, nil
+}
+
+// Artifacts lists the files a finished request produced.
+func (s *TrainingService) Artifacts(ctx context.Context, requestID string) (*TrainingArtifactsResponse, error) {
+	var artifactsResp TrainingArtifactsResponse
+	if err := s.get(ctx, trainingRequestPath+url.PathEscape(requestID)+"/artifacts", &artifactsResp); err != nil {
+		return nil, err
+	}
+	return &artifactsResp, nil
+}
+
+// DownloadArtifact streams an artifact into w. Credentials are only sent
+// when the artifact is served by the API itself, over the same scheme and
+// host, never to external storage or over a downgraded connection.
+func (s *TrainingService) DownloadArtifact(ctx context.Context, artifact TrainingArtifact, w io.Writer) (int64, error) {
+	target, err := s.client.baseURL.Parse(artifact.URL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid artifact url %q: %w", artifact.URL, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", s.client.userAgent)
+	if target.Scheme == s.client.baseURL.Scheme && target.Host == s.client.baseURL.Host {
+		if err := s.client.authorize(req); err != nil {
+			return 0, err
+		}
+	}
+	resp, err := s.client.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to contact server: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return 0, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return io.Copy(w, resp.Body)
+}
+
+func (s *TrainingService) get(ctx context.Context, path string, v interface{}) error {
+	req, err := s.client.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	if err := s.client.authorize(req); err != nil {
+		return err
+	}
+	return s.client.do(req, v)
+}
+
+func (s *TrainingService) post(ctx context.Context, path string, v interface{}) error {
+	req, err := s.client.newJSONRequest(ctx, http.MethodPost, path, struct{}{})
+	if err != nil {
+		return err
+	}
+	if err := s.client.authorize(req); err != nil {
+		return err
+	}
+	return s.client.do(req, v)
+}
@@ -0,0 +1,55 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/synxms/synexis/pkg/client"
+)
+
+func TestDownloadArtifactCredentials(t *testing.T) {
+	var authorization string
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte("weights"))
+	}))
+	defer storage.Close()
+	external := httptest.NewServer(storage.Config.Handler)
+	defer external.Close()
+	host := strings.TrimPrefix(storage.URL, "http://")
+
+	tests := []struct {
+		name     string
+		baseURL  string
+		url      string
+		wantAuth bool
+	}{
+		{"served by the api", storage.URL, "/artifacts/model.bin", true},
+		{"absolute url on the api", storage.URL, storage.URL + "/artifacts/model.bin", true},
+		{"external storage", storage.URL, external.URL + "/model.bin", false},
+		{"same host over plain http", "https://" + host, storage.URL + "/model.bin", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authorization = ""
+			c, err := client.New(client.WithBaseURL(tt.baseURL), client.WithTokenSource(client.StaticTokenSource("secret")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if _, err := c.Training.DownloadArtifact(context.Background(), client.TrainingArtifact{URL: tt.url}, &out); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != "weights" {
+				t.Errorf("downloaded %q", out.String())
+			}
+			if sent := authorization != ""; sent != tt.wantAuth {
+				t.Errorf("sent credentials %q, want them sent: %v", authorization, tt.wantAuth)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+const (
+	uploadDatasetPath = "/api/v1/sentinel/sessions/upload/dataset"
+	uploadSensoryPath = "/api/v1/sentinel/sessions/upload/sensory"
+)
+
+type (
+	DatasetsService       struct{ service }
+	SensoryService        struct{ service }
+	UploadDatasetResponse struct {
+		ResponseCode    string `json:"success"`
+		ResponseMessage string `json:"messages"`
+		Data            struct {
+			DatasetID string `json:"dataset_id"`
+		} `json:"data"`
+	}
+	UploadSensoryResponse struct {
+		ResponseCode    string `json:"success"`
+		ResponseMessage string `json:"messages"`
+		Data            struct {
+			SensoryID string `json:"sensory_id"`
+		} `json:"data"`
+	}
+)
+
+// Upload sends content as it is read. When content is an io.Seeker a
+// throttled upload is replayed from where content started, other readers are
+// not retried.
+func (s *DatasetsService) Upload(ctx context.Context, fileName string, content io.Reader, opts ...UploadOption) (*UploadDatasetResponse, error) {
+	var uploadResp UploadDatasetResponse
+	if err := s.client.uploadFile(ctx, uploadDatasetPath, fileName, content, rewinder(content), &uploadResp, opts...); err != nil {
+		return nil, err
+	}
+	return &uploadResp, nil
+}
+
+// Encodings lists the content encodings the server accepts for dataset
+// uploads, empty when it accepts only uncompressed bodies.
+func (s *DatasetsService) Encodings(ctx context.Context) ([]string, error) {
+	return s.client.encodings(ctx, uploadDatasetPath)
+}
+
+// UploadStream sends content as it is read, for content produced on the fly
+// such as a packed directory. A streamed upload cannot be replayed, so it is
+// never retried.
+func (s *DatasetsService) UploadStream(ctx context.Context, fileName string, content io.Reader, opts ...UploadOption) (*UploadDatasetResponse, error) {
+	var uploadResp UploadDatasetResponse
+	if err := s.client.uploadFile(ctx, uploadDatasetPath, fileName, content, nil, &uploadResp, opts...); err != nil {
+		return nil, err
+	}
+	return &uploadResp, nil
+}
+
+// UploadFile streams the file at path, reopening it when a throttled upload
+// is replayed.
+func (s *DatasetsService) UploadFile(ctx context.Context, path string, opts ...UploadOption) (*UploadDatasetResponse, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	var uploadResp UploadDatasetResponse
+	if err := s.client.uploadFile(ctx, uploadDatasetPath, filepath.Base(path), file, reopener(path), &uploadResp, opts...); err != nil {
+		return nil, err
+	}
+	return &uploadResp, nil
+}
+
+// Upload sends content as it is read, like DatasetsService.Upload.
+func (s *SensoryService) Upload(ctx context.Context, fileName string, content io.Reader, opts ...UploadOption) (*UploadSensoryResponse, error) {
+	var uploadResp UploadSensoryResponse
+	if err := s.client.uploadFile(ctx, uploadSensoryPath, fileName, content, rewinder(content), &uploadResp, opts...); err != nil {
+		return nil, err
+	}
+	return &uploadResp, nil
+}
+
+// Encodings lists the content encodings the server accepts for sensory
+// uploads.
+func (s *SensoryService) Encodings(ctx context.Context) ([]string, error) {
+	return s.client.encodings(ctx, uploadSensoryPath)
+}
+
+func (s *SensoryService) UploadFile(ctx context.Context, path string, opts ...UploadOption) (*UploadSensoryResponse, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	var uploadResp UploadSensoryResponse
+	if err := s.client.uploadFile(ctx, uploadSensoryPath, filepath.Base(path), file, reopener(path), &uploadResp, opts...); err != nil {
+		return nil, err
+	}
+	return &uploadResp, nil
+}
+
+// reopener replays an upload from the file at path.
+func reopener(path string) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+}
+
+// rewinder replays an upload by seeking content back to where it started,
+// nil when content cannot seek.
+func rewinder(content io.Reader) func() (io.ReadCloser, error) {
+	seeker, ok := content.(io.Seeker)
+	if !ok {
+		return nil
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil
+	}
+	return func() (io.ReadCloser, error) {
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(content), nil
+	}
+}
+
+// uploadForm writes the attempts of an upload as multipart forms into
+// pipes, one attempt at a time.
+type uploadForm struct {
+	fileName string
+	encoding string
+	boundary string
+	pipe     *io.PipeReader
+	done     chan struct{}
+}
+
+// send starts writing a form with content as its "file" field, compressed
+// on the way when an encoding is set, and returns the body to send.
+func (f *uploadForm) send(content io.ReadCloser) io.ReadCloser {
+	reader, pipe := io.Pipe()
+	done := make(chan struct{})
+	f.pipe, f.done = reader, done
+	go func() {
+		defer close(done)
+		defer content.Close()
+		body, closeBody := encodeBody(pipe, f.encoding)
+		writer := multipart.NewWriter(body)
+		err := writer.SetBoundary(f.boundary)
+		if err == nil {
+			var formFile io.Writer
+			if formFile, err = writer.CreateFormFile("file", f.fileName); err == nil {
+				_, err = io.Copy(formFile, content)
+			}
+		}
+		if err == nil {
+			err = writer.Close()
+		}
+		if err == nil {
+			err = closeBody()
+		}
+		pipe.CloseWithError(err)
+	}()
+	return reader
+}
+
+// stop ends the attempt in flight and waits until its content is no longer
+// read.
+func (f *uploadForm) stop() {
+	if f.pipe != nil {
+		f.pipe.CloseWithError(io.ErrClosedPipe)
+		<-f.done
+	}
+}
+
+// uploadFile sends content as the "file" field of a multipart form, written
+// through a pipe while the request is sent so that large files are never
+// held in memory. Throttled requests are replayed with the content reopen
+// returns, a nil reopen means the upload is not retried.
+func (c *Client) uploadFile(ctx context.Context, path, fileName string, content io.Reader, reopen func() (io.ReadCloser, error), v interface{}, opts ...UploadOption) error {
+	encoding, content, err := newUploadOptions(opts).resolve(content)
+	if err != nil {
+		return err
+	}
+	writer := multipart.NewWriter(io.Discard)
+	form := &uploadForm{fileName: fileName, encoding: encoding, boundary: writer.Boundary()}
+	defer form.stop()
+	req, err := c.newRequest(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return err
+	}
+	req.Body = form.send(io.NopCloser(content))
+	if reopen != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			form.stop()
+			content, err := reopen()
+			if err != nil {
+				return nil, err
+			}
+			return form.send(content), nil
+		}
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	if err := c.authorize(req); err != nil {
+		return err
+	}
+	return c.do(req, v)
+}
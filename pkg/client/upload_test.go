@@ -0,0 +1,137 @@
+package client_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/synxms/synexis/pkg/client"
+	"github.com/synxms/synexis/pkg/zstd"
+)
+
+type received struct {
+	encoding string
+	content  []byte
+}
+
+// newDecodingServer accepts gzip and zstd uploads and records the decoded
+// file of the last one.
+func newDecodingServer(t *testing.T, last *received) *client.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Accept-Encoding", "gzip, zstd")
+			return
+		}
+		last.encoding = r.Header.Get("Content-Encoding")
+		switch last.encoding {
+		case client.EncodingGzip:
+			decoded, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(decoded)
+		case client.EncodingZstd:
+			r.Body = io.NopCloser(zstd.NewReader(r.Body))
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		if last.content, err = io.ReadAll(file); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": "success", "data": map[string]string{"dataset_id": "d-1"}})
+	}))
+	t.Cleanup(server.Close)
+	c, err := client.New(client.WithBaseURL(server.URL), client.WithTokenSource(client.StaticTokenSource("token")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestUploadEncodings(t *testing.T) {
+	var last received
+	c := newDecodingServer(t, &last)
+	ctx := context.Background()
+	accepted, err := c.Datasets.Encodings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csv := []byte(strings.Repeat("id,label,value\n1,cat,0.5\n2,dog,0.25\n", 4000))
+	var gzipped bytes.Buffer
+	compressor := gzip.NewWriter(&gzipped)
+	_, _ = compressor.Write(csv)
+	_ = compressor.Close()
+
+	tests := []struct {
+		name    string
+		content []byte
+		opts    []client.UploadOption
+		want    string
+	}{
+		{"none", csv, nil, ""},
+		{"gzip", csv, []client.UploadOption{client.WithContentEncoding(client.EncodingGzip)}, client.EncodingGzip},
+		{"zstd", csv, []client.UploadOption{client.WithContentEncoding(client.EncodingZstd)}, client.EncodingZstd},
+		{"auto without accepted encodings", csv, []client.UploadOption{client.WithContentEncoding(client.EncodingAuto)}, client.EncodingGzip},
+		{"auto", csv, []client.UploadOption{client.WithContentEncoding(client.EncodingAuto), client.WithAcceptedEncodings(accepted)}, client.EncodingZstd},
+		{"auto compressed", gzipped.Bytes(), []client.UploadOption{client.WithContentEncoding(client.EncodingAuto), client.WithAcceptedEncodings(accepted)}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.csv")
+			if err := os.WriteFile(path, tt.content, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			uploads := map[string]func() error{
+				"UploadFile": func() error {
+					_, err := c.Datasets.UploadFile(ctx, path, tt.opts...)
+					return err
+				},
+				"Upload": func() error {
+					_, err := c.Datasets.Upload(ctx, "data.csv", bytes.NewReader(tt.content), tt.opts...)
+					return err
+				},
+				"UploadStream": func() error {
+					_, err := c.Datasets.UploadStream(ctx, "data.csv", io.MultiReader(bytes.NewReader(tt.content)), tt.opts...)
+					return err
+				},
+			}
+			for method, upload := range uploads {
+				last = received{}
+				if err := upload(); err != nil {
+					t.Fatalf("%s: %v", method, err)
+				}
+				if last.encoding != tt.want {
+					t.Errorf("%s: sent Content-Encoding %q, want %q", method, last.encoding, tt.want)
+				}
+				if !bytes.Equal(last.content, tt.content) {
+					t.Errorf("%s: server decoded %d bytes that differ from the %d uploaded", method, len(last.content), len(tt.content))
+				}
+			}
+		})
+	}
+}
+
+func TestUploadUnknownEncoding(t *testing.T) {
+	var last received
+	c := newDecodingServer(t, &last)
+	_, err := c.Datasets.Upload(context.Background(), "data.csv", strings.NewReader("x"), client.WithContentEncoding("br"))
+	if !errors.Is(err, client.ErrUnsupportedEncoding) {
+		t.Fatalf("got %v, want ErrUnsupportedEncoding", err)
+	}
+}
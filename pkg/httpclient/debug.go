@@ -0,0 +1,200 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	redacted        = "[REDACTED]"
+	maxLoggedBody   = 16 << 10
+	debugTimeFormat = "15:04:05.000"
+)
+
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+}
+
+var sensitiveFieldMarkers = []string{"token", "access", "refresh", "apikey", "api_key", "secret", "password", "validationlayer"}
+
+type debugTransport struct {
+	next      http.RoundTripper
+	out       io.Writer
+	logBodies bool
+	trace     *TraceRecorder
+}
+
+// NewDebugTransport logs every request and response to out and, when trace is
+// not nil, records them as HAR entries. Credentials are always redacted.
+func NewDebugTransport(next http.RoundTripper, out io.Writer, logBodies bool, trace *TraceRecorder) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &debugTransport{next: next, out: out, logBodies: logBodies, trace: trace}
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	started := time.Now()
+	requestBody := peekRequestBody(req)
+	if t.out != nil {
+		fmt.Fprintf(t.out, "[%s] > %s %s\n", started.Format(debugTimeFormat), req.Method, redactURL(req.URL))
+		writeHeaders(t.out, "> ", req.Header)
+		if t.logBodies && requestBody != "" {
+			fmt.Fprintf(t.out, "> %s\n", requestBody)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(started)
+	if err != nil {
+		if t.out != nil {
+			fmt.Fprintf(t.out, "[%s] < error after %s: %v\n", time.Now().Format(debugTimeFormat), elapsed.Round(time.Millisecond), err)
+		}
+		t.trace.record(req, requestBody, nil, "", started, elapsed)
+		return resp, err
+	}
+
+	responseBody := peekResponseBody(resp)
+	if t.out != nil {
+		fmt.Fprintf(t.out, "[%s] < %s in %s\n", time.Now().Format(debugTimeFormat), resp.Status, elapsed.Round(time.Millisecond))
+		writeHeaders(t.out, "< ", resp.Header)
+		if t.logBodies && responseBody != "" {
+			fmt.Fprintf(t.out, "< %s\n", responseBody)
+		}
+	}
+	t.trace.record(req, requestBody, resp, responseBody, started, elapsed)
+	return resp, nil
+}
+
+func writeHeaders(out io.Writer, prefix string, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			fmt.Fprintf(out, "%s%s: %s\n", prefix, name, redactHeader(name, value))
+		}
+	}
+}
+
+func redactHeader(name, value string) string {
+	if !sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+		return value
+	}
+	if scheme, _, ok := strings.Cut(value, " "); ok && strings.EqualFold(http.CanonicalHeaderKey(name), "Authorization") {
+		return scheme + " " + redacted
+	}
+	return redacted
+}
+
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	clone := *u
+	if clone.User != nil {
+		clone.User = url.User(redacted)
+	}
+	query := clone.Query()
+	for key := range query {
+		if isSensitiveField(key) {
+			query.Set(key, redacted)
+		}
+	}
+	clone.RawQuery = query.Encode()
+	return clone.String()
+}
+
+func isSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, marker := range sensitiveFieldMarkers {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// peekRequestBody returns the redacted body of small JSON requests without
+// consuming it, relying on GetBody to obtain an independent copy.
+func peekRequestBody(req *http.Request) string {
+	if req.GetBody == nil || req.ContentLength <= 0 || req.ContentLength > maxLoggedBody || !isJSON(req.Header.Get("Content-Type")) {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	raw, err := io.ReadAll(io.LimitReader(body, maxLoggedBody))
+	if err != nil {
+		return ""
+	}
+	return redactJSON(raw)
+}
+
+// peekResponseBody reads a small JSON response into memory and puts it back
+// so the caller still sees the full body.
+func peekResponseBody(resp *http.Response) string {
+	if resp.ContentLength > maxLoggedBody || !isJSON(resp.Header.Get("Content-Type")) {
+		return ""
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxLoggedBody+1))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(raw), resp.Body), resp.Body}
+	if err != nil || len(raw) > maxLoggedBody {
+		return ""
+	}
+	return redactJSON(raw)
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+func redactJSON(raw []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return ""
+	}
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return ""
+	}
+	return string(redacted)
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if isSensitiveField(key) {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redactValue(inner)
+		}
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = redactValue(inner)
+		}
+	}
+	return value
+}
@@ -0,0 +1,168 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const secret = "s3cr3t-value"
+
+func TestRedactHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"Authorization", "Bearer " + secret, "Bearer " + redacted},
+		{"authorization", "ApiKey " + secret, "ApiKey " + redacted},
+		{"Authorization", secret, redacted},
+		{"Proxy-Authorization", "Basic " + secret, redacted},
+		{"Cookie", "session=" + secret, redacted},
+		{"Set-Cookie", "session=" + secret + "; HttpOnly", redacted},
+		{"X-Api-Key", secret, redacted},
+		{"x-api-key", secret, redacted},
+		{"Content-Type", "application/json", "application/json"},
+		{"X-Request-Id", "req-1", "req-1"},
+	}
+	for _, tt := range tests {
+		if got := redactHeader(tt.name, tt.value); got != tt.want {
+			t.Errorf("redactHeader(%q, %q) = %q, want %q", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"top level tokens", `{"accessToken":"` + secret + `","refresh_token":"` + secret + `","email":"a@b.c"}`, `{"accessToken":"[REDACTED]","email":"a@b.c","refresh_token":"[REDACTED]"}`},
+		{"nested object", `{"data":{"token":{"value":"` + secret + `"},"name":"ci"}}`, `{"data":{"name":"ci","token":"[REDACTED]"}}`},
+		{"objects in arrays", `{"data":[{"apiKey":"` + secret + `","prefix":"SYX"},{"ValidationLayerOne":"` + secret + `"}]}`, `{"data":[{"apiKey":"[REDACTED]","prefix":"SYX"},{"ValidationLayerOne":"[REDACTED]"}]}`},
+		{"passwords and secrets", `{"client_secret":"` + secret + `","Password":"` + secret + `"}`, `{"Password":"[REDACTED]","client_secret":"[REDACTED]"}`},
+		{"nothing sensitive", `{"success":"00","messages":"ok"}`, `{"messages":"ok","success":"00"}`},
+		{"not json", `token=` + secret, ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactJSON([]byte(tt.body)); got != tt.want {
+				t.Errorf("redactJSON(%s) = %s, want %s", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	u, _ := url.Parse("https://user:" + secret + "@synexis.example/api?access_token=" + secret + "&page=2")
+	got := redactURL(u)
+	if strings.Contains(got, secret) || !strings.Contains(got, "page=2") || !strings.Contains(got, "synexis.example/api") {
+		t.Errorf("redactURL = %s", got)
+	}
+}
+
+// newDebugServer answers with a session cookie and a JSON body holding a
+// token, echoing nothing of the request.
+func newDebugServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session="+secret)
+		_, _ = io.WriteString(w, `{"success":"00","data":{"accessToken":"`+secret+`","email":"mock@synexis.test"}}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newDebugRequest(t *testing.T, serverURL string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, serverURL+"/token?refresh_token="+secret, bytes.NewReader([]byte(`{"refreshToken":"`+secret+`","profile":"ci"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+secret)
+	req.Header.Set("Cookie", "session="+secret)
+	req.Header.Set("X-Api-Key", secret)
+	return req
+}
+
+func TestDebugTransport(t *testing.T) {
+	server := newDebugServer(t)
+	var out bytes.Buffer
+	client := &http.Client{Transport: NewDebugTransport(nil, &out, true, nil)}
+	resp, err := client.Do(newDebugRequest(t, server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), secret) {
+		t.Errorf("caller got body %s, want it untouched by logging", body)
+	}
+	logged := out.String()
+	if strings.Contains(logged, secret) {
+		t.Errorf("debug log leaks a credential:\n%s", logged)
+	}
+	for _, want := range []string{"> Authorization: Bearer [REDACTED]", "> Cookie: [REDACTED]", "> X-Api-Key: [REDACTED]", "< Set-Cookie: [REDACTED]", `"profile":"ci"`, `"email":"mock@synexis.test"`} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("debug log lacks %q:\n%s", want, logged)
+		}
+	}
+}
+
+func TestTraceRecorder(t *testing.T) {
+	server := newDebugServer(t)
+	path := filepath.Join(t.TempDir(), "trace.har")
+	client := &http.Client{Transport: NewDebugTransport(nil, nil, false, NewTraceRecorder(path))}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Do(newDebugRequest(t, server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), secret) {
+		t.Errorf("trace leaks a credential:\n%s", raw)
+	}
+	var har harDocument
+	if err := json.Unmarshal(raw, &har); err != nil {
+		t.Fatal(err)
+	}
+	if har.Log.Version != "1.2" || har.Log.Creator.Name != "synexis" || len(har.Log.Entries) != 2 {
+		t.Fatalf("trace has version %q by %q with %d entries, want 1.2 by synexis with 2", har.Log.Version, har.Log.Creator.Name, len(har.Log.Entries))
+	}
+	entry := har.Log.Entries[0]
+	if entry.Request.Method != http.MethodPost || entry.Response.Status != http.StatusOK {
+		t.Errorf("entry records %s answered with %d", entry.Request.Method, entry.Response.Status)
+	}
+	if entry.Request.PostData == nil || entry.Request.PostData.Text != `{"profile":"ci","refreshToken":"[REDACTED]"}` {
+		t.Errorf("entry records post data %+v", entry.Request.PostData)
+	}
+	if entry.Response.Content.Text != `{"data":{"accessToken":"[REDACTED]","email":"mock@synexis.test"},"success":"00"}` {
+		t.Errorf("entry records response %s", entry.Response.Content.Text)
+	}
+	if len(entry.Request.QueryString) != 1 || entry.Request.QueryString[0] != (harNameValue{Name: "refresh_token", Value: redacted}) {
+		t.Errorf("entry records query %+v", entry.Request.QueryString)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("trace file mode %v, want 0600", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,103 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+type Config struct {
+	ConnectTimeout     time.Duration
+	ReadTimeout        time.Duration
+	Timeout            time.Duration
+	MaxRetries         int
+	CACertFile         string
+	ClientCertFile     string
+	ClientKeyFile      string
+	InsecureSkipVerify bool
+	DebugOutput        io.Writer
+	DebugBodies        bool
+	TraceFile          string
+}
+
+func DefaultConfig() Config {
+	return Config{
+		ConnectTimeout: 15 * time.Second,
+		ReadTimeout:    2 * time.Minute,
+		MaxRetries:     3,
+	}
+}
+
+// New builds the client shared by every service call. Proxies are taken
+// from HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+func New(cfg Config) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{
+		Timeout:   cfg.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   cfg.ConnectTimeout,
+		ResponseHeaderTimeout: cfg.ReadTimeout,
+		ExpectContinueTimeout: time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConns:          10,
+		ForceAttemptHTTP2:     true,
+	}
+	var roundTripper http.RoundTripper = transport
+	if cfg.DebugOutput != nil || cfg.TraceFile != "" {
+		var trace *TraceRecorder
+		if cfg.TraceFile != "" {
+			trace = NewTraceRecorder(cfg.TraceFile)
+		}
+		roundTripper = NewDebugTransport(roundTripper, cfg.DebugOutput, cfg.DebugBodies, trace)
+	}
+	return &http.Client{
+		Transport: NewRetryTransport(roundTripper, cfg.MaxRetries),
+		Timeout:   cfg.Timeout,
+	}, nil
+}
+
+func newTLSConfig(cfg Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CACertFile != "" {
+		pem, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca cert: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.ClientCertFile != "" || cfg.ClientKeyFile != "" {
+		if cfg.ClientCertFile == "" || cfg.ClientKeyFile == "" {
+			return nil, errors.New("client certificate and client key must be provided together")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
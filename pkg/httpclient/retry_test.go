@@ -0,0 +1,169 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedServer answers with statuses in turn, repeating the last one, and
+// records the body of every attempt.
+type scriptedServer struct {
+	mu         sync.Mutex
+	statuses   []int
+	retryAfter string
+	bodies     []string
+}
+
+func (s *scriptedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.statuses[min(len(s.bodies), len(s.statuses)-1)]
+	s.bodies = append(s.bodies, string(body))
+	if s.retryAfter != "" && status != http.StatusOK {
+		w.Header().Set("Retry-After", s.retryAfter)
+	}
+	w.WriteHeader(status)
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		body         io.Reader
+		statuses     []int
+		retryAfter   string
+		maxRetries   int
+		wantStatus   int
+		wantAttempts int
+	}{
+		{"get recovers after 503", http.MethodGet, nil, []int{503, 200}, "0", 3, 200, 2},
+		{"get retries gateway errors", http.MethodGet, nil, []int{502, 504, 200}, "0", 3, 200, 3},
+		{"get stops at the limit", http.MethodGet, nil, []int{503}, "0", 3, 503, 4},
+		{"no retries configured", http.MethodGet, nil, []int{503}, "0", 0, 503, 1},
+		{"get is not retried on 500", http.MethodGet, nil, []int{500, 200}, "0", 3, 500, 1},
+		{"post replays its body on 429", http.MethodPost, strings.NewReader("payload"), []int{429, 429, 200}, "0", 3, 200, 3},
+		{"post is retried on 503 with retry-after", http.MethodPost, bytes.NewReader([]byte("payload")), []int{503, 200}, "0", 3, 200, 2},
+		{"post is not retried on 503 alone", http.MethodPost, strings.NewReader("payload"), []int{503, 200}, "", 3, 503, 1},
+		{"post is not retried on 502", http.MethodPost, strings.NewReader("payload"), []int{502, 200}, "0", 3, 502, 1},
+		{"post without GetBody is not replayed", http.MethodPost, io.MultiReader(strings.NewReader("payload")), []int{429, 200}, "0", 3, 429, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := &scriptedServer{statuses: tt.statuses, retryAfter: tt.retryAfter}
+			server := httptest.NewServer(script)
+			defer server.Close()
+			req, err := http.NewRequest(tt.method, server.URL, tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &http.Client{Transport: NewRetryTransport(nil, tt.maxRetries)}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus || len(script.bodies) != tt.wantAttempts {
+				t.Errorf("got %d after %d attempts, want %d after %d", resp.StatusCode, len(script.bodies), tt.wantStatus, tt.wantAttempts)
+			}
+			if tt.body != nil {
+				for i, body := range script.bodies {
+					if body != "payload" {
+						t.Errorf("attempt %d sent body %q", i+1, body)
+					}
+				}
+			}
+		})
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryTransportErrors(t *testing.T) {
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		attempts := 0
+		failing := roundTripFunc(func(*http.Request) (*http.Response, error) {
+			attempts++
+			return nil, errors.New("connection reset")
+		})
+		req, _ := http.NewRequest(method, "http://synexis.invalid", http.NoBody)
+		if _, err := NewRetryTransport(failing, 1).RoundTrip(req); err == nil {
+			t.Errorf("%s: transport error was swallowed", method)
+		}
+		want := 1
+		if method == http.MethodGet {
+			want = 2
+		}
+		if attempts != want {
+			t.Errorf("%s: %d attempts after a transport error, want %d", method, attempts, want)
+		}
+	}
+}
+
+func TestRetryTransportCancelledWait(t *testing.T) {
+	server := httptest.NewServer(&scriptedServer{statuses: []int{503}, retryAfter: "30"})
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	start := time.Now()
+	if _, err := NewRetryTransport(nil, 3).RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("waited %s for Retry-After after the context ended", elapsed)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 0; attempt < 70; attempt++ {
+		ceiling := retryBaseDelay << attempt
+		if ceiling <= 0 || ceiling > retryMaxDelay {
+			ceiling = retryMaxDelay
+		}
+		for i := 0; i < 20; i++ {
+			if delay := backoff(attempt); delay <= 0 || delay > ceiling {
+				t.Fatalf("attempt %d waits %s, want within (0, %s]", attempt, delay, ceiling)
+			}
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"7", 7 * time.Second, true},
+		{"3600", retryMaxDelay, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+		{time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), retryMaxDelay, true},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %s, %v, want %s, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+	date := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	if got, ok := parseRetryAfter(date); !ok || got <= 8*time.Second || got > 10*time.Second {
+		t.Errorf("parseRetryAfter(%q) = %s, %v, want about 10s", date, got, ok)
+	}
+}
@@ -0,0 +1,160 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+type (
+	// TraceRecorder keeps a HAR 1.2 log of the exchanged requests and
+	// rewrites its file after every entry, so the trace survives even when
+	// the command exits early.
+	TraceRecorder struct {
+		mu   sync.Mutex
+		path string
+		har  harDocument
+	}
+	harDocument struct {
+		Log harLog `json:"log"`
+	}
+	harLog struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	}
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	harEntry struct {
+		StartedDateTime string      `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+	}
+	harRequest struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		HTTPVersion string         `json:"httpVersion"`
+		Headers     []harNameValue `json:"headers"`
+		QueryString []harNameValue `json:"queryString"`
+		PostData    *harPostData   `json:"postData,omitempty"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int64          `json:"bodySize"`
+	}
+	harResponse struct {
+		Status      int            `json:"status"`
+		StatusText  string         `json:"statusText"`
+		HTTPVersion string         `json:"httpVersion"`
+		Headers     []harNameValue `json:"headers"`
+		Content     harContent     `json:"content"`
+		RedirectURL string         `json:"redirectURL"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int64          `json:"bodySize"`
+	}
+	harNameValue struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	harPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	}
+	harContent struct {
+		Size     int64  `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+	}
+	harTimings struct {
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	}
+)
+
+func NewTraceRecorder(path string) *TraceRecorder {
+	version := "devel"
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		version = info.Main.Version
+	}
+	return &TraceRecorder{
+		path: path,
+		har: harDocument{Log: harLog{
+			Version: "1.2",
+			Creator: harCreator{Name: "synexis", Version: version},
+			Entries: []harEntry{},
+		}},
+	}
+}
+
+func (r *TraceRecorder) record(req *http.Request, requestBody string, resp *http.Response, responseBody string, started time.Time, elapsed time.Duration) {
+	if r == nil {
+		return
+	}
+	millis := float64(elapsed.Microseconds()) / 1000
+	entry := harEntry{
+		StartedDateTime: started.Format(time.RFC3339Nano),
+		Time:            millis,
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         redactURL(req.URL),
+			HTTPVersion: req.Proto,
+			Headers:     harHeaders(req.Header),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    req.ContentLength,
+		},
+		Response: harResponse{
+			HeadersSize: -1,
+			BodySize:    -1,
+			Headers:     []harNameValue{},
+		},
+		Timings: harTimings{Wait: millis},
+	}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			if isSensitiveField(name) {
+				value = redacted
+			}
+			entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: name, Value: value})
+		}
+	}
+	if requestBody != "" {
+		entry.Request.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: requestBody}
+	}
+	if resp != nil {
+		entry.Response.Status = resp.StatusCode
+		entry.Response.StatusText = http.StatusText(resp.StatusCode)
+		entry.Response.HTTPVersion = resp.Proto
+		entry.Response.Headers = harHeaders(resp.Header)
+		entry.Response.BodySize = resp.ContentLength
+		entry.Response.Content = harContent{
+			Size:     resp.ContentLength,
+			MimeType: resp.Header.Get("Content-Type"),
+			Text:     responseBody,
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.har.Log.Entries = append(r.har.Log.Entries, entry)
+	if raw, err := json.MarshalIndent(r.har, "", "  "); err == nil {
+		_ = os.WriteFile(r.path, raw, 0600)
+	}
+}
+
+func harHeaders(header http.Header) []harNameValue {
+	headers := []harNameValue{}
+	for name, values := range header {
+		for _, value := range values {
+			headers = append(headers, harNameValue{Name: name, Value: redactHeader(name, value)})
+		}
+	}
+	return headers
+}
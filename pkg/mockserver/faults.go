@@ -0,0 +1,138 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/synxms/synexis/pkg/client"
+)
+
+// Faults makes the server misbehave on a share of API requests. Rates are
+// between 0 and 1, and PathPrefix limits injection to matching endpoints.
+type Faults struct {
+	Latency      time.Duration `json:"latency"`
+	ErrorRate    float64       `json:"errorRate"`
+	ErrorStatus  int           `json:"errorStatus"`
+	TruncateRate float64       `json:"truncateRate"`
+	PathPrefix   string        `json:"pathPrefix,omitempty"`
+}
+
+// SetFaults replaces the active faults, the zero value turns injection off.
+func (s *Server) SetFaults(faults Faults) {
+	s.mu.Lock()
+	s.faults = faults
+	s.mu.Unlock()
+}
+
+func (s *Server) Faults() Faults {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.faults
+}
+
+// injectFault applies the active faults to r and reports whether it already
+// answered the request.
+func (s *Server) injectFault(w *statusRecorder, r *http.Request) bool {
+	faults := s.Faults()
+	if !strings.HasPrefix(r.URL.Path, faults.PathPrefix) {
+		return false
+	}
+	if faults.Latency > 0 {
+		select {
+		case <-time.After(faults.Latency):
+		case <-r.Context().Done():
+			return true
+		}
+	}
+	if faults.ErrorRate > 0 && rand.Float64() < faults.ErrorRate {
+		status := faults.ErrorStatus
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+			w.Header().Set("Retry-After", "1")
+		}
+		writeJSON(w, status, envelope{ResponseCode: responseCodeFailed, ResponseMessage: "injected fault"})
+		return true
+	}
+	if faults.TruncateRate > 0 && rand.Float64() < faults.TruncateRate {
+		recorder := httptest.NewRecorder()
+		s.mux.ServeHTTP(recorder, r)
+		for name, values := range recorder.Header() {
+			w.Header()[name] = values
+		}
+		body := recorder.Body.Bytes()
+		w.WriteHeader(recorder.Code)
+		_, _ = w.Write(body[:len(body)/2])
+		return true
+	}
+	return false
+}
+
+// handleFaults lets tests and developers change faults on a running server:
+// GET shows them, PUT replaces them, DELETE clears them. Latency is given as
+// a duration string such as "250ms".
+func (s *Server) handleFaults(w http.ResponseWriter, r *http.Request) {
+	type faultsDocument struct {
+		Faults
+		Latency string `json:"latency"`
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var document faultsDocument
+		if err := json.NewDecoder(r.Body).Decode(&document); err != nil {
+			http.Error(w, "invalid faults document: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if document.Latency != "" {
+			latency, err := time.ParseDuration(document.Latency)
+			if err != nil {
+				http.Error(w, "invalid latency: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			document.Faults.Latency = latency
+		}
+		s.SetFaults(document.Faults)
+	case http.MethodDelete:
+		s.SetFaults(Faults{})
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	faults := s.Faults()
+	writeJSON(w, http.StatusOK, faultsDocument{Faults: faults, Latency: faults.Latency.String()})
+}
+
+// envelope is the response layout of the sentinel endpoints.
+type envelope struct {
+	ResponseCode    string      `json:"success"`
+	ResponseMessage string      `json:"messages"`
+	Data            interface{} `json:"data,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func sortRequests(requests []client.TrainingRequest) {
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].CreatedAt.After(requests[j].CreatedAt)
+	})
+}
@@ -0,0 +1,455 @@
+// Package mockserver is an in-memory stand-in for the Synexis API, meant for
+// local development and integration tests. Server is an http.Handler, so it
+// can be mounted on httptest.NewServer as well as on a real listener:
+//
+//	mock := mockserver.New()
+//	ts := httptest.NewServer(mock)
+//	defer ts.Close()
+//	access, refresh, err := mock.IssueTokens(ts.URL)
+//
+// Tokens are signed with a key generated at start-up and published on the
+// usual JWKS endpoint, so signature checks in the CLI pass against it.
+package mockserver
+
+import (
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/synxms/synexis/pkg/client"
+	"github.com/synxms/synexis/pkg/zstd"
+)
+
+const (
+	loginPath                 = "/api/v1/authentication/login"
+	refreshPath               = "/api/v1/authentication/refresh"
+	revokePath                = "/api/v1/authentication/logout"
+	mePath                    = "/api/v1/authentication/me"
+	createAPIKeyPath          = "/api/v1/authentication/create/apikey"
+	listAPIKeysPath           = "/api/v1/authentication/apikeys"
+	uploadDatasetPath         = "/api/v1/sentinel/sessions/upload/dataset"
+	uploadSensoryPath         = "/api/v1/sentinel/sessions/upload/sensory"
+	createTrainingRequestPath = "/api/v1/sentinel/sessions/create/request"
+	trainingRequestPath       = "/api/v1/sentinel/sessions/request/"
+	listTrainingRequestsPath  = "/api/v1/sentinel/sessions/requests"
+	listServicesPath          = "/api/v1/services"
+	jwksPath                  = "/.well-known/jwks.json"
+	authorizePath             = "/_mock/authorize"
+	faultsPath                = "/_mock/faults"
+
+	responseCodeSuccess = "00"
+	responseCodeFailed  = "01"
+	maxUploadSize       = 256 << 20
+)
+
+type (
+	Account struct {
+		UserID    string `json:"userId"`
+		Name      string `json:"name"`
+		Email     string `json:"email"`
+		CompanyID string `json:"companyId"`
+	}
+	Option func(*Server)
+	// Server holds all state in memory; nothing survives a restart.
+	Server struct {
+		mu         sync.Mutex
+		key        *ecdsa.PrivateKey
+		kid        string
+		account    Account
+		accessTTL  time.Duration
+		refreshTTL time.Duration
+		stepTime   time.Duration
+		now        func() time.Time
+		logf       func(format string, args ...interface{})
+		faults     Faults
+		encodings  []string
+		services   []client.ServiceInfo
+		mux        *http.ServeMux
+		sequence   int
+
+		refreshTokens map[string]bool
+		apiKeys       map[string]apiKey
+		datasets      map[string]Upload
+		sensory       map[string]Upload
+		requests      map[string]*client.TrainingRequest
+	}
+	apiKey struct {
+		Prefix             string
+		ValidationLayerOne string
+		ValidationLayerTwo string
+		CreatedAt          time.Time
+	}
+	// Upload describes a file received by the server. Size and SHA256 are
+	// of the decoded content.
+	Upload struct {
+		ID              string
+		Kind            string
+		FileName        string
+		Size            int64
+		SHA256          string
+		ContentEncoding string
+	}
+)
+
+// WithAccount sets the identity tokens are issued for.
+func WithAccount(account Account) Option {
+	return func(s *Server) {
+		s.account = account
+	}
+}
+
+// WithTokenLifetime sets how long issued access and refresh tokens last.
+func WithTokenLifetime(access, refresh time.Duration) Option {
+	return func(s *Server) {
+		s.accessTTL = access
+		s.refreshTTL = refresh
+	}
+}
+
+// WithTrainingStep sets how long a training request stays in each state
+// before moving on, so status polling can be exercised quickly.
+func WithTrainingStep(step time.Duration) Option {
+	return func(s *Server) {
+		s.stepTime = step
+	}
+}
+
+// WithClock replaces time.Now, for tests that need to move time forward.
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+// WithLogger receives one line per handled request.
+func WithLogger(logf func(format string, args ...interface{})) Option {
+	return func(s *Server) {
+		s.logf = logf
+	}
+}
+
+// WithFaults starts the server with fault injection already enabled.
+func WithFaults(faults Faults) Option {
+	return func(s *Server) {
+		s.faults = faults
+	}
+}
+
+// WithUploadEncodings sets the content encodings accepted for uploads, gzip
+// and zstd by default. Without any the server behaves like one that predates
+// compressed uploads.
+func WithUploadEncodings(encodings ...string) Option {
+	return func(s *Server) {
+		s.encodings = encodings
+	}
+}
+
+// WithServices sets what the service discovery endpoint lists, sentinel by
+// default. Without any the endpoint is not served, like on servers that
+// predate it, and clients have to probe.
+func WithServices(services ...client.ServiceInfo) Option {
+	return func(s *Server) {
+		s.services = services
+	}
+}
+
+func New(opts ...Option) *Server {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		// the system random source failing leaves nothing to fall back to
+		panic(fmt.Sprintf("mockserver: failed to generate signing key: %v", err))
+	}
+	s := &Server{
+		key: key,
+		kid: "mock-" + randomID(4),
+		account: Account{
+			UserID:    "user-mock",
+			Name:      "Mock User",
+			Email:     "mock@synexis.test",
+			CompanyID: "company-mock",
+		},
+		accessTTL:  15 * time.Minute,
+		refreshTTL: 7 * 24 * time.Hour,
+		stepTime:   5 * time.Second,
+		now:        time.Now,
+		logf:       func(string, ...interface{}) {},
+		encodings:  []string{client.EncodingGzip, client.EncodingZstd},
+		services: []client.ServiceInfo{{
+			Name:        "sentinel",
+			Version:     "v1",
+			Status:      client.ServiceStatusAvailable,
+			Description: "Custom model training",
+		}},
+		refreshTokens: map[string]bool{},
+		apiKeys:       map[string]apiKey{},
+		datasets:      map[string]Upload{},
+		sensory:       map[string]Upload{},
+		requests:      map[string]*client.TrainingRequest{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("POST "+loginPath, s.handleLogin)
+	s.mux.HandleFunc("POST "+refreshPath, s.handleRefresh)
+	s.mux.HandleFunc("POST "+revokePath, s.handleRevoke)
+	s.mux.HandleFunc("GET "+mePath, s.authenticated(s.handleMe))
+	s.mux.HandleFunc("POST "+createAPIKeyPath, s.authenticated(s.handleCreateAPIKey))
+	s.mux.HandleFunc("GET "+listAPIKeysPath, s.authenticated(s.handleListAPIKeys))
+	s.mux.HandleFunc("POST "+uploadDatasetPath, s.authenticated(s.handleUpload(s.datasets, "dataset")))
+	s.mux.HandleFunc("POST "+uploadSensoryPath, s.authenticated(s.handleUpload(s.sensory, "sensory")))
+	s.mux.HandleFunc("OPTIONS "+uploadDatasetPath, s.handleUploadOptions)
+	s.mux.HandleFunc("OPTIONS "+uploadSensoryPath, s.handleUploadOptions)
+	s.mux.HandleFunc("POST "+createTrainingRequestPath, s.authenticated(s.handleCreateRequest))
+	s.mux.HandleFunc("GET "+trainingRequestPath+"{id}", s.authenticated(s.handleGetRequest))
+	s.mux.HandleFunc("GET "+listTrainingRequestsPath, s.authenticated(s.handleListRequests))
+	s.mux.HandleFunc("GET "+trainingRequestPath+"{id}/logs", s.authenticated(s.handleRequestLogs))
+	s.mux.HandleFunc("POST "+trainingRequestPath+"{id}/cancel", s.authenticated(s.handleCancelRequest))
+	s.mux.HandleFunc("POST "+trainingRequestPath+"{id}/retry", s.authenticated(s.handleRetryRequest))
+	s.mux.HandleFunc("GET "+trainingRequestPath+"{id}/artifacts", s.authenticated(s.handleListArtifacts))
+	s.mux.HandleFunc("GET "+trainingRequestPath+"{id}/artifacts/{name}", s.authenticated(s.handleDownloadArtifact))
+	if len(s.services) > 0 {
+		s.mux.HandleFunc("GET "+listServicesPath, s.handleListServices)
+	}
+	s.mux.HandleFunc("GET "+jwksPath, s.handleJWKS)
+	s.mux.HandleFunc("GET "+authorizePath, s.handleAuthorize)
+	s.mux.HandleFunc(faultsPath, s.handleFaults)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	started := s.now()
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	if strings.HasPrefix(r.URL.Path, "/_mock/") || !s.injectFault(recorder, r) {
+		s.mux.ServeHTTP(recorder, r)
+	}
+	s.logf("%s %s %d %s", r.Method, r.URL.Path, recorder.status, s.now().Sub(started).Round(time.Millisecond))
+}
+
+// Requests returns a snapshot of every training request created so far.
+func (s *Server) Requests() []client.TrainingRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := make([]client.TrainingRequest, 0, len(s.requests))
+	for _, request := range s.requests {
+		s.advance(request)
+		requests = append(requests, *request)
+	}
+	sortRequests(requests)
+	return requests
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, client.LoginResponse{
+		ResponseCode:    responseCodeSuccess,
+		ResponseMessage: "success",
+		RedirectURL:     baseURL(r) + authorizePath,
+	})
+}
+
+// handleAuthorize plays the part of the browser sign-in and hands out a
+// token pair together with the commands that store it.
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	access, refresh, err := s.IssueTokens(baseURL(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "Signed in to the mock server as %s.\n\n", s.account.Email)
+	fmt.Fprintf(w, "synexis token set accesstoken %s\n", access)
+	fmt.Fprintf(w, "synexis token set refreshtoken %s\n", refresh)
+}
+
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.parseToken(bearerToken(r), tokenTypeRefresh)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, client.RefreshResponse{ResponseCode: responseCodeFailed, ResponseMessage: err.Error()})
+		return
+	}
+	jti, _ := claims["jti"].(string)
+	s.mu.Lock()
+	valid := s.refreshTokens[jti]
+	// refresh tokens rotate, each one can be exchanged once
+	delete(s.refreshTokens, jti)
+	s.mu.Unlock()
+	if !valid {
+		writeJSON(w, http.StatusUnauthorized, client.RefreshResponse{ResponseCode: responseCodeFailed, ResponseMessage: "refresh token was revoked or already used"})
+		return
+	}
+	access, refresh, err := s.IssueTokens(baseURL(r))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, client.RefreshResponse{ResponseCode: responseCodeFailed, ResponseMessage: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, client.RefreshResponse{
+		ResponseCode:    responseCodeSuccess,
+		ResponseMessage: "success",
+		Access:          access,
+		Refresh:         refresh,
+	})
+}
+
+func (s *Server) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.parseToken(bearerToken(r), tokenTypeRefresh)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, client.APIKeyResponse{ResponseCode: responseCodeFailed, ResponseMessage: err.Error()})
+		return
+	}
+	jti, _ := claims["jti"].(string)
+	s.mu.Lock()
+	delete(s.refreshTokens, jti)
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, client.APIKeyResponse{ResponseCode: responseCodeSuccess, ResponseMessage: "success"})
+}
+
+func (s *Server) handleMe(w http.ResponseWriter, _ *http.Request) {
+	var me client.MeResponse
+	me.ResponseCode = responseCodeSuccess
+	me.ResponseMessage = "success"
+	me.Data.UserID = s.account.UserID
+	me.Data.Name = s.account.Name
+	me.Data.Email = s.account.Email
+	me.Data.CompanyID = s.account.CompanyID
+	writeJSON(w, http.StatusOK, me)
+}
+
+func (s *Server) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var request client.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Prefix == "" {
+		writeJSON(w, http.StatusBadRequest, client.APIKeyResponse{ResponseCode: responseCodeFailed, ResponseMessage: "prefix and validation layers are required"})
+		return
+	}
+	s.mu.Lock()
+	s.apiKeys[request.Prefix] = apiKey{
+		Prefix:             request.Prefix,
+		ValidationLayerOne: request.ValidationLayerOne,
+		ValidationLayerTwo: request.ValidationLayerTwo,
+		CreatedAt:          s.now(),
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, client.APIKeyResponse{ResponseCode: responseCodeSuccess, ResponseMessage: "success"})
+}
+
+func (s *Server) handleListAPIKeys(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	keys := make([]client.APIKey, 0, len(s.apiKeys))
+	for _, key := range s.apiKeys {
+		keys = append(keys, client.APIKey{Prefix: key.Prefix, CreatedAt: key.CreatedAt})
+	}
+	s.mu.Unlock()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Prefix < keys[j].Prefix
+	})
+	writeJSON(w, http.StatusOK, client.ListAPIKeysResponse{ResponseCode: responseCodeSuccess, ResponseMessage: "success", Data: keys})
+}
+
+// handleUpload stores only the size and digest of the uploaded file, the
+// content is read and discarded.
+func (s *Server) handleUpload(uploads map[string]Upload, kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+		encoding := strings.ToLower(r.Header.Get("Content-Encoding"))
+		if encoding != "" && !slices.Contains(s.encodings, encoding) {
+			w.Header().Set("Accept-Encoding", strings.Join(s.encodings, ", "))
+			writeJSON(w, http.StatusUnsupportedMediaType, envelope{ResponseCode: responseCodeFailed, ResponseMessage: "unsupported content encoding " + encoding})
+			return
+		}
+		switch encoding {
+		case client.EncodingGzip:
+			decoded, err := gzip.NewReader(r.Body)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, envelope{ResponseCode: responseCodeFailed, ResponseMessage: "invalid gzip body"})
+				return
+			}
+			r.Body = http.MaxBytesReader(w, decoded, maxUploadSize)
+		case client.EncodingZstd:
+			r.Body = http.MaxBytesReader(w, io.NopCloser(zstd.NewReader(r.Body)), maxUploadSize)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{ResponseCode: responseCodeFailed, ResponseMessage: "multipart field \"file\" is required"})
+			return
+		}
+		defer file.Close()
+		hash := sha256.New()
+		size, err := io.Copy(hash, file)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{ResponseCode: responseCodeFailed, ResponseMessage: err.Error()})
+			return
+		}
+		s.mu.Lock()
+		id := s.nextID(kind)
+		uploads[id] = Upload{
+			ID:              id,
+			Kind:            kind,
+			FileName:        header.Filename,
+			Size:            size,
+			SHA256:          hex.EncodeToString(hash.Sum(nil)),
+			ContentEncoding: encoding,
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, envelope{
+			ResponseCode:    responseCodeSuccess,
+			ResponseMessage: "success",
+			Data:            map[string]string{kind + "_id": id},
+		})
+	}
+}
+
+func (s *Server) handleListServices(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, envelope{ResponseCode: responseCodeSuccess, ResponseMessage: "success", Data: s.services})
+}
+
+// handleUploadOptions advertises the accepted encodings the way RFC 7694
+// describes.
+func (s *Server) handleUploadOptions(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Allow", "OPTIONS, POST")
+	if len(s.encodings) > 0 {
+		w.Header().Set("Accept-Encoding", strings.Join(s.encodings, ", "))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Uploads returns every dataset and sensory upload received so far.
+func (s *Server) Uploads() []Upload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uploads := make([]Upload, 0, len(s.datasets)+len(s.sensory))
+	for _, upload := range s.datasets {
+		uploads = append(uploads, upload)
+	}
+	for _, upload := range s.sensory {
+		uploads = append(uploads, upload)
+	}
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].ID < uploads[j].ID })
+	return uploads
+}
+
+// nextID must be called with s.mu held.
+func (s *Server) nextID(kind string) string {
+	s.sequence++
+	return fmt.Sprintf("%s-%04d-%s", kind, s.sequence, randomID(3))
+}
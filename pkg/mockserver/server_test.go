@@ -0,0 +1,390 @@
+package mockserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/synxms/synexis/pkg/client"
+)
+
+// testClock is a clock tests move forward by hand.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func newTestServer(t *testing.T, opts ...Option) (*Server, *httptest.Server) {
+	t.Helper()
+	mock := New(opts...)
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+	return mock, server
+}
+
+// call sends a request with the given credential, a bearer token or, when it
+// has no dots, an API key, and returns the status and body.
+func call(t *testing.T, method, url, credential string, body io.Reader, header http.Header) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	switch {
+	case strings.Count(credential, ".") == 2:
+		req.Header.Set("Authorization", "Bearer "+credential)
+	case credential != "":
+		req.Header.Set("X-Api-Key", credential)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	return resp, string(raw)
+}
+
+func issueTokens(t *testing.T, mock *Server, server *httptest.Server) (string, string) {
+	t.Helper()
+	access, refresh, err := mock.IssueTokens(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return access, refresh
+}
+
+func TestAuthentication(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	mock, server := newTestServer(t, WithClock(clock.Now), WithTokenLifetime(time.Minute, time.Hour))
+	access, refresh := issueTokens(t, mock, server)
+
+	tests := []struct {
+		name       string
+		credential string
+		wantStatus int
+		wantBody   string
+	}{
+		{"access token", access, http.StatusOK, "mock@synexis.test"},
+		{"refresh token", refresh, http.StatusUnauthorized, "wrong token type"},
+		{"no credentials", "", http.StatusUnauthorized, "missing bearer token"},
+		{"forged token", access[:strings.LastIndex(access, ".")] + ".c2lnbmF0dXJl", http.StatusUnauthorized, "invalid token"},
+		{"unknown api key", "ci-one-two-company-mock", http.StatusUnauthorized, "invalid api key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := call(t, http.MethodGet, server.URL+mePath, tt.credential, nil, nil)
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(body, tt.wantBody) {
+				t.Errorf("got %d %s, want %d with %q", resp.StatusCode, body, tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+
+	clock.Add(2 * time.Minute)
+	if resp, body := call(t, http.MethodGet, server.URL+mePath, access, nil, nil); resp.StatusCode != http.StatusUnauthorized || !strings.Contains(body, "expired") {
+		t.Errorf("expired access token: got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestRefreshRotation(t *testing.T) {
+	mock, server := newTestServer(t)
+	_, refresh := issueTokens(t, mock, server)
+
+	resp, body := call(t, http.MethodPost, server.URL+refreshPath, refresh, nil, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("refresh: got %d %s", resp.StatusCode, body)
+	}
+	var rotated client.RefreshResponse
+	if err := json.Unmarshal([]byte(body), &rotated); err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := call(t, http.MethodGet, server.URL+mePath, rotated.Access, nil, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("refreshed access token rejected with %d", resp.StatusCode)
+	}
+	if resp, body := call(t, http.MethodPost, server.URL+refreshPath, refresh, nil, nil); resp.StatusCode != http.StatusUnauthorized || !strings.Contains(body, "already used") {
+		t.Errorf("reused refresh token: got %d %s", resp.StatusCode, body)
+	}
+
+	if resp, _ := call(t, http.MethodPost, server.URL+revokePath, rotated.Refresh, nil, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("revoke: got %d", resp.StatusCode)
+	}
+	if resp, _ := call(t, http.MethodPost, server.URL+refreshPath, rotated.Refresh, nil, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("revoked refresh token: got %d", resp.StatusCode)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	mock, server := newTestServer(t)
+	access, _ := issueTokens(t, mock, server)
+	key, _ := json.Marshal(client.CreateAPIKeyRequest{Prefix: "ci", ValidationLayerOne: "one", ValidationLayerTwo: "two"})
+	if resp, body := call(t, http.MethodPost, server.URL+createAPIKeyPath, access, bytes.NewReader(key), nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("create api key: got %d %s", resp.StatusCode, body)
+	}
+	if resp, body := call(t, http.MethodGet, server.URL+listAPIKeysPath, access, nil, nil); !strings.Contains(body, `"ci"`) {
+		t.Errorf("list api keys: got %d %s", resp.StatusCode, body)
+	}
+	for credential, want := range map[string]int{
+		"ci-one-two-company-mock":   http.StatusOK,
+		"ci-one-three-company-mock": http.StatusUnauthorized,
+		"ci-one-two-company-other":  http.StatusUnauthorized,
+		"ci-one-two":                http.StatusUnauthorized,
+	} {
+		if resp, _ := call(t, http.MethodGet, server.URL+mePath, credential, nil, nil); resp.StatusCode != want {
+			t.Errorf("api key %s: got %d, want %d", credential, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	mock, server := newTestServer(t)
+	access, _ := issueTokens(t, mock, server)
+	c, err := client.New(client.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := client.NewVerifier(c, memoryCache{}, client.VerifyOptions{Issuer: server.URL}).Verify(context.Background(), access)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["email"] != "mock@synexis.test" {
+		t.Errorf("verified claims %v", claims)
+	}
+}
+
+type memoryCache map[string]string
+
+func (c memoryCache) Get(key string) (string, error) {
+	return c[key], nil
+}
+
+func (c memoryCache) Set(key, value string) error {
+	c[key] = value
+	return nil
+}
+
+// multipartFile builds an upload body, gzip compressed when asked.
+func multipartFile(t *testing.T, name, content string, compress bool) (io.Reader, http.Header) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.WriteString(part, content)
+	_ = form.Close()
+	header := http.Header{"Content-Type": {form.FormDataContentType()}}
+	if !compress {
+		return &body, header
+	}
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write(body.Bytes())
+	_ = gz.Close()
+	header.Set("Content-Encoding", "gzip")
+	return &compressed, header
+}
+
+func TestUploads(t *testing.T) {
+	mock, server := newTestServer(t)
+	access, _ := issueTokens(t, mock, server)
+	for _, compress := range []bool{false, true} {
+		body, header := multipartFile(t, "train.csv", "x,y\n1,2\n", compress)
+		if resp, raw := call(t, http.MethodPost, server.URL+uploadDatasetPath, access, body, header); resp.StatusCode != http.StatusOK || !strings.Contains(raw, "dataset_id") {
+			t.Fatalf("upload: got %d %s", resp.StatusCode, raw)
+		}
+	}
+	uploads := mock.Uploads()
+	if len(uploads) != 2 {
+		t.Fatalf("server recorded %d uploads, want 2", len(uploads))
+	}
+	if uploads[0].SHA256 != uploads[1].SHA256 || uploads[0].Size != 8 || uploads[0].FileName != "train.csv" {
+		t.Errorf("uploads %+v, want the same decoded file twice", uploads)
+	}
+	if uploads[0].ContentEncoding != "" || uploads[1].ContentEncoding != "gzip" {
+		t.Errorf("uploads recorded encodings %q and %q", uploads[0].ContentEncoding, uploads[1].ContentEncoding)
+	}
+
+	resp, _ := call(t, http.MethodOptions, server.URL+uploadDatasetPath, "", nil, nil)
+	if resp.Header.Get("Accept-Encoding") != "gzip, zstd" {
+		t.Errorf("options advertised %q", resp.Header.Get("Accept-Encoding"))
+	}
+
+	legacy, legacyServer := newTestServer(t, WithUploadEncodings())
+	access, _ = issueTokens(t, legacy, legacyServer)
+	body, header := multipartFile(t, "train.csv", "x,y\n1,2\n", true)
+	if resp, raw := call(t, http.MethodPost, legacyServer.URL+uploadDatasetPath, access, body, header); resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("compressed upload to a server without encodings: got %d %s", resp.StatusCode, raw)
+	}
+}
+
+// createRequest uploads inputs and submits a training request for them.
+func createRequest(t *testing.T, server *httptest.Server, access, datasetName string) string {
+	t.Helper()
+	ids := map[string]string{}
+	for path, name := range map[string]string{uploadSensoryPath: "sensory.json", uploadDatasetPath: datasetName} {
+		body, header := multipartFile(t, name, "{}", false)
+		_, raw := call(t, http.MethodPost, server.URL+path, access, body, header)
+		var uploaded struct {
+			Data map[string]string `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(raw), &uploaded); err != nil {
+			t.Fatal(err)
+		}
+		for key, id := range uploaded.Data {
+			ids[key] = id
+		}
+	}
+	payload, _ := json.Marshal(ids)
+	_, raw := call(t, http.MethodPost, server.URL+createTrainingRequestPath, access, bytes.NewReader(payload), nil)
+	var created struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(raw), &created); err != nil || created.Data["request_id"] == "" {
+		t.Fatalf("create request answered %s", raw)
+	}
+	return created.Data["request_id"]
+}
+
+func requestStatus(t *testing.T, server *httptest.Server, access, id string) client.TrainingRequest {
+	t.Helper()
+	_, raw := call(t, http.MethodGet, server.URL+trainingRequestPath+id, access, nil, nil)
+	var got struct {
+		Data client.TrainingRequest `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(raw), &got); err != nil {
+		t.Fatal(err)
+	}
+	return got.Data
+}
+
+func TestTrainingLifecycle(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	mock, server := newTestServer(t, WithClock(clock.Now), WithTrainingStep(time.Minute))
+	access, _ := issueTokens(t, mock, server)
+	succeeding := createRequest(t, server, access, "train.csv")
+	failing := createRequest(t, server, access, "fail.csv")
+	cancelled := createRequest(t, server, access, "train.csv")
+
+	if status := requestStatus(t, server, access, succeeding).Status; status != client.TrainingStatusPending {
+		t.Errorf("new request is %s", status)
+	}
+	if resp, _ := call(t, http.MethodPost, server.URL+trainingRequestPath+cancelled+"/cancel", access, nil, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("cancel pending request: got %d", resp.StatusCode)
+	}
+	clock.Add(time.Minute)
+	if status := requestStatus(t, server, access, succeeding).Status; status != client.TrainingStatusRunning {
+		t.Errorf("request after one step is %s", status)
+	}
+	clock.Add(2 * time.Minute)
+	done := requestStatus(t, server, access, succeeding)
+	if done.Status != client.TrainingStatusSucceeded || done.Metrics["epoch"] != 2*epochsPerStep {
+		t.Errorf("request after three steps is %s with metrics %v", done.Status, done.Metrics)
+	}
+	if failed := requestStatus(t, server, access, failing); failed.Status != client.TrainingStatusFailed || failed.Message != "training diverged" {
+		t.Errorf("request on fail.csv is %s: %s", failed.Status, failed.Message)
+	}
+	if status := requestStatus(t, server, access, cancelled).Status; status != client.TrainingStatusCancelled {
+		t.Errorf("cancelled request is %s", status)
+	}
+
+	_, raw := call(t, http.MethodGet, server.URL+trainingRequestPath+succeeding+"/logs", access, nil, nil)
+	if !strings.Contains(raw, "request queued") || !strings.Contains(raw, "epoch 6") || !strings.Contains(raw, "training succeeded") {
+		t.Errorf("logs of the finished request: %s", raw)
+	}
+	_, raw = call(t, http.MethodGet, server.URL+trainingRequestPath+succeeding+"/artifacts", access, nil, nil)
+	if !strings.Contains(raw, "model.onnx") || !strings.Contains(raw, "metrics.json") {
+		t.Errorf("artifacts: %s", raw)
+	}
+	if _, model := call(t, http.MethodGet, server.URL+trainingRequestPath+succeeding+"/artifacts/model.onnx", access, nil, nil); model != "mock model for "+succeeding+"\n" {
+		t.Errorf("model artifact holds %q", model)
+	}
+	if resp, _ := call(t, http.MethodGet, server.URL+trainingRequestPath+failing+"/artifacts/model.onnx", access, nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("artifact of a failed request: got %d", resp.StatusCode)
+	}
+
+	if resp, _ := call(t, http.MethodPost, server.URL+trainingRequestPath+succeeding+"/cancel", access, nil, nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("cancel finished request: got %d", resp.StatusCode)
+	}
+	if resp, _ := call(t, http.MethodPost, server.URL+trainingRequestPath+succeeding+"/retry", access, nil, nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("retry succeeded request: got %d", resp.StatusCode)
+	}
+	if resp, raw := call(t, http.MethodPost, server.URL+trainingRequestPath+failing+"/retry", access, nil, nil); resp.StatusCode != http.StatusOK || strings.Contains(raw, failing) {
+		t.Errorf("retry failed request: got %d %s, want a new request id", resp.StatusCode, raw)
+	}
+	if resp, _ := call(t, http.MethodGet, server.URL+trainingRequestPath+"request-9999", access, nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown request: got %d", resp.StatusCode)
+	}
+	if got := len(mock.Requests()); got != 4 {
+		t.Errorf("server holds %d requests, want 4", got)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	mock, server := newTestServer(t)
+	access, _ := issueTokens(t, mock, server)
+
+	mock.SetFaults(Faults{ErrorRate: 1, ErrorStatus: http.StatusServiceUnavailable, PathPrefix: "/api/v1/authentication/me"})
+	resp, body := call(t, http.MethodGet, server.URL+mePath, access, nil, nil)
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" || !strings.Contains(body, "injected fault") {
+		t.Errorf("injected 503: got %d %v %s", resp.StatusCode, resp.Header, body)
+	}
+	if resp, _ := call(t, http.MethodGet, server.URL+listAPIKeysPath, access, nil, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("endpoint outside the prefix: got %d", resp.StatusCode)
+	}
+
+	mock.SetFaults(Faults{TruncateRate: 1})
+	if _, body := call(t, http.MethodGet, server.URL+mePath, access, nil, nil); json.Valid([]byte(body)) {
+		t.Errorf("truncated response is still valid json: %s", body)
+	}
+
+	resp, body = call(t, http.MethodPut, server.URL+faultsPath, "", strings.NewReader(`{"latency":"5ms","errorRate":0.5}`), nil)
+	if resp.StatusCode != http.StatusOK || mock.Faults() != (Faults{Latency: 5 * time.Millisecond, ErrorRate: 0.5}) {
+		t.Errorf("put faults: got %d %s, server has %+v", resp.StatusCode, body, mock.Faults())
+	}
+	if _, body := call(t, http.MethodGet, server.URL+faultsPath, "", nil, nil); !strings.Contains(body, `"latency":"5ms"`) {
+		t.Errorf("get faults: %s", body)
+	}
+	if resp, _ := call(t, http.MethodPut, server.URL+faultsPath, "", strings.NewReader(`{"latency":"soon"}`), nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid latency: got %d", resp.StatusCode)
+	}
+	call(t, http.MethodDelete, server.URL+faultsPath, "", nil, nil)
+	if mock.Faults() != (Faults{}) {
+		t.Errorf("faults left after delete: %+v", mock.Faults())
+	}
+}
+
+func TestServices(t *testing.T) {
+	_, server := newTestServer(t)
+	if resp, body := call(t, http.MethodGet, server.URL+listServicesPath, "", nil, nil); resp.StatusCode != http.StatusOK || !strings.Contains(body, "sentinel") {
+		t.Errorf("services: got %d %s", resp.StatusCode, body)
+	}
+	_, legacy := newTestServer(t, WithServices())
+	if resp, _ := call(t, http.MethodGet, legacy.URL+listServicesPath, "", nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("services on a server without discovery: got %d", resp.StatusCode)
+	}
+}
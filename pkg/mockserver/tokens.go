@@ -0,0 +1,139 @@
+package mockserver
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/synxms/synexis/pkg/client"
+)
+
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+// IssueTokens signs a new access and refresh token pair for the configured
+// account. issuer should be the base URL clients reach the server on.
+func (s *Server) IssueTokens(issuer string) (access, refresh string, err error) {
+	now := s.now()
+	access, err = s.sign(jwt.MapClaims{
+		"iss":       issuer,
+		"sub":       s.account.UserID,
+		"name":      s.account.Name,
+		"email":     s.account.Email,
+		"companyId": s.account.CompanyID,
+		"typ":       tokenTypeAccess,
+		"iat":       now.Unix(),
+		"exp":       now.Add(s.accessTTL).Unix(),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	jti := randomID(8)
+	refresh, err = s.sign(jwt.MapClaims{
+		"iss":       issuer,
+		"sub":       s.account.UserID,
+		"companyId": s.account.CompanyID,
+		"typ":       tokenTypeRefresh,
+		"jti":       jti,
+		"iat":       now.Unix(),
+		"exp":       now.Add(s.refreshTTL).Unix(),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	s.mu.Lock()
+	s.refreshTokens[jti] = true
+	s.mu.Unlock()
+	return access, refresh, nil
+}
+
+func (s *Server) sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = s.kid
+	return token.SignedString(s.key)
+}
+
+func (s *Server) parseToken(raw, tokenType string) (jwt.MapClaims, error) {
+	if raw == "" {
+		return nil, errors.New("missing bearer token")
+	}
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}))
+	// the clock may be replaced, so expiry is checked against it below
+	parser.SkipClaimsValidation = true
+	if _, err := parser.ParseWithClaims(raw, claims, func(*jwt.Token) (interface{}, error) {
+		return &s.key.PublicKey, nil
+	}); err != nil {
+		return nil, errors.New("invalid token")
+	}
+	if !claims.VerifyExpiresAt(s.now().Unix(), true) {
+		return nil, errors.New("token is expired")
+	}
+	if claims["typ"] != tokenType {
+		return nil, errors.New("wrong token type")
+	}
+	return claims, nil
+}
+
+// authenticated accepts an access token or an API key issued through the
+// create apikey endpoint.
+func (s *Server) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get("X-Api-Key"); key != "" {
+			if s.validAPIKey(key) {
+				next(w, r)
+				return
+			}
+			writeJSON(w, http.StatusUnauthorized, envelope{ResponseCode: responseCodeFailed, ResponseMessage: "invalid api key"})
+			return
+		}
+		if _, err := s.parseToken(bearerToken(r), tokenTypeAccess); err != nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{ResponseCode: responseCodeFailed, ResponseMessage: err.Error()})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// validAPIKey matches the layout the CLI prints, prefix-one-two-company.
+func (s *Server) validAPIKey(key string) bool {
+	parts := strings.SplitN(key, "-", 4)
+	if len(parts) != 4 {
+		return false
+	}
+	s.mu.Lock()
+	registered, ok := s.apiKeys[parts[0]]
+	s.mu.Unlock()
+	return ok && registered.ValidationLayerOne == parts[1] &&
+		registered.ValidationLayerTwo == parts[2] && parts[3] == s.account.CompanyID
+}
+
+func (s *Server) handleJWKS(w http.ResponseWriter, _ *http.Request) {
+	size := (s.key.Curve.Params().BitSize + 7) / 8
+	writeJSON(w, http.StatusOK, client.JSONWebKeySet{Keys: []client.JSONWebKey{{
+		Kid: s.kid,
+		Kty: "EC",
+		Alg: jwt.SigningMethodES256.Alg(),
+		Use: "sig",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(s.key.X.FillBytes(make([]byte, size))),
+		Y:   base64.RawURLEncoding.EncodeToString(s.key.Y.FillBytes(make([]byte, size))),
+	}}})
+}
+
+func bearerToken(r *http.Request) string {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token
+}
+
+func randomID(n int) string {
+	raw := make([]byte, n)
+	_, _ = rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
@@ -0,0 +1,248 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/synxms/synexis/pkg/client"
+)
+
+// epochsPerStep is how many training epochs a request logs per step while
+// running, it runs for two steps.
+const epochsPerStep = 3
+
+var artifactNames = []string{"model.onnx", "metrics.json"}
+
+func (s *Server) handleCreateRequest(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		SensoryID string `json:"sensory_id"`
+		DatasetID string `json:"dataset_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, envelope{ResponseCode: responseCodeFailed, ResponseMessage: "invalid request body"})
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sensory[payload.SensoryID]; !ok {
+		writeJSON(w, http.StatusNotFound, envelope{ResponseCode: responseCodeFailed, ResponseMessage: "unknown sensory id"})
+		return
+	}
+	if _, ok := s.datasets[payload.DatasetID]; !ok {
+		writeJSON(w, http.StatusNotFound, envelope{ResponseCode: responseCodeFailed, ResponseMessage: "unknown dataset id"})
+		return
+	}
+	request := s.createRequest(payload.SensoryID, payload.DatasetID)
+	writeJSON(w, http.StatusOK, envelope{
+		ResponseCode:    responseCodeSuccess,
+		ResponseMessage: "success",
+		Data:            map[string]string{"request_id": request.RequestID},
+	})
+}
+
+// createRequest must be called with s.mu held.
+func (s *Server) createRequest(sensoryID, datasetID string) *client.TrainingRequest {
+	now := s.now()
+	request := &client.TrainingRequest{
+		RequestID: s.nextID("request"),
+		SensoryID: sensoryID,
+		DatasetID: datasetID,
+		Status:    client.TrainingStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.requests[request.RequestID] = request
+	return request
+}
+
+// lookupRequest answers 404 itself when the request is unknown. It must be
+// called with s.mu held.
+func (s *Server) lookupRequest(w http.ResponseWriter, r *http.Request) (*client.TrainingRequest, bool) {
+	request, ok := s.requests[r.PathValue("id")]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, envelope{ResponseCode: responseCodeFailed, ResponseMessage: "unknown request id"})
+		return nil, false
+	}
+	s.advance(request)
+	return request, true
+}
+
+func (s *Server) handleGetRequest(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if request, ok := s.lookupRequest(w, r); ok {
+		writeJSON(w, http.StatusOK, envelope{ResponseCode: responseCodeSuccess, ResponseMessage: "success", Data: request})
+	}
+}
+
+func (s *Server) handleListRequests(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, envelope{ResponseCode: responseCodeSuccess, ResponseMessage: "success", Data: s.Requests()})
+}
+
+func (s *Server) handleRequestLogs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if request, ok := s.lookupRequest(w, r); ok {
+		writeJSON(w, http.StatusOK, envelope{ResponseCode: responseCodeSuccess, ResponseMessage: "success", Data: s.logs(request)})
+	}
+}
+
+func (s *Server) handleCancelRequest(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	request, ok := s.lookupRequest(w, r)
+	if !ok {
+		return
+	}
+	if request.Finished() {
+		writeJSON(w, http.StatusConflict, envelope{ResponseCode: responseCodeFailed, ResponseMessage: "request already " + request.Status})
+		return
+	}
+	request.Status = client.TrainingStatusCancelled
+	request.Message = "cancelled by user"
+	request.UpdatedAt = s.now()
+	writeJSON(w, http.StatusOK, envelope{ResponseCode: responseCodeSuccess, ResponseMessage: "success", Data: request})
+}
+
+func (s *Server) handleRetryRequest(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	request, ok := s.lookupRequest(w, r)
+	if !ok {
+		return
+	}
+	if request.Status != client.TrainingStatusFailed && request.Status != client.TrainingStatusCancelled {
+		writeJSON(w, http.StatusConflict, envelope{ResponseCode: responseCodeFailed, ResponseMessage: "only failed or cancelled requests can be retried"})
+		return
+	}
+	retried := s.createRequest(request.SensoryID, request.DatasetID)
+	writeJSON(w, http.StatusOK, envelope{
+		ResponseCode:    responseCodeSuccess,
+		ResponseMessage: "success",
+		Data:            map[string]string{"request_id": retried.RequestID},
+	})
+}
+
+func (s *Server) handleListArtifacts(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	request, ok := s.lookupRequest(w, r)
+	if !ok {
+		return
+	}
+	artifacts := []client.TrainingArtifact{}
+	if request.Status == client.TrainingStatusSucceeded {
+		for _, name := range artifactNames {
+			artifacts = append(artifacts, client.TrainingArtifact{
+				Name: name,
+				URL:  trainingRequestPath + request.RequestID + "/artifacts/" + name,
+				Size: int64(len(artifactContent(request, name))),
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, envelope{ResponseCode: responseCodeSuccess, ResponseMessage: "success", Data: artifacts})
+}
+
+func (s *Server) handleDownloadArtifact(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	request, ok := s.lookupRequest(w, r)
+	if !ok {
+		return
+	}
+	name := r.PathValue("name")
+	content := artifactContent(request, name)
+	if request.Status != client.TrainingStatusSucceeded || content == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(content)
+}
+
+func artifactContent(request *client.TrainingRequest, name string) []byte {
+	switch name {
+	case "model.onnx":
+		return []byte("mock model for " + request.RequestID + "\n")
+	case "metrics.json":
+		raw, _ := json.Marshal(request.Metrics)
+		return append(raw, '\n')
+	}
+	return nil
+}
+
+// advance moves a request through its lifecycle based on its age. Datasets
+// whose file name contains "fail" end in the failed state, which gives tests
+// a way to exercise both outcomes.
+func (s *Server) advance(request *client.TrainingRequest) {
+	if request.Finished() {
+		return
+	}
+	steps := s.now().Sub(request.CreatedAt) / s.stepTime
+	switch {
+	case steps >= 3 && strings.Contains(s.datasets[request.DatasetID].FileName, "fail"):
+		request.Status = client.TrainingStatusFailed
+		request.Message = "training diverged"
+		request.UpdatedAt = request.CreatedAt.Add(3 * s.stepTime)
+	case steps >= 3:
+		request.Status = client.TrainingStatusSucceeded
+		request.UpdatedAt = request.CreatedAt.Add(3 * s.stepTime)
+	case steps >= 1:
+		request.Status = client.TrainingStatusRunning
+		request.UpdatedAt = request.CreatedAt.Add(s.stepTime)
+	}
+	if epoch := s.epoch(request); epoch > 0 {
+		request.Metrics = epochMetrics(epoch)
+	}
+}
+
+// epoch is the last epoch the request completed.
+func (s *Server) epoch(request *client.TrainingRequest) int {
+	end := s.now()
+	if request.Finished() {
+		end = request.UpdatedAt
+	}
+	running := end.Sub(request.CreatedAt.Add(s.stepTime))
+	if running <= 0 {
+		return 0
+	}
+	return min(int(running*epochsPerStep/s.stepTime), 2*epochsPerStep)
+}
+
+func epochMetrics(epoch int) map[string]float64 {
+	loss := 1 / float64(1+epoch)
+	return map[string]float64{
+		"epoch":    float64(epoch),
+		"loss":     loss,
+		"accuracy": 1 - loss/2,
+	}
+}
+
+func (s *Server) logs(request *client.TrainingRequest) []client.TrainingLogEntry {
+	datasetFile := s.datasets[request.DatasetID].FileName
+	logs := []client.TrainingLogEntry{{Time: request.CreatedAt, Message: "request queued"}}
+	if request.Status == client.TrainingStatusPending {
+		return logs
+	}
+	started := request.CreatedAt.Add(s.stepTime)
+	if request.Status != client.TrainingStatusCancelled || request.UpdatedAt.After(started) {
+		logs = append(logs, client.TrainingLogEntry{Time: started, Message: "training started on " + datasetFile})
+	}
+	for epoch := 1; epoch <= s.epoch(request); epoch++ {
+		metrics := epochMetrics(epoch)
+		logs = append(logs, client.TrainingLogEntry{
+			Time:    started.Add(time.Duration(epoch) * s.stepTime / epochsPerStep),
+			Message: fmt.Sprintf("epoch %d: loss %.4f accuracy %.4f", epoch, metrics["loss"], metrics["accuracy"]),
+		})
+	}
+	switch request.Status {
+	case client.TrainingStatusSucceeded:
+		logs = append(logs, client.TrainingLogEntry{Time: request.UpdatedAt, Message: "training succeeded"})
+	case client.TrainingStatusFailed, client.TrainingStatusCancelled:
+		logs = append(logs, client.TrainingLogEntry{Time: request.UpdatedAt, Message: "training " + request.Status + ": " + request.Message})
+	}
+	return logs
+}
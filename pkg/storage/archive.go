@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	archiveFormat     = "synexis-store-export"
+	archiveVersion    = 1
+	keyDerivation     = "pbkdf2-sha256"
+	keyDerivationIter = 600000
+	// maxKeyDerivationIter keeps a crafted archive from tying up the CPU
+	// before the passphrase is even checked.
+	maxKeyDerivationIter = 10000000
+	minSaltSize          = 16
+)
+
+type (
+	// Archive is the portable JSON form of the local store.
+	Archive struct {
+		Format        string                    `json:"format"`
+		Version       int                       `json:"version"`
+		SchemaVersion int                       `json:"schemaVersion"`
+		ExportedAt    time.Time                 `json:"exportedAt"`
+		Profiles      map[string]ArchiveProfile `json:"profiles"`
+	}
+	ArchiveProfile struct {
+		Values  map[string]string `json:"values"`
+		Secrets *EncryptedSecrets `json:"secrets,omitempty"`
+	}
+	// EncryptedSecrets holds the profile's credentials sealed with AES-256-GCM
+	// under a key derived from a passphrase with PBKDF2-SHA256.
+	EncryptedSecrets struct {
+		KDF        string `json:"kdf"`
+		Iterations int    `json:"iterations"`
+		Salt       []byte `json:"salt"`
+		Nonce      []byte `json:"nonce"`
+		Ciphertext []byte `json:"ciphertext"`
+	}
+)
+
+func NewArchive() *Archive {
+	return &Archive{
+		Format:        archiveFormat,
+		Version:       archiveVersion,
+		SchemaVersion: SchemaVersion,
+		ExportedAt:    time.Now().UTC(),
+		Profiles:      map[string]ArchiveProfile{},
+	}
+}
+
+// AddProfile copies the settings and job registry of store into the archive.
+// Secrets are only included, encrypted, when passphrase is not empty. Other
+// machine local caches are left out.
+func (a *Archive) AddProfile(store Storage, passphrase string) error {
+	keys, err := store.Keys()
+	if err != nil {
+		return err
+	}
+	profile := ArchiveProfile{Values: map[string]string{}}
+	secrets := map[string]string{}
+	for _, key := range keys {
+		value, err := store.Get(key)
+		if err != nil {
+			return err
+		}
+		switch {
+		case IsSecretKey(key):
+			secrets[key] = value
+		case strings.HasPrefix(key, JobKeyPrefix), !IsDerivedKey(key):
+			profile.Values[key] = value
+		}
+	}
+	if passphrase != "" && len(secrets) > 0 {
+		if profile.Secrets, err = sealSecrets(secrets, passphrase); err != nil {
+			return err
+		}
+	}
+	a.Profiles[store.Profile()] = profile
+	return nil
+}
+
+// ParseArchive validates raw as an export written by a compatible synexis.
+func ParseArchive(raw []byte) (*Archive, error) {
+	var archive Archive
+	if err := json.Unmarshal(raw, &archive); err != nil {
+		return nil, fmt.Errorf("not a synexis store export: %w", err)
+	}
+	if archive.Format != archiveFormat {
+		return nil, errors.New("not a synexis store export")
+	}
+	if archive.Version > archiveVersion {
+		return nil, fmt.Errorf("export version %d is newer than this synexis supports", archive.Version)
+	}
+	return &archive, nil
+}
+
+// Restore merges the archived profile into store, overwriting keys that
+// exist in both. It returns the number of keys written. Only what AddProfile
+// writes is accepted: settings and jobs in the clear, credentials sealed,
+// and no caches, so a crafted export cannot plant signing keys or tokens.
+func (p ArchiveProfile) Restore(store Storage, passphrase string) (int, error) {
+	values := map[string]string{}
+	for key, value := range p.Values {
+		switch {
+		case IsSecretKey(key):
+			return 0, fmt.Errorf("export holds %s unencrypted, credentials are only imported from the encrypted secrets", key)
+		case IsDerivedKey(key) && !strings.HasPrefix(key, JobKeyPrefix):
+			return 0, fmt.Errorf("export holds %s, a cache that is never exported", key)
+		}
+		values[key] = value
+	}
+	if p.Secrets != nil {
+		if passphrase == "" {
+			return 0, errors.New("export contains encrypted secrets, a passphrase is required")
+		}
+		secrets, err := openSecrets(p.Secrets, passphrase)
+		if err != nil {
+			return 0, err
+		}
+		for key, value := range secrets {
+			if !IsSecretKey(key) {
+				return 0, fmt.Errorf("export holds %s among the encrypted secrets", key)
+			}
+			values[key] = value
+		}
+	}
+	for key, value := range values {
+		if err := store.Set(key, value); err != nil {
+			return 0, err
+		}
+	}
+	return len(values), nil
+}
+
+func secretsCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sealSecrets(secrets map[string]string, passphrase string) (*EncryptedSecrets, error) {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, err
+	}
+	sealed := &EncryptedSecrets{KDF: keyDerivation, Iterations: keyDerivationIter, Salt: make([]byte, 16)}
+	if _, err := rand.Read(sealed.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := secretsCipher(passphrase, sealed.Salt, sealed.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return nil, err
+	}
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, plaintext, []byte(archiveFormat))
+	return sealed, nil
+}
+
+func openSecrets(sealed *EncryptedSecrets, passphrase string) (map[string]string, error) {
+	if sealed.KDF != keyDerivation {
+		return nil, fmt.Errorf("unsupported key derivation %q", sealed.KDF)
+	}
+	if sealed.Iterations < keyDerivationIter || sealed.Iterations > maxKeyDerivationIter {
+		return nil, fmt.Errorf("key derivation iterations %d outside of %d to %d", sealed.Iterations, keyDerivationIter, maxKeyDerivationIter)
+	}
+	if len(sealed.Salt) < minSaltSize {
+		return nil, fmt.Errorf("key derivation salt shorter than %d bytes", minSaltSize)
+	}
+	aead, err := secretsCipher(passphrase, sealed.Salt, sealed.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return nil, errors.New("corrupted secrets in export")
+	}
+	plaintext, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, []byte(archiveFormat))
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted secrets in export")
+	}
+	var secrets map[string]string
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOpenSecrets(t *testing.T) {
+	secrets := map[string]string{"accesstoken": "access", "refreshtoken": "refresh"}
+	sealed, err := sealSecrets(secrets, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := openSecrets(sealed, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opened) != len(secrets) || opened["accesstoken"] != "access" || opened["refreshtoken"] != "refresh" {
+		t.Fatalf("opened %v, want %v", opened, secrets)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(s *EncryptedSecrets)
+		wantErr string
+	}{
+		{"other kdf", func(s *EncryptedSecrets) { s.KDF = "scrypt" }, "unsupported key derivation"},
+		{"too few iterations", func(s *EncryptedSecrets) { s.Iterations = 1 }, "iterations"},
+		{"negative iterations", func(s *EncryptedSecrets) { s.Iterations = -1 }, "iterations"},
+		{"too many iterations", func(s *EncryptedSecrets) { s.Iterations = maxKeyDerivationIter + 1 }, "iterations"},
+		{"short salt", func(s *EncryptedSecrets) { s.Salt = s.Salt[:4] }, "salt"},
+		{"tampered ciphertext", func(s *EncryptedSecrets) { s.Ciphertext[0] ^= 1 }, "wrong passphrase"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified := *sealed
+			modified.Salt = append([]byte{}, sealed.Salt...)
+			modified.Ciphertext = append([]byte{}, sealed.Ciphertext...)
+			tt.modify(&modified)
+			_, err := openSecrets(&modified, "passphrase")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want an error about %s", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := openSecrets(sealed, "other"); err == nil {
+		t.Fatal("opened secrets with the wrong passphrase")
+	}
+}
+
+func newTestStore(t *testing.T, profile string) Storage {
+	t.Helper()
+	store := NewProfileStorage(profile)
+	if err := store.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestRestore(t *testing.T) {
+	t.Setenv(EnvHome, t.TempDir())
+	source := newTestStore(t, "source")
+	for key, value := range map[string]string{
+		"base_url":      "https://synexis.example",
+		"access_token":  "access",
+		"refresh_token": "refresh",
+		"jwks":          `{"keySet":{"keys":[]}}`,
+		"job/request-1": `{"requestId":"request-1"}`,
+		"upload/file":   "{}",
+	} {
+		if err := source.Set(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archive := NewArchive()
+	if err := archive.AddProfile(source, "passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	target := newTestStore(t, "target")
+	written, err := archive.Profiles["source"].Restore(target, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != 4 {
+		t.Errorf("restored %d keys, want base_url, job and both tokens", written)
+	}
+	for _, key := range []string{"jwks", "upload/file"} {
+		if value, _ := target.Get(key); value != "" {
+			t.Errorf("restored cache %s = %q", key, value)
+		}
+	}
+
+	sealed, err := sealSecrets(map[string]string{"jwks": "{}"}, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		profile ArchiveProfile
+		wantErr string
+	}{
+		{"plaintext access token", ArchiveProfile{Values: map[string]string{"access_token": "forged"}}, "access_token"},
+		{"plaintext refresh token", ArchiveProfile{Values: map[string]string{"base_url": "https://evil.example", "refresh_token": "forged"}}, "refresh_token"},
+		{"signing keys", ArchiveProfile{Values: map[string]string{"jwks": `{"fetchedAt":"2100-01-01T00:00:00Z"}`}}, "jwks"},
+		{"upload cache", ArchiveProfile{Values: map[string]string{"upload/file": "{}"}}, "upload/file"},
+		{"cache among the secrets", ArchiveProfile{Secrets: sealed}, "jwks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t, strings.ReplaceAll(tt.name, " ", "-"))
+			_, err := tt.profile.Restore(store, "passphrase")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want an error about %s", err, tt.wantErr)
+			}
+			if keys, _ := store.Keys(); len(keys) != 0 {
+				t.Errorf("rejected export still wrote %v", keys)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package storage
+
+import "strings"
+
+const (
+	JobKeyPrefix    = "job/"
+	UploadKeyPrefix = "upload/"
+)
+
+var (
+	secretKeys = map[string]bool{
+		"access_token":  true,
+		"refresh_token": true,
+	}
+	derivedKeys        = map[string]bool{"jwks": true}
+	derivedKeyPrefixes = []string{"pending_upload_", JobKeyPrefix, UploadKeyPrefix}
+)
+
+// IsSecretKey reports whether the key holds credentials.
+func IsSecretKey(key string) bool {
+	return secretKeys[key]
+}
+
+// IsDerivedKey reports whether the key is a cache that can be rebuilt or is
+// only meaningful on this machine, such as signing keys, the job registry or
+// the list of uploads.
+func IsDerivedKey(key string) bool {
+	if derivedKeys[key] {
+		return true
+	}
+	for _, prefix := range derivedKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"fmt"
+	"go.etcd.io/bbolt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	metaBucket       = "meta"
+	schemaVersionKey = "schema_version"
+)
+
+type (
+	Migration struct {
+		Version     int
+		Description string
+		apply       func(tx *bbolt.Tx) error
+	}
+	MigrationResult struct {
+		From       int
+		To         int
+		Applied    []Migration
+		BackupPath string
+	}
+)
+
+// migrations are applied in order, each one moves the schema to its Version.
+// Never edit or reorder an entry that has shipped, append a new one instead.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "move the unversioned key/value bucket into the default profile bucket",
+		apply:       migrateLegacyBucket,
+	},
+}
+
+// SchemaVersion is the version this build writes.
+var SchemaVersion = migrations[len(migrations)-1].Version
+
+func migrateLegacyBucket(tx *bbolt.Tx) error {
+	legacy := tx.Bucket([]byte(legacyBucketName))
+	if legacy == nil {
+		return nil
+	}
+	target, err := tx.CreateBucketIfNotExists([]byte(profileBucketPrefix + DefaultProfile))
+	if err != nil {
+		return err
+	}
+	if err := legacy.ForEach(func(k, v []byte) error {
+		return target.Put(k, v)
+	}); err != nil {
+		return err
+	}
+	return tx.DeleteBucket([]byte(legacyBucketName))
+}
+
+func readSchemaVersion(tx *bbolt.Tx) (int, error) {
+	meta := tx.Bucket([]byte(metaBucket))
+	if meta == nil {
+		return 0, nil
+	}
+	raw := meta.Get([]byte(schemaVersionKey))
+	if raw == nil {
+		return 0, nil
+	}
+	return strconv.Atoi(string(raw))
+}
+
+func pendingMigrations(current int) []Migration {
+	var pending []Migration
+	for _, migration := range migrations {
+		if migration.Version > current {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+// MigrationStatus reports the schema version of the store on disk and the
+// migrations that the next Init would apply, without changing anything.
+func MigrationStatus() (int, []Migration, error) {
+	dbPath, err := Path()
+	if err != nil {
+		return 0, nil, err
+	}
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return 0, pendingMigrations(0), nil
+	}
+	s := &storage{dbPath: dbPath}
+	var current int
+	err = s.view(func(tx *bbolt.Tx) error {
+		current, err = readSchemaVersion(tx)
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return current, pendingMigrations(current), nil
+}
+
+// Migrate brings the store on disk up to SchemaVersion.
+func Migrate() (*MigrationResult, error) {
+	dbPath, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return (&storage{dbPath: dbPath}).migrate()
+}
+
+// migrate copies the database aside before applying anything, then runs all
+// pending migrations and the version bump in a single transaction.
+func (s *storage) migrate() (*MigrationResult, error) {
+	result := &MigrationResult{}
+	err := s.update(func(tx *bbolt.Tx) error {
+		current, err := readSchemaVersion(tx)
+		if err != nil {
+			return fmt.Errorf("failed to read schema version: %w", err)
+		}
+		result.From, result.To = current, current
+		if current > SchemaVersion {
+			return fmt.Errorf("store schema version %d is newer than this synexis supports (%d), please upgrade", current, SchemaVersion)
+		}
+		pending := pendingMigrations(current)
+		if len(pending) == 0 {
+			return nil
+		}
+		if hasData(tx) {
+			result.BackupPath = fmt.Sprintf("%s.v%d-%s.bak", s.dbPath, current, time.Now().Format("20060102150405"))
+			if err := tx.CopyFile(result.BackupPath, 0600); err != nil {
+				return fmt.Errorf("failed to back up store before migrating: %w", err)
+			}
+		}
+		for _, migration := range pending {
+			if err := migration.apply(tx); err != nil {
+				return fmt.Errorf("migration %d failed: %w", migration.Version, err)
+			}
+			result.Applied = append(result.Applied, migration)
+			result.To = migration.Version
+		}
+		meta, err := tx.CreateBucketIfNotExists([]byte(metaBucket))
+		if err != nil {
+			return err
+		}
+		return meta.Put([]byte(schemaVersionKey), []byte(strconv.Itoa(result.To)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func hasData(tx *bbolt.Tx) bool {
+	found := false
+	_ = tx.ForEach(func(_ []byte, b *bbolt.Bucket) error {
+		if b.Stats().KeyN > 0 {
+			found = true
+		}
+		return nil
+	})
+	return found
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+const (
+	dbName       = "synexis.db"
+	configDBName = "config.db"
+	legacyDBName = "synexis-cli-cache.db"
+	appDirName   = "synexis"
+	EnvHome      = "SYNEXIS_HOME"
+)
+
+var pathOverride string
+
+// UsePath makes the store live at path instead of the default location.
+func UsePath(path string) {
+	pathOverride = path
+}
+
+// Path is where tokens and caches live on disk, in order of precedence: the
+// path given to UsePath, $SYNEXIS_HOME/synexis.db, then synexis.db in the
+// state directory, $XDG_STATE_HOME/synexis on Linux. SYNEXIS_HOME is checked
+// before any XDG variable. A store found at the old cache location is moved
+// over first.
+func Path() (string, error) {
+	if pathOverride != "" {
+		return pathOverride, ensureDir(filepath.Dir(pathOverride))
+	}
+	if home := os.Getenv(EnvHome); home != "" {
+		return filepath.Join(home, dbName), ensureDir(home)
+	}
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	if err := ensureDir(dir); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, dbName)
+	if err := moveLegacyStore(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// ConfigPath is where settings and profiles live on disk. UsePath and
+// SYNEXIS_HOME keep everything in the single file returned by Path, otherwise
+// it is config.db in the config directory, $XDG_CONFIG_HOME/synexis on Linux.
+func ConfigPath() (string, error) {
+	if pathOverride != "" || os.Getenv(EnvHome) != "" {
+		return Path()
+	}
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configDBName), ensureDir(dir)
+}
+
+// stateDir keeps credentials out of cache directories that cleanup tools
+// are free to wipe.
+func stateDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", appDirName), nil
+	case "windows":
+		base := os.Getenv("LocalAppData")
+		if base == "" {
+			base = os.Getenv("AppData")
+		}
+		if base == "" {
+			return "", errors.New("neither LocalAppData nor AppData environment variable is set")
+		}
+		return filepath.Join(base, appDirName), nil
+	default:
+		base := os.Getenv("XDG_STATE_HOME")
+		if base == "" || !filepath.IsAbs(base) {
+			base = filepath.Join(home, ".local", "state")
+		}
+		return filepath.Join(base, appDirName), nil
+	}
+}
+
+func configDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", appDirName), nil
+	case "windows":
+		base := os.Getenv("AppData")
+		if base == "" {
+			return "", errors.New("AppData environment variable is not set")
+		}
+		return filepath.Join(base, appDirName), nil
+	default:
+		base := os.Getenv("XDG_CONFIG_HOME")
+		if base == "" || !filepath.IsAbs(base) {
+			base = filepath.Join(home, ".config")
+		}
+		return filepath.Join(base, appDirName), nil
+	}
+}
+
+// legacyPath is where releases before the move kept the store.
+func legacyPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, "Library", "Caches", "synexis-cli", legacyDBName), nil
+	case "linux":
+		return filepath.Join(home, ".cache", "synexis-cli", legacyDBName), nil
+	case "windows":
+		if appData := os.Getenv("AppData"); appData != "" {
+			return filepath.Join(appData, "synexis-cli", legacyDBName), nil
+		}
+	}
+	return "", nil
+}
+
+func moveLegacyStore(path string) error {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return nil
+	}
+	legacy, err := legacyPath()
+	if err != nil || legacy == "" {
+		return nil
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		return nil
+	}
+	if err := os.Rename(legacy, path); err != nil {
+		// the cache may sit on another file system
+		if err := copyFile(legacy, path); err != nil {
+			return fmt.Errorf("failed to move store from %s: %w", legacy, err)
+		}
+		_ = os.Remove(legacy)
+	}
+	fmt.Fprintf(notices, "Local store moved from %s to %s\n", legacy, path)
+	return nil
+}
+
+func copyFile(from, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(to, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(to)
+		return err
+	}
+	return out.Close()
+}
+
+func ensureDir(dir string) error {
+	return os.MkdirAll(dir, 0700)
+}
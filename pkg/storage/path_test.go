@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"go.etcd.io/bbolt"
+)
+
+// useXDG points the XDG directories at fresh temporary ones with no
+// override set.
+func useXDG(t *testing.T) (configHome, stateHome string) {
+	t.Helper()
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("XDG directories are only used on Linux and the BSDs")
+	}
+	configHome, stateHome = t.TempDir(), t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(EnvHome, "")
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("XDG_STATE_HOME", stateHome)
+	return configHome, stateHome
+}
+
+func TestPathPrecedence(t *testing.T) {
+	configHome, stateHome := useXDG(t)
+	statePath := filepath.Join(stateHome, appDirName, dbName)
+	configPath := filepath.Join(configHome, appDirName, configDBName)
+	assertPaths := func(wantState, wantConfig string) {
+		t.Helper()
+		if path, err := Path(); err != nil || path != wantState {
+			t.Errorf("Path() = %q, %v, want %q", path, err, wantState)
+		}
+		if path, err := ConfigPath(); err != nil || path != wantConfig {
+			t.Errorf("ConfigPath() = %q, %v, want %q", path, err, wantConfig)
+		}
+	}
+	assertPaths(statePath, configPath)
+
+	home := t.TempDir()
+	t.Setenv(EnvHome, home)
+	assertPaths(filepath.Join(home, dbName), filepath.Join(home, dbName))
+
+	override := filepath.Join(t.TempDir(), "store.db")
+	UsePath(override)
+	t.Cleanup(func() { UsePath("") })
+	assertPaths(override, override)
+}
+
+func TestSettingsAndStateFiles(t *testing.T) {
+	configHome, stateHome := useXDG(t)
+	store := newTestStore(t, DefaultProfile)
+	for key, value := range map[string]string{"base_url": "https://api.example.test", "access_token": "token", JobKeyPrefix + "1": "{}"} {
+		if err := store.Set(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertKeys(t, filepath.Join(configHome, appDirName, configDBName), "base_url")
+	assertKeys(t, filepath.Join(stateHome, appDirName, dbName), "access_token", JobKeyPrefix+"1")
+	if keys, err := store.Keys(); err != nil || len(keys) != 3 {
+		t.Errorf("Keys() = %v, %v, want the keys of both files", keys, err)
+	}
+}
+
+func TestMoveSettings(t *testing.T) {
+	configHome, stateHome := useXDG(t)
+	statePath := filepath.Join(stateHome, appDirName, dbName)
+	if err := os.MkdirAll(filepath.Dir(statePath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	old := &storage{profile: "work", dbPath: statePath, configPath: statePath}
+	if err := old.Init(); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"base_url", "refresh_token"} {
+		if err := old.Set(key, "value"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := NewProfileStorage("work")
+	if err := store.Init(); err != nil {
+		t.Fatal(err)
+	}
+	assertKeys(t, filepath.Join(configHome, appDirName, configDBName), "base_url")
+	assertKeys(t, statePath, "refresh_token")
+	if value, err := store.Get("base_url"); err != nil || value != "value" {
+		t.Errorf("base_url = %q, %v after the move, want it kept", value, err)
+	}
+}
+
+// assertKeys checks the keys the profiles hold in a single file.
+func assertKeys(t *testing.T, path string, want ...string) {
+	t.Helper()
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var got []string
+	_ = db.View(func(tx *bbolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+			if !strings.HasPrefix(string(name), profileBucketPrefix) {
+				return nil
+			}
+			return b.ForEach(func(k, _ []byte) error {
+				got = append(got, string(k))
+				return nil
+			})
+		})
+	})
+	if len(got) != len(want) {
+		t.Fatalf("%s holds %v, want %v", filepath.Base(path), got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s holds %v, want %v", filepath.Base(path), got, want)
+		}
+	}
+}
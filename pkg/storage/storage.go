@@ -1,11 +1,21 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"go.etcd.io/bbolt"
+	bbolterrors "go.etcd.io/bbolt/errors"
+	"io"
 	"os"
-	"path/filepath"
-	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultProfile      = "default"
+	legacyBucketName    = legacyDBName
+	profileBucketPrefix = "profile/"
 )
 
 type (
@@ -13,79 +23,224 @@ type (
 		Init() error
 		Set(key, value string) error
 		Get(key string) (string, error)
+		Delete(key string) error
+		Keys() ([]string, error)
+		Reset() error
+		Profile() string
+		Profiles() ([]string, error)
 		Close()
 	}
 	storage struct {
-		boldDBName string
-		db         *bbolt.DB
+		profile    string
+		dbPath     string
+		configPath string
 	}
 )
 
+// ErrBusy is returned when another synexis process keeps the store locked
+// for longer than lockTimeout.
+var ErrBusy = errors.New("store is busy, another synexis process is holding it, try again")
+
+var activeProfile = os.Getenv("SYNEXIS_PROFILE")
+
+// lockTimeout is how long an operation waits for another process to release
+// the store, a variable so tests can shorten it.
+var lockTimeout = 5 * time.Second
+
+// notices receives what the store reports on its own, such as a schema
+// migration or a move from the legacy location.
+var notices io.Writer = io.Discard
+
+// UseNotices sends the store's notices to w, the CLI passes its stderr.
+func UseNotices(w io.Writer) {
+	notices = w
+}
+
+// UseProfile selects the profile opened by NewStorage.
+func UseProfile(profile string) {
+	activeProfile = profile
+}
+
 func NewStorage() Storage {
+	return NewProfileStorage(activeProfile)
+}
+
+func NewProfileStorage(profile string) Storage {
+	if profile == "" {
+		profile = DefaultProfile
+	}
 	return &storage{
-		boldDBName: "synexis-cli-cache.db",
+		profile: profile,
 	}
 }
 
-// platform-specific default path
-func getDefaultDBPath(fileName string) (string, error) {
-	var basePath string
-	home, err := os.UserHomeDir()
+func (s *storage) bucket() []byte {
+	return []byte(profileBucketPrefix + s.profile)
+}
+
+func (s *storage) Init() error {
+	dbPath, err := Path()
 	if err != nil {
-		return "", err
-	}
-
-	switch runtime.GOOS {
-	case "darwin":
-		basePath = filepath.Join(home, "Library", "Caches", "synexis-cli")
-	case "linux":
-		basePath = filepath.Join(home, ".cache", "synexis-cli")
-	case "windows":
-		appData := os.Getenv("AppData")
-		if appData == "" {
-			return "", fmt.Errorf("AppData environment variable not set")
+		return err
+	}
+	configPath, err := ConfigPath()
+	if err != nil {
+		return err
+	}
+	s.dbPath, s.configPath = dbPath, configPath
+
+	result, err := s.migrate()
+	if err != nil {
+		return err
+	}
+	if len(result.Applied) > 0 && result.BackupPath != "" {
+		fmt.Fprintf(notices, "Local store migrated from schema %d to %d, backup kept at %s\n", result.From, result.To, result.BackupPath)
+	}
+	if err := s.moveSettings(); err != nil {
+		return err
+	}
+
+	for _, path := range s.paths() {
+		err := s.updateFile(path, func(tx *bbolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(s.bucket())
+			return err
+		})
+		if err != nil {
+			return err
 		}
-		basePath = filepath.Join(appData, "synexis-cli")
-	default:
-		return "", fmt.Errorf("unsupported OS: %s", runtime.GOOS)
 	}
+	return nil
+}
 
-	// Ensure directory exists
-	if err := os.MkdirAll(basePath, 0700); err != nil {
-		return "", err
+// paths lists the files holding the store, a single one when an override
+// keeps settings next to tokens.
+func (s *storage) paths() []string {
+	if s.configPath == s.dbPath {
+		return []string{s.dbPath}
 	}
+	return []string{s.configPath, s.dbPath}
+}
 
-	return filepath.Join(basePath, fileName), nil
+// pathOf picks the file a key belongs to: credentials and caches go to the
+// state file, everything else is a setting.
+func (s *storage) pathOf(key string) string {
+	if IsSecretKey(key) || IsDerivedKey(key) {
+		return s.dbPath
+	}
+	return s.configPath
 }
 
-func (s *storage) Init() error {
-	dbPath, err := getDefaultDBPath(s.boldDBName)
+// moveSettings takes the settings out of a store written before they got a
+// file of their own, once, when that file does not exist yet.
+func (s *storage) moveSettings() error {
+	if s.configPath == s.dbPath {
+		return nil
+	}
+	if _, err := os.Stat(s.configPath); !os.IsNotExist(err) {
+		return nil
+	}
+	config, err := openFile(s.configPath, false)
 	if err != nil {
 		return err
 	}
+	moved := 0
+	err = s.update(func(tx *bbolt.Tx) error {
+		return config.Update(func(configTx *bbolt.Tx) error {
+			return tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+				if !strings.HasPrefix(string(name), profileBucketPrefix) {
+					return nil
+				}
+				target, err := configTx.CreateBucketIfNotExists(name)
+				if err != nil {
+					return err
+				}
+				var keys [][]byte
+				if err := b.ForEach(func(k, v []byte) error {
+					if IsSecretKey(string(k)) || IsDerivedKey(string(k)) {
+						return nil
+					}
+					keys = append(keys, k)
+					return target.Put(k, v)
+				}); err != nil {
+					return err
+				}
+				for _, k := range keys {
+					if err := b.Delete(k); err != nil {
+						return err
+					}
+				}
+				moved += len(keys)
+				return nil
+			})
+		})
+	})
+	_ = config.Close()
+	if err != nil {
+		_ = os.Remove(s.configPath)
+		return fmt.Errorf("failed to move settings to %s: %w", s.configPath, err)
+	}
+	if moved > 0 {
+		fmt.Fprintf(notices, "Local settings moved from %s to %s\n", s.dbPath, s.configPath)
+	}
+	return nil
+}
+
+// openFile holds the file lock only for the duration of a single transaction
+// so parallel invocations, such as one running a long upload, do not block
+// each other. Read-only opens share the lock.
+func openFile(path string, readOnly bool) (*bbolt.DB, error) {
+	if path == "" {
+		return nil, errors.New("storage is not initialized")
+	}
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: lockTimeout, ReadOnly: readOnly})
+	if errors.Is(err, bbolterrors.ErrTimeout) {
+		return nil, ErrBusy
+	}
+	return db, err
+}
 
-	s.db, err = bbolt.Open(dbPath, 0600, nil)
+func (s *storage) updateFile(path string, fn func(tx *bbolt.Tx) error) error {
+	db, err := openFile(path, false)
 	if err != nil {
 		return err
 	}
+	defer db.Close()
+	return db.Update(fn)
+}
 
-	return s.db.Update(func(tx *bbolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(s.boldDBName))
+func (s *storage) viewFile(path string, fn func(tx *bbolt.Tx) error) error {
+	db, err := openFile(path, true)
+	if err != nil {
 		return err
-	})
+	}
+	defer db.Close()
+	return db.View(fn)
+}
+
+// update and view work on the state file, which also holds the schema
+// version.
+func (s *storage) update(fn func(tx *bbolt.Tx) error) error {
+	return s.updateFile(s.dbPath, fn)
+}
+
+func (s *storage) view(fn func(tx *bbolt.Tx) error) error {
+	return s.viewFile(s.dbPath, fn)
 }
 
 func (s *storage) Set(key, value string) error {
-	return s.db.Update(func(tx *bbolt.Tx) error {
-		b := tx.Bucket([]byte(s.boldDBName))
+	return s.updateFile(s.pathOf(key), func(tx *bbolt.Tx) error {
+		b := tx.Bucket(s.bucket())
 		return b.Put([]byte(key), []byte(value))
 	})
 }
 
 func (s *storage) Get(key string) (string, error) {
 	var val string
-	err := s.db.View(func(tx *bbolt.Tx) error {
-		b := tx.Bucket([]byte(s.boldDBName))
+	err := s.viewFile(s.pathOf(key), func(tx *bbolt.Tx) error {
+		b := tx.Bucket(s.bucket())
+		if b == nil {
+			return nil
+		}
 		v := b.Get([]byte(key))
 		if v != nil {
 			val = string(v)
@@ -95,8 +250,78 @@ func (s *storage) Get(key string) (string, error) {
 	return val, err
 }
 
-func (s *storage) Close() {
-	if s.db != nil {
-		_ = s.db.Close()
+func (s *storage) Delete(key string) error {
+	return s.updateFile(s.pathOf(key), func(tx *bbolt.Tx) error {
+		b := tx.Bucket(s.bucket())
+		return b.Delete([]byte(key))
+	})
+}
+
+func (s *storage) Keys() ([]string, error) {
+	var keys []string
+	for _, path := range s.paths() {
+		err := s.viewFile(path, func(tx *bbolt.Tx) error {
+			b := tx.Bucket(s.bucket())
+			if b == nil {
+				return nil
+			}
+			return b.ForEach(func(k, _ []byte) error {
+				keys = append(keys, string(k))
+				return nil
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
+	return keys, nil
 }
+
+// Reset removes every key of the profile.
+func (s *storage) Reset() error {
+	for _, path := range s.paths() {
+		err := s.updateFile(path, func(tx *bbolt.Tx) error {
+			if tx.Bucket(s.bucket()) != nil {
+				if err := tx.DeleteBucket(s.bucket()); err != nil {
+					return err
+				}
+			}
+			_, err := tx.CreateBucket(s.bucket())
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *storage) Profile() string {
+	return s.profile
+}
+
+func (s *storage) Profiles() ([]string, error) {
+	seen := map[string]bool{}
+	var profiles []string
+	for _, path := range s.paths() {
+		err := s.viewFile(path, func(tx *bbolt.Tx) error {
+			return tx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+				profile, ok := strings.CutPrefix(string(name), profileBucketPrefix)
+				if ok && !seen[profile] {
+					seen[profile] = true
+					profiles = append(profiles, profile)
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(profiles)
+	return profiles, nil
+}
+
+// Close is kept for callers that pair it with Init, the database is never
+// held open between operations.
+func (s *storage) Close() {}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+const (
+	envWorker   = "SYNEXIS_STORAGE_TEST_WORKER"
+	envWorkerID = "SYNEXIS_STORAGE_TEST_WORKER_ID"
+	workers     = 8
+	writes      = 40
+)
+
+// TestMain turns the test binary into a worker process when the stress tests
+// re-execute it, so several processes really contend for the file lock.
+func TestMain(m *testing.M) {
+	switch os.Getenv(envWorker) {
+	case "":
+		os.Exit(m.Run())
+	case "write":
+		os.Exit(runWriter(os.Getenv(envWorkerID)))
+	case "busy":
+		os.Exit(runBusy())
+	}
+	fmt.Fprintln(os.Stderr, "unknown worker", os.Getenv(envWorker))
+	os.Exit(2)
+}
+
+func workerKey(id string, i int) string {
+	return fmt.Sprintf("worker-%s-%03d", id, i)
+}
+
+// runWriter sets and reads back its own keys, failing on the first lost or
+// refused write.
+func runWriter(id string) int {
+	store := NewProfileStorage(DefaultProfile)
+	if err := store.Init(); err != nil {
+		fmt.Fprintln(os.Stderr, "init:", err)
+		return 1
+	}
+	for i := 0; i < writes; i++ {
+		key := workerKey(id, i)
+		if err := store.Set(key, strconv.Itoa(i)); err != nil {
+			fmt.Fprintln(os.Stderr, "set:", err)
+			return 1
+		}
+		value, err := store.Get(key)
+		if err != nil || value != strconv.Itoa(i) {
+			fmt.Fprintf(os.Stderr, "get %s: %q, %v\n", key, value, err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// runBusy expects the store to be held by the parent and reports whether
+// Set gave up with ErrBusy.
+func runBusy() int {
+	lockTimeout = 200 * time.Millisecond
+	path := filepath.Join(os.Getenv(EnvHome), dbName)
+	store := &storage{profile: DefaultProfile, dbPath: path, configPath: path}
+	err := store.Set("key", "value")
+	if !errors.Is(err, ErrBusy) {
+		fmt.Fprintln(os.Stderr, "set returned", err, "instead of ErrBusy")
+		return 1
+	}
+	return 0
+}
+
+func worker(ctx context.Context, home, mode, id string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), EnvHome+"="+home, envWorker+"="+mode, envWorkerID+"="+id)
+	return cmd
+}
+
+func TestConcurrentProcesses(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(EnvHome, home)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		cmd := worker(ctx, home, "write", strconv.Itoa(w))
+		go func() {
+			if output, err := cmd.CombinedOutput(); err != nil {
+				errs <- fmt.Errorf("worker %d: %v: %s", w, err, output)
+				return
+			}
+			errs <- nil
+		}()
+	}
+	for w := 0; w < workers; w++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	if ctx.Err() != nil {
+		t.Fatal("workers did not finish in time, the store lock hangs")
+	}
+
+	store := NewProfileStorage(DefaultProfile)
+	if err := store.Init(); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := store.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != workers*writes {
+		t.Errorf("store holds %d keys, want %d", len(keys), workers*writes)
+	}
+	for w := 0; w < workers; w++ {
+		for i := 0; i < writes; i++ {
+			key := workerKey(strconv.Itoa(w), i)
+			if value, err := store.Get(key); err != nil || value != strconv.Itoa(i) {
+				t.Errorf("%s = %q, %v after all workers finished, want %d", key, value, err, i)
+			}
+		}
+	}
+}
+
+func TestBusyStore(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(EnvHome, home)
+	store := NewProfileStorage(DefaultProfile)
+	if err := store.Init(); err != nil {
+		t.Fatal(err)
+	}
+	db, err := bbolt.Open(filepath.Join(home, dbName), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	output, err := worker(ctx, home, "busy", "").CombinedOutput()
+	if ctx.Err() != nil {
+		t.Fatal("Set hung on a held store instead of returning ErrBusy")
+	}
+	if err != nil {
+		t.Fatalf("%v: %s", err, output)
+	}
+}
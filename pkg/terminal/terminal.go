@@ -0,0 +1,7 @@
+// Package terminal switches a terminal between line and raw input and
+// reports its size, which is all the full-screen views of the CLI need.
+package terminal
+
+import "errors"
+
+var ErrNotTerminal = errors.New("not a terminal")
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package terminal
+
+func IsTerminal(uintptr) bool {
+	return false
+}
+
+func MakeRaw(uintptr) (func() error, error) {
+	return nil, ErrNotTerminal
+}
+
+func EnableANSI(uintptr) (func() error, error) {
+	return nil, ErrNotTerminal
+}
+
+func Size(uintptr) (int, int, error) {
+	return 0, 0, ErrNotTerminal
+}
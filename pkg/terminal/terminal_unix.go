@@ -0,0 +1,45 @@
+//go:build linux || darwin || freebsd
+
+package terminal
+
+import "golang.org/x/sys/unix"
+
+func IsTerminal(fd uintptr) bool {
+	_, err := unix.IoctlGetTermios(int(fd), ioctlReadTermios)
+	return err == nil
+}
+
+// MakeRaw delivers key presses as they are typed, without echo. Signal keys
+// such as Ctrl-C keep working and output processing is left alone.
+func MakeRaw(fd uintptr) (restore func() error, err error) {
+	original, err := unix.IoctlGetTermios(int(fd), ioctlReadTermios)
+	if err != nil {
+		return nil, ErrNotTerminal
+	}
+	raw := *original
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(int(fd), ioctlWriteTermios, &raw); err != nil {
+		return nil, err
+	}
+	return func() error {
+		return unix.IoctlSetTermios(int(fd), ioctlWriteTermios, original)
+	}, nil
+}
+
+// EnableANSI is a no-op, unix terminals understand escape sequences.
+func EnableANSI(uintptr) (restore func() error, err error) {
+	return func() error { return nil }, nil
+}
+
+func Size(fd uintptr) (width, height int, err error) {
+	size, err := unix.IoctlGetWinsize(int(fd), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, ErrNotTerminal
+	}
+	return int(size.Col), int(size.Row), nil
+}
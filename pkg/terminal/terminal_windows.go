@@ -0,0 +1,45 @@
+package terminal
+
+import "golang.org/x/sys/windows"
+
+func IsTerminal(fd uintptr) bool {
+	var mode uint32
+	return windows.GetConsoleMode(windows.Handle(fd), &mode) == nil
+}
+
+// MakeRaw delivers key presses as they are typed, without echo, and turns
+// arrow keys into the same escape sequences unix terminals send.
+func MakeRaw(fd uintptr) (restore func() error, err error) {
+	return setMode(fd, func(mode uint32) uint32 {
+		mode &^= windows.ENABLE_ECHO_INPUT | windows.ENABLE_LINE_INPUT
+		return mode | windows.ENABLE_VIRTUAL_TERMINAL_INPUT
+	})
+}
+
+// EnableANSI makes the console interpret escape sequences written to fd.
+func EnableANSI(fd uintptr) (restore func() error, err error) {
+	return setMode(fd, func(mode uint32) uint32 {
+		return mode | windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING
+	})
+}
+
+func setMode(fd uintptr, change func(uint32) uint32) (func() error, error) {
+	var original uint32
+	if err := windows.GetConsoleMode(windows.Handle(fd), &original); err != nil {
+		return nil, ErrNotTerminal
+	}
+	if err := windows.SetConsoleMode(windows.Handle(fd), change(original)); err != nil {
+		return nil, err
+	}
+	return func() error {
+		return windows.SetConsoleMode(windows.Handle(fd), original)
+	}, nil
+}
+
+func Size(fd uintptr) (width, height int, err error) {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(fd), &info); err != nil {
+		return 0, 0, ErrNotTerminal
+	}
+	return int(info.Window.Right-info.Window.Left) + 1, int(info.Window.Bottom-info.Window.Top) + 1, nil
+}
@@ -0,0 +1,92 @@
+package zstd
+
+import "encoding/binary"
+
+// bitWriter packs values least significant bit first. zstd reads these
+// streams backwards, the value written last is read first.
+type bitWriter struct {
+	out []byte
+	acc uint64
+	n   uint
+}
+
+// add writes the low count bits of value, count is at most 32.
+func (w *bitWriter) add(value uint64, count uint) {
+	if count == 0 {
+		return
+	}
+	w.acc |= (value & (1<<count - 1)) << w.n
+	w.n += count
+	for w.n >= 8 {
+		w.out = append(w.out, byte(w.acc))
+		w.acc >>= 8
+		w.n -= 8
+	}
+}
+
+// close ends the stream with the marker bit the reader looks for.
+func (w *bitWriter) close() []byte {
+	w.add(1, 1)
+	if w.n > 0 {
+		w.out = append(w.out, byte(w.acc))
+		w.acc, w.n = 0, 0
+	}
+	return w.out
+}
+
+// bitsAt returns count bits of in starting at bit start, count is at most
+// 56. Bits outside of in read as zero.
+func bitsAt(in []byte, start int, count uint) uint64 {
+	if count == 0 {
+		return 0
+	}
+	shift := uint(0)
+	if start < 0 {
+		if int(count) <= -start {
+			return 0
+		}
+		shift = uint(-start)
+		count -= shift
+		start = 0
+	}
+	var word [8]byte
+	copy(word[:], in[min(start>>3, len(in)):])
+	value := binary.LittleEndian.Uint64(word[:]) >> uint(start&7)
+	return (value & (1<<count - 1)) << shift
+}
+
+// backwardReader reads a stream written by bitWriter from its end.
+type backwardReader struct {
+	in  []byte
+	pos int
+}
+
+func newBackwardReader(in []byte) (*backwardReader, error) {
+	if len(in) == 0 || in[len(in)-1] == 0 {
+		return nil, ErrCorrupt
+	}
+	return &backwardReader{in: in, pos: (len(in)-1)*8 + int(highBit(uint32(in[len(in)-1])))}, nil
+}
+
+func (r *backwardReader) read(count uint) uint64 {
+	r.pos -= int(count)
+	return bitsAt(r.in, r.pos, count)
+}
+
+func (r *backwardReader) peek(count uint) uint64 {
+	return bitsAt(r.in, r.pos-int(count), count)
+}
+
+func (r *backwardReader) skip(count uint) {
+	r.pos -= int(count)
+}
+
+// overflowed reports whether more bits were read than the stream holds.
+func (r *backwardReader) overflowed() bool {
+	return r.pos < 0
+}
+
+// finished reports whether every bit was read, no more and no fewer.
+func (r *backwardReader) finished() bool {
+	return r.pos == 0
+}
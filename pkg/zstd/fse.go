@@ -0,0 +1,226 @@
+package zstd
+
+import "fmt"
+
+type (
+	fseEntry struct {
+		symbol uint8
+		bits   uint8
+		base   uint16
+	}
+	// fseTable is an FSE decoding table, indexed by state.
+	fseTable struct {
+		log     uint
+		entries []fseEntry
+	}
+	// fseEncoder runs a decoding table backwards: for a symbol and the state
+	// the decoder moves to next, it knows the state the decoder must be in.
+	fseEncoder struct {
+		table *fseTable
+		prev  [][]uint16
+		first []uint16
+	}
+)
+
+// Predefined distributions of the sequence codes, -1 is a probability below
+// one.
+var (
+	predefinedLiteralLengths = []int16{4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1, 2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1, -1, -1, -1, -1}
+	predefinedMatchLengths   = []int16{1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1, -1, -1}
+	predefinedOffsets        = []int16{1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1}
+
+	predefinedLiteralLengthTable = mustBuildFSE(predefinedLiteralLengths, 6)
+	predefinedMatchLengthTable   = mustBuildFSE(predefinedMatchLengths, 6)
+	predefinedOffsetTable        = mustBuildFSE(predefinedOffsets, 5)
+)
+
+// Baselines and extra bits of the literal length and match length codes.
+var (
+	literalLengthBase = [36]uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 18, 20, 22, 24, 28, 32, 40, 48, 64, 128, 256, 512, 1024, 2048, 4096, 8192, 16384, 32768, 65536}
+	literalLengthBits = [36]uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 3, 3, 4, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	matchLengthBase   = [53]uint32{3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34, 35, 37, 39, 41, 43, 47, 51, 59, 67, 83, 99, 131, 259, 515, 1027, 2051, 4099, 8195, 16387, 32771, 65539}
+	matchLengthBits   = [53]uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 3, 3, 4, 4, 5, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+)
+
+func literalLengthCode(length uint32) uint8 {
+	if length < 16 {
+		return uint8(length)
+	}
+	if length >= 64 {
+		return uint8(highBit(length) + 19)
+	}
+	code := uint8(16)
+	for literalLengthBase[code+1] <= length {
+		code++
+	}
+	return code
+}
+
+func matchLengthCode(length uint32) uint8 {
+	if length < 35 {
+		return uint8(length - 3)
+	}
+	if length >= 131 {
+		return uint8(highBit(length-3) + 36)
+	}
+	code := uint8(32)
+	for matchLengthBase[code+1] <= length {
+		code++
+	}
+	return code
+}
+
+// buildFSE spreads the normalized counts over a table of 1<<log states.
+func buildFSE(counts []int16, log uint) (*fseTable, error) {
+	size := 1 << log
+	total := 0
+	for _, count := range counts {
+		if count < 0 {
+			total++
+		} else {
+			total += int(count)
+		}
+	}
+	if total != size || len(counts) > 256 {
+		return nil, fmt.Errorf("%w: FSE counts add up to %d instead of %d", ErrCorrupt, total, size)
+	}
+	table := &fseTable{log: log, entries: make([]fseEntry, size)}
+	next := make([]uint32, len(counts))
+	high := size - 1
+	for symbol, count := range counts {
+		if count < 0 {
+			table.entries[high].symbol = uint8(symbol)
+			high--
+			next[symbol] = 1
+		}
+	}
+	step := size>>1 + size>>3 + 3
+	position := 0
+	for symbol, count := range counts {
+		if count <= 0 {
+			continue
+		}
+		next[symbol] = uint32(count)
+		for i := 0; i < int(count); i++ {
+			table.entries[position].symbol = uint8(symbol)
+			for position = (position + step) & (size - 1); position > high; position = (position + step) & (size - 1) {
+			}
+		}
+	}
+	if position != 0 {
+		return nil, fmt.Errorf("%w: FSE counts do not spread", ErrCorrupt)
+	}
+	for state := range table.entries {
+		entry := &table.entries[state]
+		nextState := next[entry.symbol]
+		next[entry.symbol]++
+		entry.bits = uint8(log - highBit(nextState))
+		entry.base = uint16(nextState<<entry.bits) - uint16(size)
+	}
+	return table, nil
+}
+
+func mustBuildFSE(counts []int16, log uint) *fseTable {
+	table, err := buildFSE(counts, log)
+	if err != nil {
+		panic(err)
+	}
+	return table
+}
+
+// rleTable always decodes symbol without reading any bits.
+func rleTable(symbol uint8) *fseTable {
+	return &fseTable{entries: []fseEntry{{symbol: symbol}}}
+}
+
+// readFSE reads a table description of at most maxLog accuracy for symbols
+// up to maxSymbol, and returns the table and the bytes it took.
+func readFSE(in []byte, maxSymbol int, maxLog uint) (*fseTable, int, error) {
+	if len(in) == 0 {
+		return nil, 0, ErrCorrupt
+	}
+	log := uint(in[0]&15) + 5
+	if log > maxLog {
+		return nil, 0, fmt.Errorf("%w: FSE accuracy %d above %d", ErrCorrupt, log, maxLog)
+	}
+	position := 4
+	read := func(count uint) uint32 {
+		value := uint32(bitsAt(in, position, count))
+		position += int(count)
+		return value
+	}
+	peek := func(count uint) uint32 {
+		return uint32(bitsAt(in, position, count))
+	}
+	var counts []int16
+	remaining := int32(1<<log) + 1
+	threshold := int32(1 << log)
+	width := log + 1
+	previousZero := false
+	for remaining > 1 && len(counts) <= maxSymbol {
+		if previousZero {
+			zeros := 0
+			for peek(16) == 0xFFFF {
+				zeros += 24
+				read(16)
+			}
+			for peek(2) == 3 {
+				zeros += 3
+				read(2)
+			}
+			zeros += int(read(2))
+			for ; zeros > 0; zeros-- {
+				counts = append(counts, 0)
+			}
+			if len(counts) > maxSymbol {
+				break
+			}
+		}
+		largest := 2*threshold - 1 - remaining
+		var value int32
+		if low := int32(peek(width - 1)); low < largest {
+			value = low
+			read(width - 1)
+		} else {
+			value = int32(peek(width))
+			if value >= threshold {
+				value -= largest
+			}
+			read(width)
+		}
+		count := value - 1
+		if count < 0 {
+			remaining--
+		} else {
+			remaining -= count
+		}
+		counts = append(counts, int16(count))
+		previousZero = count == 0
+		for remaining < threshold && threshold > 1 {
+			width--
+			threshold >>= 1
+		}
+	}
+	used := (position + 7) / 8
+	if remaining != 1 || len(counts) > maxSymbol+1 || used > len(in) {
+		return nil, 0, fmt.Errorf("%w: invalid FSE table description", ErrCorrupt)
+	}
+	table, err := buildFSE(counts, log)
+	return table, used, err
+}
+
+func newFSEEncoder(table *fseTable, symbols int) *fseEncoder {
+	e := &fseEncoder{table: table, prev: make([][]uint16, symbols), first: make([]uint16, symbols)}
+	size := len(table.entries)
+	for state := len(table.entries) - 1; state >= 0; state-- {
+		entry := table.entries[state]
+		if e.prev[entry.symbol] == nil {
+			e.prev[entry.symbol] = make([]uint16, size)
+		}
+		e.first[entry.symbol] = uint16(state)
+		for next := int(entry.base); next < int(entry.base)+1<<entry.bits; next++ {
+			e.prev[entry.symbol][next] = uint16(state)
+		}
+	}
+	return e
+}
@@ -0,0 +1,234 @@
+package zstd
+
+import (
+	"fmt"
+	"sort"
+)
+
+// maxHuffmanBits is the longest literal code the format allows.
+const maxHuffmanBits = 11
+
+type (
+	huffmanEntry struct {
+		symbol uint8
+		bits   uint8
+	}
+	// huffmanTable decodes literals by peeking maxBits bits.
+	huffmanTable struct {
+		maxBits uint
+		entries []huffmanEntry
+	}
+	huffmanCode struct {
+		code uint16
+		bits uint8
+	}
+	packageNode struct {
+		weight      uint64
+		leaf        int
+		left, right *packageNode
+	}
+)
+
+// huffmanLengths returns code lengths of at most maxHuffmanBits for the
+// literals counted in freq, built with the package-merge algorithm so that
+// the code is complete. At least two symbols must occur.
+func huffmanLengths(freq *[256]uint32) [256]uint8 {
+	var leaves []*packageNode
+	for symbol, count := range freq {
+		if count > 0 {
+			leaves = append(leaves, &packageNode{weight: uint64(count), leaf: symbol})
+		}
+	}
+	sort.SliceStable(leaves, func(i, j int) bool { return leaves[i].weight < leaves[j].weight })
+	list := leaves
+	for level := 1; level < maxHuffmanBits; level++ {
+		packages := make([]*packageNode, 0, len(list)/2)
+		for i := 0; i+1 < len(list); i += 2 {
+			packages = append(packages, &packageNode{weight: list[i].weight + list[i+1].weight, leaf: -1, left: list[i], right: list[i+1]})
+		}
+		merged := make([]*packageNode, 0, len(leaves)+len(packages))
+		i, j := 0, 0
+		for i < len(leaves) || j < len(packages) {
+			if j == len(packages) || (i < len(leaves) && leaves[i].weight <= packages[j].weight) {
+				merged = append(merged, leaves[i])
+				i++
+			} else {
+				merged = append(merged, packages[j])
+				j++
+			}
+		}
+		list = merged
+	}
+	var lengths [256]uint8
+	var count func(node *packageNode)
+	count = func(node *packageNode) {
+		if node.leaf >= 0 {
+			lengths[node.leaf]++
+			return
+		}
+		count(node.left)
+		count(node.right)
+	}
+	for _, node := range list[:2*len(leaves)-2] {
+		count(node)
+	}
+	return lengths
+}
+
+// huffmanCodes assigns the canonical codes of zstd: longer codes come
+// first, and symbols of the same length are ordered by value.
+func huffmanCodes(lengths *[256]uint8) [256]huffmanCode {
+	var codes [256]huffmanCode
+	code := uint32(0)
+	previous := uint8(0)
+	for length := uint8(maxHuffmanBits); length > 0; length-- {
+		for symbol := range lengths {
+			if lengths[symbol] != length {
+				continue
+			}
+			if previous != 0 && previous != length {
+				code >>= previous - length
+			}
+			previous = length
+			codes[symbol] = huffmanCode{code: uint16(code), bits: length}
+			code++
+		}
+	}
+	return codes
+}
+
+// newHuffmanTable builds the decoding table for the weights of symbols
+// 0..len(weights)-1, including the last one.
+func newHuffmanTable(weights []uint8) (*huffmanTable, error) {
+	total := uint32(0)
+	for _, weight := range weights {
+		if weight > maxHuffmanBits {
+			return nil, fmt.Errorf("%w: huffman weight %d", ErrCorrupt, weight)
+		}
+		if weight > 0 {
+			total += 1 << (weight - 1)
+		}
+	}
+	if total < 2 || total&(total-1) != 0 || highBit(total) > maxHuffmanBits {
+		return nil, fmt.Errorf("%w: huffman weights do not form a complete code", ErrCorrupt)
+	}
+	maxBits := highBit(total)
+	var lengths [256]uint8
+	for symbol, weight := range weights {
+		if weight > 0 {
+			lengths[symbol] = uint8(maxBits + 1 - uint(weight))
+		}
+	}
+	codes := huffmanCodes(&lengths)
+	table := &huffmanTable{maxBits: maxBits, entries: make([]huffmanEntry, 1<<maxBits)}
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+		start := int(codes[symbol].code) << (maxBits - uint(length))
+		for i := start; i < start+1<<(maxBits-uint(length)); i++ {
+			table.entries[i] = huffmanEntry{symbol: uint8(symbol), bits: length}
+		}
+	}
+	return table, nil
+}
+
+// readHuffmanTable reads a tree description, returning the table and the
+// bytes it took.
+func readHuffmanTable(in []byte) (*huffmanTable, int, error) {
+	if len(in) == 0 {
+		return nil, 0, ErrCorrupt
+	}
+	header := int(in[0])
+	var weights []uint8
+	used := 1
+	if header >= 128 {
+		count := header - 127
+		used += (count + 1) / 2
+		if used > len(in) {
+			return nil, 0, ErrCorrupt
+		}
+		for i := 0; i < count; i++ {
+			b := in[1+i/2]
+			if i%2 == 0 {
+				weights = append(weights, b>>4)
+			} else {
+				weights = append(weights, b&15)
+			}
+		}
+	} else {
+		used += header
+		if used > len(in) || header == 0 {
+			return nil, 0, ErrCorrupt
+		}
+		var err error
+		if weights, err = readHuffmanWeights(in[1:used]); err != nil {
+			return nil, 0, err
+		}
+	}
+	if len(weights) > 255 {
+		return nil, 0, ErrCorrupt
+	}
+	total := uint32(0)
+	for _, weight := range weights {
+		if weight > maxHuffmanBits {
+			return nil, 0, ErrCorrupt
+		}
+		if weight > 0 {
+			total += 1 << (weight - 1)
+		}
+	}
+	if total == 0 {
+		return nil, 0, ErrCorrupt
+	}
+	maxBits := highBit(total) + 1
+	rest := uint32(1)<<maxBits - total
+	if rest&(rest-1) != 0 || maxBits > maxHuffmanBits {
+		return nil, 0, fmt.Errorf("%w: huffman weights do not form a complete code", ErrCorrupt)
+	}
+	weights = append(weights, uint8(highBit(rest)+1))
+	table, err := newHuffmanTable(weights)
+	return table, used, err
+}
+
+// readHuffmanWeights decodes weights compressed with FSE, two states take
+// turns until the stream runs out.
+func readHuffmanWeights(in []byte) ([]uint8, error) {
+	table, used, err := readFSE(in, 255, 6)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := newBackwardReader(in[used:])
+	if err != nil {
+		return nil, err
+	}
+	states := [2]uint16{uint16(stream.read(table.log)), uint16(stream.read(table.log))}
+	var weights []uint8
+	for turn := 0; len(weights) < 255; turn ^= 1 {
+		entry := table.entries[states[turn]]
+		weights = append(weights, entry.symbol)
+		states[turn] = entry.base + uint16(stream.read(uint(entry.bits)))
+		if stream.overflowed() {
+			weights = append(weights, table.entries[states[turn^1]].symbol)
+			break
+		}
+	}
+	return weights, nil
+}
+
+// decode fills out with literals read from one stream.
+func (t *huffmanTable) decode(in []byte, out []byte) error {
+	stream, err := newBackwardReader(in)
+	if err != nil {
+		return err
+	}
+	for i := range out {
+		entry := t.entries[stream.peek(t.maxBits)]
+		out[i] = entry.symbol
+		stream.skip(uint(entry.bits))
+	}
+	if !stream.finished() {
+		return fmt.Errorf("%w: huffman stream size mismatch", ErrCorrupt)
+	}
+	return nil
+}
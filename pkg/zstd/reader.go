@@ -0,0 +1,501 @@
+package zstd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type (
+	// Reader decompresses a stream of zstd frames, skippable frames are
+	// passed over.
+	Reader struct {
+		r    *bufio.Reader
+		hist []byte
+		// out is where the data not yet returned by Read starts, frameStart
+		// where the current frame starts, offsets do not reach before it.
+		out        int
+		frameStart int
+		frames     int
+		frame      *frameState
+		err        error
+	}
+	frameState struct {
+		window    int
+		checksum  bool
+		size      uint64
+		sized     bool
+		produced  uint64
+		last      bool
+		digest    *xxh64
+		repeats   [3]uint32
+		huffman   *huffmanTable
+		sequences [3]*fseTable
+		block     []byte
+		literals  []byte
+	}
+	sequenceTable struct {
+		maxSymbol  int
+		maxLog     uint
+		predefined *fseTable
+	}
+)
+
+// The sequence tables in the order of the compression modes byte.
+const (
+	literalLengths = iota
+	offsets
+	matchLengths
+)
+
+var sequenceTables = [3]sequenceTable{
+	literalLengths: {maxSymbol: 35, maxLog: 9, predefined: predefinedLiteralLengthTable},
+	offsets:        {maxSymbol: 31, maxLog: 8, predefined: predefinedOffsetTable},
+	matchLengths:   {maxSymbol: 52, maxLog: 9, predefined: predefinedMatchLengthTable},
+}
+
+// NewReader returns a Reader decompressing r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+func (z *Reader) Read(p []byte) (int, error) {
+	for z.out == len(z.hist) {
+		if z.err != nil {
+			return 0, z.err
+		}
+		z.err = z.next()
+	}
+	n := copy(p, z.hist[z.out:])
+	z.out += n
+	return n, nil
+}
+
+// next decodes the next block, starting a frame when needed.
+func (z *Reader) next() error {
+	if z.frame == nil {
+		return z.readFrameHeader()
+	}
+	if z.frame.last {
+		return z.finishFrame()
+	}
+	z.slide()
+	return z.readBlock()
+}
+
+func (z *Reader) readFull(p []byte) error {
+	if _, err := io.ReadFull(z.r, p); err != nil {
+		if errors.Is(err, io.EOF) {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
+
+func (z *Reader) readFrameHeader() error {
+	var magic [4]byte
+	if _, err := io.ReadFull(z.r, magic[:]); err != nil {
+		if errors.Is(err, io.EOF) && z.frames > 0 {
+			return io.EOF
+		}
+		if errors.Is(err, io.EOF) {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	z.frames++
+	switch value := binary.LittleEndian.Uint32(magic[:]); {
+	case value&skippableMagicMask == skippableMagic:
+		if err := z.readFull(magic[:]); err != nil {
+			return err
+		}
+		skipped, err := io.CopyN(io.Discard, z.r, int64(binary.LittleEndian.Uint32(magic[:])))
+		if err != nil && skipped < int64(binary.LittleEndian.Uint32(magic[:])) {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	case value != frameMagic:
+		return fmt.Errorf("%w: unknown frame magic %#x", ErrCorrupt, value)
+	}
+
+	descriptor, err := z.r.ReadByte()
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	if descriptor&0x08 != 0 {
+		return fmt.Errorf("%w: reserved frame header bit set", ErrCorrupt)
+	}
+	singleSegment := descriptor&0x20 != 0
+	sizeBytes := [4]int{0, 2, 4, 8}[descriptor>>6]
+	if sizeBytes == 0 && singleSegment {
+		sizeBytes = 1
+	}
+	headerSize := [4]int{0, 1, 2, 4}[descriptor&3] + sizeBytes
+	if !singleSegment {
+		headerSize++
+	}
+	header := make([]byte, headerSize)
+	if err := z.readFull(header); err != nil {
+		return err
+	}
+	frame := &frameState{checksum: descriptor&0x04 != 0, digest: newXXH64(), repeats: [3]uint32{1, 4, 8}}
+	if !singleSegment {
+		exponent, mantissa := header[0]>>3, header[0]&7
+		base := uint64(1) << (10 + exponent)
+		window := base + base/8*uint64(mantissa)
+		if window > MaxWindowSize {
+			return fmt.Errorf("%w: %d bytes", ErrWindowTooLarge, window)
+		}
+		frame.window = int(window)
+		header = header[1:]
+	}
+	var dictionary uint64
+	for i, b := range header[:len(header)-sizeBytes] {
+		dictionary |= uint64(b) << (8 * i)
+	}
+	if dictionary != 0 {
+		return fmt.Errorf("%w: frame needs dictionary %d", ErrDictionary, dictionary)
+	}
+	if sizeBytes > 0 {
+		frame.sized = true
+		for i, b := range header[len(header)-sizeBytes:] {
+			frame.size |= uint64(b) << (8 * i)
+		}
+		if sizeBytes == 2 {
+			frame.size += 256
+		}
+	}
+	if singleSegment {
+		if frame.size > MaxWindowSize {
+			return fmt.Errorf("%w: %d bytes", ErrWindowTooLarge, frame.size)
+		}
+		frame.window = int(frame.size)
+	}
+	z.frame = frame
+	z.frameStart = len(z.hist)
+	return nil
+}
+
+func (z *Reader) finishFrame() error {
+	frame := z.frame
+	z.frame = nil
+	if frame.sized && frame.produced != frame.size {
+		return fmt.Errorf("%w: frame holds %d bytes instead of %d", ErrCorrupt, frame.produced, frame.size)
+	}
+	if !frame.checksum {
+		return nil
+	}
+	var checksum [4]byte
+	if err := z.readFull(checksum[:]); err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint32(checksum[:]) != uint32(frame.digest.Sum64()) {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// slide drops history no offset can reach anymore, once everything was
+// read and enough of it piled up.
+func (z *Reader) slide() {
+	keep := z.frame.window
+	if len(z.hist) <= 2*max(keep, maxBlockSize) {
+		return
+	}
+	shift := len(z.hist) - keep
+	copy(z.hist, z.hist[shift:])
+	z.hist = z.hist[:keep]
+	z.out -= shift
+	z.frameStart = max(z.frameStart-shift, 0)
+}
+
+func (z *Reader) readBlock() error {
+	frame := z.frame
+	var header [3]byte
+	if err := z.readFull(header[:]); err != nil {
+		return err
+	}
+	value := uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16
+	frame.last = value&1 != 0
+	kind, size := int(value>>1&3), int(value>>3)
+	if size > maxBlockSize {
+		return fmt.Errorf("%w: block of %d bytes", ErrCorrupt, size)
+	}
+	start := len(z.hist)
+	switch kind {
+	case blockRaw:
+		z.hist = append(z.hist, make([]byte, size)...)
+		if err := z.readFull(z.hist[start:]); err != nil {
+			return err
+		}
+	case blockRLE:
+		b, err := z.r.ReadByte()
+		if err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		for i := 0; i < size; i++ {
+			z.hist = append(z.hist, b)
+		}
+	case blockCompressed:
+		if cap(frame.block) < size {
+			frame.block = make([]byte, size)
+		}
+		frame.block = frame.block[:size]
+		if err := z.readFull(frame.block); err != nil {
+			return err
+		}
+		if err := z.decodeBlock(frame.block); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: reserved block type", ErrCorrupt)
+	}
+	frame.produced += uint64(len(z.hist) - start)
+	if frame.checksum {
+		frame.digest.Write(z.hist[start:])
+	}
+	return nil
+}
+
+func (z *Reader) decodeBlock(in []byte) error {
+	used, err := z.decodeLiterals(in)
+	if err != nil {
+		return err
+	}
+	return z.decodeSequences(in[used:])
+}
+
+func (z *Reader) decodeLiterals(in []byte) (int, error) {
+	frame := z.frame
+	if len(in) == 0 {
+		return 0, ErrCorrupt
+	}
+	kind, format := in[0]&3, in[0]>>2&3
+	if kind < 2 {
+		var size, used int
+		switch format {
+		case 0, 2:
+			size, used = int(in[0]>>3), 1
+		case 1:
+			if len(in) < 2 {
+				return 0, ErrCorrupt
+			}
+			size, used = int(in[0]>>4)|int(in[1])<<4, 2
+		case 3:
+			if len(in) < 3 {
+				return 0, ErrCorrupt
+			}
+			size, used = int(in[0]>>4)|int(in[1])<<4|int(in[2])<<12, 3
+		}
+		if size > maxBlockSize {
+			return 0, ErrCorrupt
+		}
+		if kind == 0 {
+			if used+size > len(in) {
+				return 0, ErrCorrupt
+			}
+			frame.literals = append(frame.literals[:0], in[used:used+size]...)
+			return used + size, nil
+		}
+		if used >= len(in) {
+			return 0, ErrCorrupt
+		}
+		frame.literals = frame.literals[:0]
+		for i := 0; i < size; i++ {
+			frame.literals = append(frame.literals, in[used])
+		}
+		return used + 1, nil
+	}
+
+	used, width := [4]int{3, 3, 4, 5}[format], [4]uint{10, 10, 14, 18}[format]
+	if len(in) < used {
+		return 0, ErrCorrupt
+	}
+	var value uint64
+	for i, b := range in[:used] {
+		value |= uint64(b) << (8 * i)
+	}
+	regenerated := int(value >> 4 & (1<<width - 1))
+	compressed := int(value >> (4 + width) & (1<<width - 1))
+	if regenerated > maxBlockSize || used+compressed > len(in) {
+		return 0, ErrCorrupt
+	}
+	streams := in[used : used+compressed]
+	if kind == 2 {
+		table, size, err := readHuffmanTable(streams)
+		if err != nil {
+			return 0, err
+		}
+		frame.huffman = table
+		streams = streams[size:]
+	} else if frame.huffman == nil {
+		return 0, fmt.Errorf("%w: literals repeat a missing huffman table", ErrCorrupt)
+	}
+	if cap(frame.literals) < regenerated {
+		frame.literals = make([]byte, regenerated)
+	}
+	frame.literals = frame.literals[:regenerated]
+	if format == 0 {
+		return used + compressed, frame.huffman.decode(streams, frame.literals)
+	}
+	if len(streams) < 6 {
+		return 0, ErrCorrupt
+	}
+	sizes := [4]int{int(binary.LittleEndian.Uint16(streams)), int(binary.LittleEndian.Uint16(streams[2:])), int(binary.LittleEndian.Uint16(streams[4:]))}
+	sizes[3] = len(streams) - 6 - sizes[0] - sizes[1] - sizes[2]
+	segment := (regenerated + 3) / 4
+	if sizes[3] < 0 || 3*segment > regenerated {
+		return 0, ErrCorrupt
+	}
+	streams = streams[6:]
+	for i, size := range sizes {
+		out := frame.literals[i*segment:]
+		if i < 3 {
+			out = out[:segment]
+		}
+		if err := frame.huffman.decode(streams[:size], out); err != nil {
+			return 0, err
+		}
+		streams = streams[size:]
+	}
+	return used + compressed, nil
+}
+
+func (z *Reader) decodeSequences(in []byte) error {
+	frame := z.frame
+	if len(in) == 0 {
+		return ErrCorrupt
+	}
+	count, used := int(in[0]), 1
+	switch {
+	case count == 255:
+		if len(in) < 3 {
+			return ErrCorrupt
+		}
+		count, used = int(in[1])|int(in[2])<<8+0x7F00, 3
+	case count >= 128:
+		if len(in) < 2 {
+			return ErrCorrupt
+		}
+		count, used = (count-128)<<8|int(in[1]), 2
+	}
+	start := len(z.hist)
+	literals := frame.literals
+	if count == 0 {
+		if used != len(in) {
+			return ErrCorrupt
+		}
+		z.hist = append(z.hist, literals...)
+		return nil
+	}
+	if used >= len(in) {
+		return ErrCorrupt
+	}
+	modes := in[used]
+	used++
+	if modes&3 != 0 {
+		return fmt.Errorf("%w: reserved sequence mode bits set", ErrCorrupt)
+	}
+	for i, shift := range [3]uint{6, 4, 2} {
+		spec := sequenceTables[i]
+		switch modes >> shift & 3 {
+		case 0:
+			frame.sequences[i] = spec.predefined
+		case 1:
+			if used >= len(in) || int(in[used]) > spec.maxSymbol {
+				return ErrCorrupt
+			}
+			frame.sequences[i] = rleTable(in[used])
+			used++
+		case 2:
+			table, size, err := readFSE(in[used:], spec.maxSymbol, spec.maxLog)
+			if err != nil {
+				return err
+			}
+			frame.sequences[i] = table
+			used += size
+		case 3:
+			if frame.sequences[i] == nil {
+				return fmt.Errorf("%w: sequences repeat a missing table", ErrCorrupt)
+			}
+		}
+	}
+	stream, err := newBackwardReader(in[used:])
+	if err != nil {
+		return err
+	}
+	tables := frame.sequences
+	var states [3]uint16
+	for _, i := range [3]int{literalLengths, offsets, matchLengths} {
+		states[i] = uint16(stream.read(tables[i].log))
+	}
+	repeats := &frame.repeats
+	for n := 0; n < count; n++ {
+		literalCode := tables[literalLengths].entries[states[literalLengths]].symbol
+		offsetCode := tables[offsets].entries[states[offsets]].symbol
+		matchCode := tables[matchLengths].entries[states[matchLengths]].symbol
+		offset := uint32(1)<<offsetCode + uint32(stream.read(uint(offsetCode)))
+		match := int(matchLengthBase[matchCode] + uint32(stream.read(uint(matchLengthBits[matchCode]))))
+		literalLength := int(literalLengthBase[literalCode] + uint32(stream.read(uint(literalLengthBits[literalCode]))))
+
+		if offset > 3 {
+			offset -= 3
+			repeats[0], repeats[1], repeats[2] = offset, repeats[0], repeats[1]
+		} else {
+			index := int(offset) - 1
+			if literalLength == 0 {
+				index++
+			}
+			switch index {
+			case 0:
+				offset = repeats[0]
+			case 1:
+				offset = repeats[1]
+				repeats[0], repeats[1] = offset, repeats[0]
+			case 2:
+				offset = repeats[2]
+				repeats[0], repeats[1], repeats[2] = offset, repeats[0], repeats[1]
+			case 3:
+				offset = repeats[0] - 1
+				repeats[0], repeats[1], repeats[2] = offset, repeats[0], repeats[1]
+			}
+		}
+
+		if n < count-1 {
+			for _, i := range [3]int{literalLengths, matchLengths, offsets} {
+				entry := tables[i].entries[states[i]]
+				states[i] = entry.base + uint16(stream.read(uint(entry.bits)))
+			}
+		}
+		if stream.overflowed() {
+			return fmt.Errorf("%w: sequences stream too short", ErrCorrupt)
+		}
+
+		if literalLength > len(literals) {
+			return fmt.Errorf("%w: sequence needs more literals than decoded", ErrCorrupt)
+		}
+		z.hist = append(z.hist, literals[:literalLength]...)
+		literals = literals[literalLength:]
+		from := len(z.hist) - int(offset)
+		if offset == 0 || from < z.frameStart || len(z.hist)-start+match > maxBlockSize {
+			return fmt.Errorf("%w: match offset %d out of range", ErrCorrupt, offset)
+		}
+		for match > 0 {
+			chunk := z.hist[from:min(from+match, len(z.hist))]
+			z.hist = append(z.hist, chunk...)
+			from += len(chunk)
+			match -= len(chunk)
+		}
+	}
+	if !stream.finished() {
+		return fmt.Errorf("%w: sequences stream size mismatch", ErrCorrupt)
+	}
+	z.hist = append(z.hist, literals...)
+	if len(z.hist)-start > maxBlockSize {
+		return fmt.Errorf("%w: block decodes to more than %d bytes", ErrCorrupt, maxBlockSize)
+	}
+	return nil
+}
@@ -0,0 +1,369 @@
+package zstd
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	hashLog  = 16
+	minMatch = 4
+	// minHuffmanLiterals is where coding literals starts to pay for the
+	// tree description.
+	minHuffmanLiterals = 256
+)
+
+var (
+	literalLengthEncoder = newFSEEncoder(predefinedLiteralLengthTable, len(predefinedLiteralLengths))
+	matchLengthEncoder   = newFSEEncoder(predefinedMatchLengthTable, len(predefinedMatchLengths))
+	offsetEncoder        = newFSEEncoder(predefinedOffsetTable, len(predefinedOffsets))
+)
+
+type (
+	// Writer compresses what is written to it into a single zstd frame,
+	// finished by Close.
+	Writer struct {
+		w       io.Writer
+		hist    []byte
+		done    int
+		table   []int32
+		digest  *xxh64
+		started bool
+		closed  bool
+		err     error
+	}
+	sequence struct {
+		literals uint32
+		match    uint32
+		offset   uint32
+	}
+	// bitField is a value and its width, collected in the order the decoder
+	// reads them and written in reverse.
+	bitField struct {
+		value uint64
+		bits  uint8
+	}
+)
+
+// NewWriter returns a Writer compressing into w. Close does not close w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, table: make([]int32, 1<<hashLog), digest: newXXH64()}
+}
+
+func (z *Writer) Write(p []byte) (int, error) {
+	if z.closed {
+		return 0, errors.New("zstd: write after close")
+	}
+	if z.err != nil {
+		return 0, z.err
+	}
+	written := len(p)
+	for len(p) > 0 {
+		room := maxBlockSize - (len(z.hist) - z.done)
+		chunk := p[:min(room, len(p))]
+		z.hist = append(z.hist, chunk...)
+		p = p[len(chunk):]
+		if len(z.hist)-z.done == maxBlockSize {
+			if z.err = z.flushBlock(false); z.err != nil {
+				return 0, z.err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Close writes the pending data as the last block followed by the content
+// checksum.
+func (z *Writer) Close() error {
+	if z.closed {
+		return z.err
+	}
+	z.closed = true
+	if z.err != nil {
+		return z.err
+	}
+	if z.err = z.flushBlock(true); z.err != nil {
+		return z.err
+	}
+	var checksum [4]byte
+	binary.LittleEndian.PutUint32(checksum[:], uint32(z.digest.Sum64()))
+	_, z.err = z.w.Write(checksum[:])
+	return z.err
+}
+
+func (z *Writer) flushBlock(last bool) error {
+	var out []byte
+	if !z.started {
+		z.started = true
+		// no content size, a content checksum, and the window
+		out = binary.LittleEndian.AppendUint32(out, frameMagic)
+		out = append(out, 0x04, byte(windowLog-10)<<3)
+	}
+	block := z.hist[z.done:]
+	z.digest.Write(block)
+	out = z.appendBlock(out, last)
+	z.done = len(z.hist)
+	z.slide()
+	_, err := z.w.Write(out)
+	return err
+}
+
+// slide drops history the window no longer reaches, once it has grown to
+// twice the window.
+func (z *Writer) slide() {
+	const window = 1 << windowLog
+	if len(z.hist) < 2*window {
+		return
+	}
+	shift := len(z.hist) - window
+	copy(z.hist, z.hist[shift:])
+	z.hist = z.hist[:window]
+	z.done -= shift
+	for i, position := range z.table {
+		if int(position) > shift {
+			z.table[i] = position - int32(shift)
+		} else {
+			z.table[i] = 0
+		}
+	}
+}
+
+func (z *Writer) appendBlock(out []byte, last bool) []byte {
+	block := z.hist[z.done:]
+	header := func(kind, size int) []byte {
+		value := uint32(size)<<3 | uint32(kind)<<1
+		if last {
+			value |= 1
+		}
+		return append(out, byte(value), byte(value>>8), byte(value>>16))
+	}
+	if len(block) > 0 && allSame(block) {
+		return append(header(blockRLE, len(block)), block[0])
+	}
+	if len(block) > minMatch {
+		compressed := z.compressBlock()
+		if len(compressed) < len(block) {
+			return append(header(blockCompressed, len(compressed)), compressed...)
+		}
+	}
+	return append(header(blockRaw, len(block)), block...)
+}
+
+func allSame(p []byte) bool {
+	for _, b := range p[1:] {
+		if b != p[0] {
+			return false
+		}
+	}
+	return true
+}
+
+func hash4(p []byte) uint32 {
+	return binary.LittleEndian.Uint32(p) * 2654435761 >> (32 - hashLog)
+}
+
+// compressBlock finds matches greedily and codes the block.
+func (z *Writer) compressBlock() []byte {
+	hist := z.hist
+	start, end := z.done, len(z.hist)
+	var sequences []sequence
+	var literals []byte
+	anchor := start
+	for i := start; i+minMatch <= end; {
+		h := hash4(hist[i:])
+		candidate := int(z.table[h]) - 1
+		z.table[h] = int32(i + 1)
+		if candidate < 0 || i-candidate >= 1<<windowLog || binary.LittleEndian.Uint32(hist[candidate:]) != binary.LittleEndian.Uint32(hist[i:]) {
+			i += 1 + (i-anchor)>>6
+			continue
+		}
+		length := minMatch
+		for i+length < end && hist[candidate+length] == hist[i+length] {
+			length++
+		}
+		for i > anchor && candidate > 0 && hist[i-1] == hist[candidate-1] {
+			i--
+			candidate--
+			length++
+		}
+		literals = append(literals, hist[anchor:i]...)
+		sequences = append(sequences, sequence{literals: uint32(i - anchor), match: uint32(length), offset: uint32(i-candidate) + 3})
+		i += length
+		anchor = i
+		if i-2 > start && i+2 <= end {
+			z.table[hash4(hist[i-2:])] = int32(i - 1)
+		}
+	}
+	literals = append(literals, hist[anchor:end]...)
+	out := appendLiterals(nil, literals)
+	return appendSequences(out, sequences)
+}
+
+// appendLiterals writes the literals section, Huffman coded when that is
+// smaller and every literal fits the direct weight description.
+func appendLiterals(out, literals []byte) []byte {
+	if len(literals) >= minHuffmanLiterals {
+		if coded := huffmanLiterals(literals); coded != nil {
+			return coded
+		}
+	}
+	size := len(literals)
+	switch {
+	case size < 32:
+		out = append(out, byte(size<<3))
+	case size < 4096:
+		out = append(out, byte(size<<4|1<<2), byte(size>>4))
+	default:
+		out = append(out, byte(size<<4|3<<2), byte(size>>4), byte(size>>12))
+	}
+	return append(out, literals...)
+}
+
+// huffmanLiterals codes literals in four streams, nil when that does not
+// save anything.
+func huffmanLiterals(literals []byte) []byte {
+	var freq [256]uint32
+	last := 0
+	distinct := 0
+	for _, b := range literals {
+		if freq[b] == 0 {
+			distinct++
+		}
+		freq[b]++
+		last = max(last, int(b))
+	}
+	if distinct < 2 || last > 128 {
+		return nil
+	}
+	lengths := huffmanLengths(&freq)
+	maxBits := uint8(0)
+	for _, length := range lengths {
+		maxBits = max(maxBits, length)
+	}
+	codes := huffmanCodes(&lengths)
+
+	// the weight of the last symbol is implied
+	description := []byte{byte(127 + last)}
+	for symbol := 0; symbol < last; symbol += 2 {
+		weights := byte(0)
+		if lengths[symbol] > 0 {
+			weights = (maxBits + 1 - lengths[symbol]) << 4
+		}
+		if symbol+1 < last && lengths[symbol+1] > 0 {
+			weights |= maxBits + 1 - lengths[symbol+1]
+		}
+		description = append(description, weights)
+	}
+
+	segment := (len(literals) + 3) / 4
+	var streams [4][]byte
+	for i := range streams {
+		part := literals[min(i*segment, len(literals)):min((i+1)*segment, len(literals))]
+		var w bitWriter
+		for j := len(part) - 1; j >= 0; j-- {
+			code := codes[part[j]]
+			w.add(uint64(code.code), uint(code.bits))
+		}
+		streams[i] = w.close()
+	}
+	body := description
+	for _, stream := range streams[:3] {
+		if len(stream) > 0xFFFF {
+			return nil
+		}
+		body = binary.LittleEndian.AppendUint16(body, uint16(len(stream)))
+	}
+	for _, stream := range streams {
+		body = append(body, stream...)
+	}
+
+	regenerated, compressed := uint64(len(literals)), uint64(len(body))
+	var header []byte
+	switch {
+	case regenerated < 1<<10 && compressed < 1<<10:
+		value := 2 | 1<<2 | regenerated<<4 | compressed<<14
+		header = []byte{byte(value), byte(value >> 8), byte(value >> 16)}
+	case regenerated < 1<<14 && compressed < 1<<14:
+		value := 2 | 2<<2 | regenerated<<4 | compressed<<18
+		header = binary.LittleEndian.AppendUint32(nil, uint32(value))
+	case compressed < 1<<18:
+		value := 2 | 3<<2 | regenerated<<4 | compressed<<22
+		header = []byte{byte(value), byte(value >> 8), byte(value >> 16), byte(value >> 24), byte(value >> 32)}
+	default:
+		return nil
+	}
+	if len(header)+len(body) >= len(literals) {
+		return nil
+	}
+	return append(header, body...)
+}
+
+// appendSequences writes the sequences section with the predefined tables.
+func appendSequences(out []byte, sequences []sequence) []byte {
+	count := len(sequences)
+	switch {
+	case count < 128:
+		out = append(out, byte(count))
+	case count < 0x7F00:
+		out = append(out, byte(count>>8+128), byte(count))
+	default:
+		out = append(out, 255, byte(count-0x7F00), byte((count-0x7F00)>>8))
+	}
+	if count == 0 {
+		return out
+	}
+	out = append(out, 0)
+
+	literalCodes := make([]uint8, count)
+	matchCodes := make([]uint8, count)
+	offsetCodes := make([]uint8, count)
+	for i, seq := range sequences {
+		literalCodes[i] = literalLengthCode(seq.literals)
+		matchCodes[i] = matchLengthCode(seq.match)
+		offsetCodes[i] = uint8(highBit(seq.offset))
+	}
+	// the decoder must end in a state of the last codes, walk back from there
+	literalStates := make([]uint16, count)
+	matchStates := make([]uint16, count)
+	offsetStates := make([]uint16, count)
+	literalStates[count-1] = literalLengthEncoder.first[literalCodes[count-1]]
+	matchStates[count-1] = matchLengthEncoder.first[matchCodes[count-1]]
+	offsetStates[count-1] = offsetEncoder.first[offsetCodes[count-1]]
+	for i := count - 2; i >= 0; i-- {
+		literalStates[i] = literalLengthEncoder.prev[literalCodes[i]][literalStates[i+1]]
+		matchStates[i] = matchLengthEncoder.prev[matchCodes[i]][matchStates[i+1]]
+		offsetStates[i] = offsetEncoder.prev[offsetCodes[i]][offsetStates[i+1]]
+	}
+
+	fields := make([]bitField, 0, 3+6*count)
+	fields = append(fields,
+		bitField{uint64(literalStates[0]), uint8(predefinedLiteralLengthTable.log)},
+		bitField{uint64(offsetStates[0]), uint8(predefinedOffsetTable.log)},
+		bitField{uint64(matchStates[0]), uint8(predefinedMatchLengthTable.log)},
+	)
+	update := func(table *fseTable, state, next uint16) bitField {
+		entry := table.entries[state]
+		return bitField{uint64(next - entry.base), entry.bits}
+	}
+	for i, seq := range sequences {
+		fields = append(fields,
+			bitField{uint64(seq.offset - 1<<offsetCodes[i]), offsetCodes[i]},
+			bitField{uint64(seq.match - matchLengthBase[matchCodes[i]]), matchLengthBits[matchCodes[i]]},
+			bitField{uint64(seq.literals - literalLengthBase[literalCodes[i]]), literalLengthBits[literalCodes[i]]},
+		)
+		if i < count-1 {
+			fields = append(fields,
+				update(predefinedLiteralLengthTable, literalStates[i], literalStates[i+1]),
+				update(predefinedMatchLengthTable, matchStates[i], matchStates[i+1]),
+				update(predefinedOffsetTable, offsetStates[i], offsetStates[i+1]),
+			)
+		}
+	}
+	var w bitWriter
+	w.out = out
+	for i := len(fields) - 1; i >= 0; i-- {
+		w.add(fields[i].value, uint(fields[i].bits))
+	}
+	return w.close()
+}
@@ -0,0 +1,93 @@
+package zstd
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// xxh64 is the XXH64 hash with seed 0, zstd keeps its low 32 bits as the
+// content checksum of a frame.
+type xxh64 struct {
+	v     [4]uint64
+	total uint64
+	buf   [32]byte
+	n     int
+}
+
+const (
+	prime64x1 uint64 = 11400714785074694791
+	prime64x2 uint64 = 14029467366897019727
+	prime64x3 uint64 = 1609587929392839161
+	prime64x4 uint64 = 9650029242287828579
+	prime64x5 uint64 = 2870177450012600261
+)
+
+func newXXH64() *xxh64 {
+	seed := uint64(0)
+	return &xxh64{v: [4]uint64{seed + prime64x1 + prime64x2, prime64x2, seed, seed - prime64x1}}
+}
+
+func xxhRound(acc, input uint64) uint64 {
+	return bits.RotateLeft64(acc+input*prime64x2, 31) * prime64x1
+}
+
+func xxhMerge(acc, value uint64) uint64 {
+	return (acc^xxhRound(0, value))*prime64x1 + prime64x4
+}
+
+func (h *xxh64) stripe(p []byte) {
+	for i := range h.v {
+		h.v[i] = xxhRound(h.v[i], binary.LittleEndian.Uint64(p[i*8:]))
+	}
+}
+
+func (h *xxh64) Write(p []byte) {
+	h.total += uint64(len(p))
+	if h.n > 0 {
+		copied := copy(h.buf[h.n:], p)
+		h.n += copied
+		p = p[copied:]
+		if h.n < len(h.buf) {
+			return
+		}
+		h.stripe(h.buf[:])
+		h.n = 0
+	}
+	for ; len(p) >= 32; p = p[32:] {
+		h.stripe(p)
+	}
+	h.n = copy(h.buf[:], p)
+}
+
+func (h *xxh64) Sum64() uint64 {
+	var sum uint64
+	if h.total >= 32 {
+		sum = bits.RotateLeft64(h.v[0], 1) + bits.RotateLeft64(h.v[1], 7) + bits.RotateLeft64(h.v[2], 12) + bits.RotateLeft64(h.v[3], 18)
+		for _, v := range h.v {
+			sum = xxhMerge(sum, v)
+		}
+	} else {
+		sum = prime64x5
+	}
+	sum += h.total
+	p := h.buf[:h.n]
+	for ; len(p) >= 8; p = p[8:] {
+		sum ^= xxhRound(0, binary.LittleEndian.Uint64(p))
+		sum = bits.RotateLeft64(sum, 27)*prime64x1 + prime64x4
+	}
+	if len(p) >= 4 {
+		sum ^= uint64(binary.LittleEndian.Uint32(p)) * prime64x1
+		sum = bits.RotateLeft64(sum, 23)*prime64x2 + prime64x3
+		p = p[4:]
+	}
+	for _, b := range p {
+		sum ^= uint64(b) * prime64x5
+		sum = bits.RotateLeft64(sum, 11) * prime64x1
+	}
+	sum ^= sum >> 33
+	sum *= prime64x2
+	sum ^= sum >> 29
+	sum *= prime64x3
+	sum ^= sum >> 32
+	return sum
+}
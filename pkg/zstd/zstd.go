@@ -0,0 +1,47 @@
+// Package zstd reads and writes Zstandard frames as described in RFC 8878,
+// so that uploads can be compressed with zstd without another dependency.
+//
+// The Writer finds matches with a single hash table and codes literals with
+// Huffman and sequences with the predefined FSE tables. That compresses
+// less than the reference encoder but decodes with any zstd implementation.
+// The Reader decodes every frame the format allows except those needing a
+// dictionary.
+package zstd
+
+import (
+	"errors"
+	"math/bits"
+)
+
+const (
+	frameMagic         = 0xFD2FB528
+	skippableMagicMask = 0xFFFFFFF0
+	skippableMagic     = 0x184D2A50
+
+	// maxBlockSize is the largest block content the format allows.
+	maxBlockSize = 128 << 10
+	// windowLog is the window the Writer declares and matches within.
+	windowLog = 20
+	// MaxWindowSize bounds the history the Reader keeps for a frame, frames
+	// asking for more are refused instead of exhausting memory.
+	MaxWindowSize = 128 << 20
+)
+
+const (
+	blockRaw = iota
+	blockRLE
+	blockCompressed
+	blockReserved
+)
+
+var (
+	ErrCorrupt        = errors.New("zstd: corrupt input")
+	ErrChecksum       = errors.New("zstd: checksum mismatch")
+	ErrDictionary     = errors.New("zstd: frames using a dictionary are not supported")
+	ErrWindowTooLarge = errors.New("zstd: window size too large")
+)
+
+// highBit is the index of the highest set bit of v, which must not be zero.
+func highBit(v uint32) uint {
+	return uint(31 - bits.LeadingZeros32(v))
+}
@@ -0,0 +1,155 @@
+package zstd
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// samples covers the block types the writer picks from: empty, a single
+// repeated byte, text that matches and codes with Huffman, bytes that do not
+// compress, and several blocks that reach back across block boundaries.
+func samples() map[string][]byte {
+	random := rand.New(rand.NewSource(1))
+	noise := make([]byte, 300<<10)
+	random.Read(noise)
+	var text bytes.Buffer
+	words := []string{"model ", "dataset ", "training ", "sensory ", "upload ", "synexis ", "\n"}
+	for text.Len() < 1<<20 {
+		text.WriteString(words[random.Intn(len(words))])
+		if random.Intn(50) == 0 {
+			text.WriteByte(byte(random.Intn(256)))
+		}
+	}
+	mixed := append(append([]byte{}, noise[:200<<10]...), noise[:200<<10]...)
+	return map[string][]byte{
+		"empty":  nil,
+		"small":  []byte("hello, hello, hello"),
+		"repeat": bytes.Repeat([]byte{'a'}, 200<<10),
+		"text":   text.Bytes(),
+		"noise":  noise,
+		"mixed":  mixed,
+	}
+}
+
+func compress(t testing.TB, data []byte) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	// uneven writes cross block boundaries
+	for len(data) > 0 {
+		n := min(len(data), 70001)
+		if _, err := w.Write(data[:n]); err != nil {
+			t.Fatal(err)
+		}
+		data = data[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	for name, data := range samples() {
+		t.Run(name, func(t *testing.T) {
+			compressed := compress(t, data)
+			got, err := io.ReadAll(NewReader(bytes.NewReader(compressed)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("round trip of %d bytes returned %d different bytes", len(data), len(got))
+			}
+		})
+	}
+}
+
+func TestReaderRejects(t *testing.T) {
+	valid := compress(t, samples()["text"])
+	corrupt := append([]byte{}, valid...)
+	corrupt[len(corrupt)-1] ^= 0xFF
+	for name, input := range map[string][]byte{
+		"empty":     {},
+		"magic":     []byte("not zstd"),
+		"truncated": valid[:len(valid)/2],
+		"checksum":  corrupt,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := io.ReadAll(NewReader(bytes.NewReader(input))); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+// TestReference checks both directions against the zstd command when it is
+// installed.
+func TestReference(t *testing.T) {
+	binary, err := exec.LookPath("zstd")
+	if err != nil {
+		t.Skip("zstd command not installed")
+	}
+	for name, data := range samples() {
+		t.Run(name, func(t *testing.T) {
+			cmd := exec.Command(binary, "-d", "-c", "-q")
+			cmd.Stdin = bytes.NewReader(compress(t, data))
+			cmd.Stderr = os.Stderr
+			got, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("zstd -d: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatal("zstd -d returned different bytes")
+			}
+			for _, level := range []string{"-1", "-3", "-9", "-19", "--ultra", "--long"} {
+				cmd := exec.Command(binary, level, "-c", "-q")
+				cmd.Stdin = bytes.NewReader(data)
+				compressed, err := cmd.Output()
+				if err != nil {
+					t.Fatalf("zstd %s: %v", level, err)
+				}
+				got, err := io.ReadAll(NewReader(bytes.NewReader(compressed)))
+				if err != nil {
+					t.Fatalf("reading zstd %s output: %v", level, err)
+				}
+				if !bytes.Equal(got, data) {
+					t.Fatalf("reading zstd %s output returned different bytes", level)
+				}
+			}
+		})
+	}
+}
+
+// FuzzReader feeds arbitrary frames to the decoder, which must fail with an
+// error rather than panic or hang, and whose output must survive a round
+// trip through the writer.
+func FuzzReader(f *testing.F) {
+	for _, data := range [][]byte{nil, []byte("hello, hello, hello"), bytes.Repeat([]byte{'a'}, 1000), bytes.Repeat([]byte("model dataset training "), 200)} {
+		compressed := compress(f, data)
+		f.Add(compressed)
+		f.Add(compressed[:len(compressed)/2])
+	}
+	f.Fuzz(func(t *testing.T, input []byte) {
+		// a tiny frame may legitimately expand to a window's worth of output
+		got, err := io.ReadAll(io.LimitReader(NewReader(bytes.NewReader(input)), 8<<20))
+		if err != nil {
+			return
+		}
+		if !bytes.Equal(decompress(t, compress(t, got)), got) {
+			t.Fatalf("round trip of %d decoded bytes returned different bytes", len(got))
+		}
+	})
+}
+
+func decompress(t testing.TB, data []byte) []byte {
+	t.Helper()
+	got, err := io.ReadAll(NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
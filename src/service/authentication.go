@@ -1,310 +1,188 @@
 package service
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
-	"fmt"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/synxms/synexis/pkg/client"
 	"github.com/synxms/synexis/pkg/utility"
 	"io"
 	"log"
-	"mime/multipart"
 	"net/http"
-	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
 	"time"
 )
 
 type (
 	Authentication interface {
-		GenerateLoginWithGoogle() (*LoginResponse, error)
-		GenerateAccessAndRefreshToken(refresh string) (*ResponseRefresh, error)
-		GenerateAPIKeySentinel(prefix, validationLayerOne, validationLayerTwo, access string) (*ResponseRefresh, error)
-		UploadFileDatasetSentinel(absoluteFile string, access string) (*ResponseUploadDataset, error)
-		UploadFileSensorySentinel(absoluteFile string, access string) (*ResponseUploadSensory, error)
-		CreateRequest(sensoryId string, datasetId string, access string) (*ResponseCreateRequest, error)
+		GenerateLoginWithGoogle(ctx context.Context) (*LoginResponse, error)
+		GenerateAccessAndRefreshToken(ctx context.Context, refresh string) (*ResponseRefresh, error)
+		RevokeRefreshToken(ctx context.Context, refresh string) error
+		GenerateAPIKeySentinel(ctx context.Context, prefix, validationLayerOne, validationLayerTwo string) (*ResponseAPIKey, error)
+		ListAPIKeys(ctx context.Context) (*ResponseListAPIKeys, error)
+		UploadFileDatasetSentinel(ctx context.Context, absoluteFile string, opts ...client.UploadOption) (*ResponseUploadDataset, error)
+		UploadDatasetStreamSentinel(ctx context.Context, fileName string, content io.Reader, opts ...client.UploadOption) (*ResponseUploadDataset, error)
+		UploadFileSensorySentinel(ctx context.Context, absoluteFile string, opts ...client.UploadOption) (*ResponseUploadSensory, error)
+		UploadEncodingsSentinel(ctx context.Context, kind string) ([]string, error)
+		CreateRequest(ctx context.Context, sensoryId string, datasetId string) (*ResponseCreateRequest, error)
+		RequestStatus(ctx context.Context, requestId string) (*ResponseRequestStatus, error)
+		ListRequests(ctx context.Context) (*ResponseListRequests, error)
+		RequestLogs(ctx context.Context, requestId string) (*ResponseRequestLogs, error)
+		CancelRequest(ctx context.Context, requestId string) (*ResponseRequestStatus, error)
+		RetryRequest(ctx context.Context, requestId string) (*ResponseCreateRequest, error)
+		RequestArtifacts(ctx context.Context, requestId string) (*ResponseRequestArtifacts, error)
+		DownloadArtifact(ctx context.Context, artifact client.TrainingArtifact, w io.Writer) (int64, error)
+		AccessToken(ctx context.Context) (*client.Token, error)
+		Account(ctx context.Context) (*ResponseAccount, error)
+		ListServices(ctx context.Context) (*ResponseListServices, error)
+		ProbeEndpoint(ctx context.Context, path string) (bool, error)
+		BaseURL() string
 		OpenDefaultBrowser(url string) error
-		IsExpired(jwtString string) (*string, *string, error)
+		IsExpired(jwtString string, now time.Time, leeway time.Duration) (*string, *string, error)
+		VerifyToken(ctx context.Context, jwtString string, cache client.KeyCache, options client.VerifyOptions) (jwt.MapClaims, error)
 	}
 	authentication struct {
-		loginEndpoint             string
-		refreshEndpoint           string
-		generateAPIKeyEndpoint    string
-		uploadDatasetFileEndpoint string
-		uploadSensoryFileEndpoint string
-		createRequestEndpoint     string
-		contentTypeJsonHeader     string
-	}
-	LoginResponse struct {
-		ResponseCode    string `json:"responseCode"`
-		ResponseMessage string `json:"responseMessage"`
-		RedirectURL     string `json:"redirectUrl"`
-	}
-	ResponseRefresh struct {
-		ResponseCode    string `json:"responseCode"`
-		ResponseMessage string `json:"responseMessage"`
-		Refresh         string `json:"refresh"`
-		Access          string `json:"access"`
-	}
-	ResponseUploadDataset struct {
-		ResponseCode    string `json:"success"`
-		ResponseMessage string `json:"messages"`
-		Data            struct {
-			DatasetID string `json:"dataset_id"`
-		} `json:"data"`
-	}
-	ResponseUploadSensory struct {
-		ResponseCode    string `json:"success"`
-		ResponseMessage string `json:"messages"`
-		Data            struct {
-			SensoryID string `json:"sensory_id"`
-		} `json:"data"`
-	}
-	ResponseCreateRequest struct {
-		ResponseCode    string `json:"success"`
-		ResponseMessage string `json:"messages"`
-		Data            struct {
-			RequestID string `json:"request_id"`
-		} `json:"data"`
-	}
+		client *client.Client
+	}
+	TokenDetails struct {
+		Header        map[string]interface{} `json:"header"`
+		Claims        map[string]interface{} `json:"claims"`
+		IssuedAt      *time.Time             `json:"issuedAt,omitempty"`
+		NotBefore     *time.Time             `json:"notBefore,omitempty"`
+		ExpiresAt     *time.Time             `json:"expiresAt,omitempty"`
+		MissingClaims []string               `json:"missingClaims,omitempty"`
+	}
+	LoginResponse            = client.LoginResponse
+	ResponseRefresh          = client.RefreshResponse
+	ResponseAPIKey           = client.APIKeyResponse
+	ResponseUploadDataset    = client.UploadDatasetResponse
+	ResponseUploadSensory    = client.UploadSensoryResponse
+	ResponseCreateRequest    = client.CreateRequestResponse
+	ResponseAccount          = client.MeResponse
+	ResponseListAPIKeys      = client.ListAPIKeysResponse
+	ResponseRequestStatus    = client.TrainingRequestResponse
+	ResponseListRequests     = client.ListTrainingRequestsResponse
+	ResponseRequestLogs      = client.TrainingLogsResponse
+	ResponseRequestArtifacts = client.TrainingArtifactsResponse
+	ResponseListServices     = client.ListServicesResponse
 )
 
-func NewAuthentication(baseUrl string) Authentication {
+// NewAuthentication builds the CLI service, credentials come from the token
+// source configured through opts.
+func NewAuthentication(baseUrl string, httpClient *http.Client, opts ...client.Option) Authentication {
 	if !utility.IsValidURL(baseUrl) {
 		log.Fatalln("please provide base url before continue")
 	}
-	return &authentication{
-		contentTypeJsonHeader:     "application/json",
-		loginEndpoint:             fmt.Sprintf("%s/api/v1/authentication/login", baseUrl),
-		refreshEndpoint:           fmt.Sprintf("%s/api/v1/authentication/refresh", baseUrl),
-		generateAPIKeyEndpoint:    fmt.Sprintf("%s/api/v1/authentication/create/apikey", baseUrl),
-		uploadDatasetFileEndpoint: fmt.Sprintf("%s/api/v1/sentinel/sessions/upload/dataset", baseUrl),
-		uploadSensoryFileEndpoint: fmt.Sprintf("%s/api/v1/sentinel/sessions/upload/sensory", baseUrl),
-		createRequestEndpoint:     fmt.Sprintf("%s/api/v1/sentinel/sessions/create/request", baseUrl),
-	}
-}
-
-func (a *authentication) CreateRequest(sensoryId string, datasetId string, access string) (*ResponseCreateRequest, error) {
-	dataRequest := map[string]interface{}{}
-	dataRequest["sensory_id"] = sensoryId
-	dataRequest["dataset_id"] = datasetId
-	dataRequestBytes, err := json.Marshal(dataRequest)
-	if err != nil {
-		return nil, errors.New("failed to marshal request")
-	}
-	req, err := http.NewRequest("POST", a.createRequestEndpoint, bytes.NewBuffer(dataRequestBytes))
-	if err != nil {
-		return nil, errors.New("failed to create request")
+	if httpClient == nil {
+		httpClient = http.DefaultClient
 	}
-	req.Header.Set("Content-Type", a.contentTypeJsonHeader)
-	req.Header.Set("Authorization", "Bearer "+access)
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	sdk, err := client.New(append([]client.Option{
+		client.WithBaseURL(baseUrl),
+		client.WithHTTPClient(httpClient),
+		client.WithUserAgent("synexis-cli"),
+	}, opts...)...)
 	if err != nil {
-		return nil, errors.New("failed to contact server")
+		log.Fatalln(err.Error())
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			panic("failed to close response body")
-		}
-	}(resp.Body)
-	var createResponse ResponseCreateRequest
-	if err := json.NewDecoder(resp.Body).Decode(&createResponse); err != nil {
-		return nil, errors.New("failed to contact server")
-	}
-	return &createResponse, nil
+	return &authentication{client: sdk}
 }
 
-func (a *authentication) GenerateLoginWithGoogle() (*LoginResponse, error) {
-	req, err := http.NewRequest("POST", a.loginEndpoint, bytes.NewBuffer([]byte("{}")))
-	if err != nil {
-		return nil, errors.New("failed to create request")
-	}
-	req.Header.Set("Content-Type", a.contentTypeJsonHeader)
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, errors.New("failed to contact server")
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			panic("failed to close response body")
-		}
-	}(resp.Body)
-	var loginResp LoginResponse
-	if err := json.NewDecoder(resp.Body).Decode(&loginResp); err != nil {
-		return nil, errors.New("failed to contact server")
-	}
-	return &loginResp, nil
+func (a *authentication) AccessToken(ctx context.Context) (*client.Token, error) {
+	return a.client.Token(ctx)
 }
 
-func (a *authentication) UploadFileDatasetSentinel(absoluteFile string, access string) (*ResponseUploadDataset, error) {
-	file, err := os.Open(absoluteFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
+func (a *authentication) Account(ctx context.Context) (*ResponseAccount, error) {
+	return a.client.Auth.Me(ctx)
+}
 
-	// Prepare multipart form
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
+func (a *authentication) ListServices(ctx context.Context) (*ResponseListServices, error) {
+	return a.client.Services.List(ctx)
+}
 
-	formFile, err := writer.CreateFormFile("file", filepath.Base(absoluteFile))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create form file: %w", err)
-	}
+func (a *authentication) ProbeEndpoint(ctx context.Context, path string) (bool, error) {
+	return a.client.Services.Probe(ctx, path)
+}
 
-	if _, err := io.Copy(formFile, file); err != nil {
-		return nil, fmt.Errorf("failed to write file to form: %w", err)
-	}
+func (a *authentication) BaseURL() string {
+	return a.client.BaseURL()
+}
 
-	// Close writer to set the terminating boundary
-	if err := writer.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close multipart writer: %w", err)
-	}
+func (a *authentication) CreateRequest(ctx context.Context, sensoryId string, datasetId string) (*ResponseCreateRequest, error) {
+	return a.client.Training.Create(ctx, sensoryId, datasetId)
+}
 
-	// Create POST request
-	req, err := http.NewRequest("POST", a.uploadDatasetFileEndpoint, &buf)
-	if err != nil {
-		return nil, errors.New("failed to create request")
-	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Authorization", "Bearer "+access)
-	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, errors.New("failed to contact server")
-	}
-	defer func(Body io.ReadCloser) {
-		if err := Body.Close(); err != nil {
-			panic("failed to close response body")
-		}
-	}(resp.Body)
+func (a *authentication) RequestStatus(ctx context.Context, requestId string) (*ResponseRequestStatus, error) {
+	return a.client.Training.Get(ctx, requestId)
+}
 
-	// Parse response
-	var uploadResp ResponseUploadDataset
-	if err := json.NewDecoder(resp.Body).Decode(&uploadResp); err != nil {
-		return nil, errors.New("failed to decode upload response")
-	}
+func (a *authentication) ListRequests(ctx context.Context) (*ResponseListRequests, error) {
+	return a.client.Training.List(ctx)
+}
 
-	return &uploadResp, nil
+func (a *authentication) RequestLogs(ctx context.Context, requestId string) (*ResponseRequestLogs, error) {
+	return a.client.Training.Logs(ctx, requestId)
 }
 
-func (a *authentication) UploadFileSensorySentinel(absoluteFile string, access string) (*ResponseUploadSensory, error) {
-	file, err := os.Open(absoluteFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
+func (a *authentication) CancelRequest(ctx context.Context, requestId string) (*ResponseRequestStatus, error) {
+	return a.client.Training.Cancel(ctx, requestId)
+}
 
-	// Prepare multipart form
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
+func (a *authentication) RetryRequest(ctx context.Context, requestId string) (*ResponseCreateRequest, error) {
+	return a.client.Training.Retry(ctx, requestId)
+}
 
-	formFile, err := writer.CreateFormFile("file", filepath.Base(absoluteFile))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create form file: %w", err)
-	}
+func (a *authentication) RequestArtifacts(ctx context.Context, requestId string) (*ResponseRequestArtifacts, error) {
+	return a.client.Training.Artifacts(ctx, requestId)
+}
 
-	if _, err := io.Copy(formFile, file); err != nil {
-		return nil, fmt.Errorf("failed to write file to form: %w", err)
-	}
+func (a *authentication) DownloadArtifact(ctx context.Context, artifact client.TrainingArtifact, w io.Writer) (int64, error) {
+	return a.client.Training.DownloadArtifact(ctx, artifact, w)
+}
 
-	// Close writer to set the terminating boundary
-	if err := writer.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close multipart writer: %w", err)
-	}
+func (a *authentication) GenerateLoginWithGoogle(ctx context.Context) (*LoginResponse, error) {
+	return a.client.Auth.LoginWithGoogle(ctx)
+}
 
-	// Create POST request
-	req, err := http.NewRequest("POST", a.uploadSensoryFileEndpoint, &buf)
-	if err != nil {
-		return nil, errors.New("failed to create request")
-	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Authorization", "Bearer "+access)
-	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, errors.New("failed to contact server")
-	}
-	defer func(Body io.ReadCloser) {
-		if err := Body.Close(); err != nil {
-			panic("failed to close response body")
-		}
-	}(resp.Body)
+func (a *authentication) UploadFileDatasetSentinel(ctx context.Context, absoluteFile string, opts ...client.UploadOption) (*ResponseUploadDataset, error) {
+	return a.client.Datasets.UploadFile(ctx, absoluteFile, opts...)
+}
 
-	// Parse response
-	var uploadResp ResponseUploadSensory
-	if err := json.NewDecoder(resp.Body).Decode(&uploadResp); err != nil {
-		return nil, errors.New("failed to decode upload response")
-	}
+func (a *authentication) UploadDatasetStreamSentinel(ctx context.Context, fileName string, content io.Reader, opts ...client.UploadOption) (*ResponseUploadDataset, error) {
+	return a.client.Datasets.UploadStream(ctx, fileName, content, opts...)
+}
 
-	return &uploadResp, nil
+func (a *authentication) UploadFileSensorySentinel(ctx context.Context, absoluteFile string, opts ...client.UploadOption) (*ResponseUploadSensory, error) {
+	return a.client.Sensory.UploadFile(ctx, absoluteFile, opts...)
 }
 
-func (a *authentication) GenerateAPIKeySentinel(prefix, validationLayerOne, validationLayerTwo, access string) (*ResponseRefresh, error) {
-	dataRequest := map[string]interface{}{}
-	dataRequest["prefix"] = prefix
-	dataRequest["validationLayerOne"] = validationLayerOne
-	dataRequest["validationLayerTwo"] = validationLayerTwo
-	dataRequestBytes, err := json.Marshal(dataRequest)
-	if err != nil {
-		return nil, errors.New("failed to marshal request")
-	}
-	req, err := http.NewRequest("POST", a.generateAPIKeyEndpoint, bytes.NewBuffer(dataRequestBytes))
-	if err != nil {
-		return nil, errors.New("failed to create request")
+// UploadEncodingsSentinel lists the content encodings accepted for uploads
+// of kind, dataset or sensory.
+func (a *authentication) UploadEncodingsSentinel(ctx context.Context, kind string) ([]string, error) {
+	if kind == "sensory" {
+		return a.client.Sensory.Encodings(ctx)
 	}
-	req.Header.Set("Content-Type", a.contentTypeJsonHeader)
-	req.Header.Set("Authorization", "Bearer "+access)
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, errors.New("failed to contact server")
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			panic("failed to close response body")
-		}
-	}(resp.Body)
-	var refreshResp ResponseRefresh
-	if err := json.NewDecoder(resp.Body).Decode(&refreshResp); err != nil {
-		return nil, errors.New("failed to contact server")
-	}
-	return &refreshResp, nil
+	return a.client.Datasets.Encodings(ctx)
 }
 
-func (a *authentication) GenerateAccessAndRefreshToken(refresh string) (*ResponseRefresh, error) {
-	req, err := http.NewRequest("POST", a.refreshEndpoint, bytes.NewBuffer([]byte("{}")))
-	if err != nil {
-		return nil, errors.New("failed to create request")
-	}
-	req.Header.Set("Content-Type", a.contentTypeJsonHeader)
-	req.Header.Set("Authorization", "Bearer "+refresh)
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, errors.New("failed to contact server")
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			panic("failed to close response body")
-		}
-	}(resp.Body)
-	var refreshResp ResponseRefresh
-	if err := json.NewDecoder(resp.Body).Decode(&refreshResp); err != nil {
-		return nil, errors.New("failed to contact server")
-	}
-	return &refreshResp, nil
+func (a *authentication) GenerateAPIKeySentinel(ctx context.Context, prefix, validationLayerOne, validationLayerTwo string) (*ResponseAPIKey, error) {
+	return a.client.APIKeys.Create(ctx, client.CreateAPIKeyRequest{
+		Prefix:             prefix,
+		ValidationLayerOne: validationLayerOne,
+		ValidationLayerTwo: validationLayerTwo,
+	})
+}
+
+func (a *authentication) ListAPIKeys(ctx context.Context) (*ResponseListAPIKeys, error) {
+	return a.client.APIKeys.List(ctx)
+}
+
+func (a *authentication) GenerateAccessAndRefreshToken(ctx context.Context, refresh string) (*ResponseRefresh, error) {
+	return a.client.Auth.Refresh(ctx, refresh)
+}
+
+func (a *authentication) RevokeRefreshToken(ctx context.Context, refresh string) error {
+	return a.client.Auth.Revoke(ctx, refresh)
 }
 
 func (a *authentication) OpenDefaultBrowser(url string) error {
@@ -318,23 +196,72 @@ func (a *authentication) OpenDefaultBrowser(url string) error {
 	}
 }
 
-func (a *authentication) IsExpired(jwtString string) (*string, *string, error) {
+var (
+	ErrInvalidToken  = errors.New("invalid token")
+	ErrNoExpiration  = errors.New("no expiration field in token")
+	ErrTokenExpired  = errors.New("token is expired")
+	ErrTokenNotValid = errors.New("token is not valid yet")
+)
+
+// expectedClaims are the claims the CLI relies on being present.
+var expectedClaims = []string{"exp", "iat", "companyId"}
+
+// InspectToken decodes the header and claims without verifying the signature.
+func InspectToken(jwtString string) (*TokenDetails, error) {
 	parser := jwt.NewParser()
 	claims := jwt.MapClaims{}
-	_, _, err := parser.ParseUnverified(jwtString, claims)
+	token, _, err := parser.ParseUnverified(jwtString, claims)
 	if err != nil {
-		return nil, nil, errors.New("invalid token")
+		return nil, ErrInvalidToken
+	}
+	details := &TokenDetails{
+		Header:    token.Header,
+		Claims:    claims,
+		IssuedAt:  claimTime(claims, "iat"),
+		NotBefore: claimTime(claims, "nbf"),
+		ExpiresAt: claimTime(claims, "exp"),
+	}
+	for _, name := range expectedClaims {
+		if _, ok := claims[name]; !ok {
+			details.MissingClaims = append(details.MissingClaims, name)
+		}
 	}
-	exp, ok := claims["exp"].(float64)
+	return details, nil
+}
+
+func claimTime(claims jwt.MapClaims, name string) *time.Time {
+	value, ok := claims[name].(float64)
 	if !ok {
-		return nil, nil, errors.New("no expiration field in token")
+		return nil
 	}
-	expTime := time.Unix(int64(exp), 0)
-	now := time.Now()
+	t := time.Unix(int64(value), 0)
+	return &t
+}
+
+// IsExpired returns the remaining lifetime and expiry of the token at now. Up
+// to leeway of clock skew is tolerated on exp and nbf.
+func (a *authentication) IsExpired(jwtString string, now time.Time, leeway time.Duration) (*string, *string, error) {
+	details, err := InspectToken(jwtString)
+	if err != nil {
+		return nil, nil, err
+	}
+	if details.ExpiresAt == nil {
+		return nil, nil, ErrNoExpiration
+	}
+	expTime := *details.ExpiresAt
 	expiredAt := expTime.Format(time.DateTime)
 	totalRemains := expTime.Sub(now).String()
-	if now.After(expTime) {
-		return &totalRemains, &expiredAt, errors.New("token is expired")
+	if now.After(expTime.Add(leeway)) {
+		return &totalRemains, &expiredAt, ErrTokenExpired
+	}
+	if details.NotBefore != nil && now.Add(leeway).Before(*details.NotBefore) {
+		return &totalRemains, &expiredAt, ErrTokenNotValid
 	}
 	return &totalRemains, &expiredAt, nil
 }
+
+// VerifyToken checks the token signature against the server's published keys,
+// caching them in cache so later checks also work offline.
+func (a *authentication) VerifyToken(ctx context.Context, jwtString string, cache client.KeyCache, options client.VerifyOptions) (jwt.MapClaims, error) {
+	return client.NewVerifier(a.client, cache, options).Verify(ctx, jwtString)
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func signToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestIsExpired(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	claims := func(exp, nbf time.Time) jwt.MapClaims {
+		c := jwt.MapClaims{"iat": now.Add(-time.Hour).Unix(), "companyId": "company"}
+		if !exp.IsZero() {
+			c["exp"] = exp.Unix()
+		}
+		if !nbf.IsZero() {
+			c["nbf"] = nbf.Unix()
+		}
+		return c
+	}
+	tests := []struct {
+		name          string
+		claims        jwt.MapClaims
+		leeway        time.Duration
+		wantErr       error
+		wantRemaining string
+	}{
+		{"valid", claims(now.Add(10*time.Minute), time.Time{}), 0, nil, "10m0s"},
+		{"expired", claims(now.Add(-time.Minute), time.Time{}), 0, ErrTokenExpired, "-1m0s"},
+		{"expired within leeway", claims(now.Add(-time.Minute), time.Time{}), 2 * time.Minute, nil, "-1m0s"},
+		{"expired beyond leeway", claims(now.Add(-3*time.Minute), time.Time{}), 2 * time.Minute, ErrTokenExpired, "-3m0s"},
+		{"not valid yet", claims(now.Add(time.Hour), now.Add(5*time.Minute)), 0, ErrTokenNotValid, "1h0m0s"},
+		{"not valid yet within leeway", claims(now.Add(time.Hour), now.Add(time.Minute)), 2 * time.Minute, nil, "1h0m0s"},
+		{"no expiry", claims(time.Time{}, time.Time{}), 0, ErrNoExpiration, ""},
+	}
+	a := &authentication{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remaining, expiredAt, err := a.IsExpired(signToken(t, tt.claims), now, tt.leeway)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantRemaining == "" {
+				if remaining != nil || expiredAt != nil {
+					t.Fatalf("got remaining %v and expiry %v, want none", remaining, expiredAt)
+				}
+				return
+			}
+			if remaining == nil || *remaining != tt.wantRemaining {
+				t.Errorf("got remaining %v, want %s", remaining, tt.wantRemaining)
+			}
+			exp := time.Unix(tt.claims["exp"].(int64), 0).Format(time.DateTime)
+			if expiredAt == nil || *expiredAt != exp {
+				t.Errorf("got expiry %v, want %s", expiredAt, exp)
+			}
+		})
+	}
+}
+
+func TestInspectToken(t *testing.T) {
+	tests := []struct {
+		name        string
+		token       string
+		wantErr     error
+		wantMissing []string
+	}{
+		{"complete", signToken(t, jwt.MapClaims{"exp": 2000000000, "iat": 1000000000, "companyId": "company"}), nil, nil},
+		{"missing claims", signToken(t, jwt.MapClaims{"exp": 2000000000}), nil, []string{"iat", "companyId"}},
+		{"malformed", "not.a.jwt", ErrInvalidToken, nil},
+		{"empty", "", ErrInvalidToken, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details, err := InspectToken(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(details.MissingClaims) != len(tt.wantMissing) {
+				t.Fatalf("got missing claims %v, want %v", details.MissingClaims, tt.wantMissing)
+			}
+			for i, name := range tt.wantMissing {
+				if details.MissingClaims[i] != name {
+					t.Errorf("got missing claims %v, want %v", details.MissingClaims, tt.wantMissing)
+				}
+			}
+			if details.ExpiresAt == nil || details.ExpiresAt.Unix() != 2000000000 {
+				t.Errorf("got expiry %v, want %v", details.ExpiresAt, time.Unix(2000000000, 0))
+			}
+		})
+	}
+}